@@ -0,0 +1,328 @@
+package assembler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/diag"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+HACK ASSEMBLY
+
+A-instruction:   '@' (value | symbol)           0vvvvvvvvvvvvvvv
+
+C-instruction:   (dest '=')? comp (';' jump)?   111a cccc ccdd djjj
+
+label:           '(' symbol ')'
+
+symbol:          Sequence of letters, digits, '_', '.', '$' and ':' NOT starting with a digit.
+
+Labels name the address of the instruction that follows them. Any other
+symbol that is not predefined is a variable, allocated from RAM[16] in the
+order it first appears. In strict mode, symbols that look like the labels of
+translated VM code are not taken for variables: a symbol containing '$', or
+a '.' not followed by a static variable index only ('Foo.bar' but not
+'Foo.3'), must be declared as a label.
+*/
+
+type AssemblerErrorType int
+
+const (
+	DUPLICATE_LABEL AssemblerErrorType = iota
+	INVALID_ADDRESS
+	INVALID_INSTRUCTION
+	INVALID_SYMBOL
+	UNDEFINED_LABEL
+)
+
+func (errorType AssemblerErrorType) String() string {
+	return []string{
+		"DUPLICATE_LABEL", "INVALID_ADDRESS", "INVALID_INSTRUCTION", "INVALID_SYMBOL", "UNDEFINED_LABEL",
+	}[errorType]
+}
+
+type AssemblerError struct {
+	diag.Diagnostic
+	Kind AssemblerErrorType
+}
+
+func (e *AssemblerError) Error() string {
+	return fmt.Sprintf(
+		"<%s:%d:%d>\tError: %s",
+		e.Filename,
+		e.Line,
+		e.Column,
+		e.Message,
+	)
+}
+
+// ErrorList is the list of every error found in a single file, in source order.
+type ErrorList = diag.List[*AssemblerError]
+
+var predefinedSymbols = map[string]int{
+	"SP": 0, "LCL": 1, "ARG": 2, "THIS": 3, "THAT": 4,
+	"R0": 0, "R1": 1, "R2": 2, "R3": 3, "R4": 4, "R5": 5, "R6": 6, "R7": 7,
+	"R8": 8, "R9": 9, "R10": 10, "R11": 11, "R12": 12, "R13": 13, "R14": 14, "R15": 15,
+	"SCREEN": 16384, "KBD": 24576,
+}
+
+// compCodes maps every computation to its 'a' bit and six 'c' bits.
+var compCodes = map[string]string{
+	"0": "0101010", "1": "0111111", "-1": "0111010",
+	"D": "0001100", "A": "0110000", "M": "1110000",
+	"!D": "0001101", "!A": "0110001", "!M": "1110001",
+	"-D": "0001111", "-A": "0110011", "-M": "1110011",
+	"D+1": "0011111", "A+1": "0110111", "M+1": "1110111",
+	"D-1": "0001110", "A-1": "0110010", "M-1": "1110010",
+	"D+A": "0000010", "D+M": "1000010",
+	"D-A": "0010011", "D-M": "1010011",
+	"A-D": "0000111", "M-D": "1000111",
+	"D&A": "0000000", "D&M": "1000000",
+	"D|A": "0010101", "D|M": "1010101",
+}
+
+var destCodes = map[string]string{
+	"": "000", "M": "001", "D": "010", "MD": "011",
+	"A": "100", "AM": "101", "AD": "110", "AMD": "111",
+}
+
+var jumpCodes = map[string]string{
+	"": "000", "JGT": "001", "JEQ": "010", "JGE": "011",
+	"JLT": "100", "JNE": "101", "JLE": "110", "JMP": "111",
+}
+
+type instruction struct {
+	column int
+	line   int
+	text   string
+}
+
+type Assembler struct {
+	errors       ErrorList
+	filename     string
+	instructions []instruction
+	nextVariable int
+	// strict rejects undeclared symbols that look like labels.
+	strict  bool
+	symbols map[string]int
+}
+
+// Option configures optional assembler behaviour.
+type Option func(*Assembler)
+
+// WithStrictSymbols reports the symbols that look like labels but are not
+// declared, instead of allocating them as variables.
+func WithStrictSymbols() Option {
+	return func(assembler *Assembler) {
+		assembler.strict = true
+	}
+}
+
+func NewAssembler(options ...Option) *Assembler {
+	assembler := new(Assembler)
+
+	for _, option := range options {
+		option(assembler)
+	}
+
+	return assembler
+}
+
+func (assembler *Assembler) addError(inst instruction, kind AssemblerErrorType, format string, args ...any) {
+	end := types.Position{Filename: assembler.filename, Line: inst.line, Column: inst.column + len(inst.text)}
+
+	assembler.errors = append(assembler.errors, &AssemblerError{
+		Diagnostic: diag.Diagnostic{
+			Column:   inst.column,
+			End:      end,
+			Filename: assembler.filename,
+			Line:     inst.line,
+			Message:  fmt.Sprintf(format, args...),
+		},
+		Kind: kind,
+	})
+}
+
+// isLabelLike reports whether a symbol looks like a label of translated VM
+// code rather than a variable.
+func isLabelLike(symbol string) bool {
+	if strings.Contains(symbol, "$") {
+		return true
+	}
+
+	dot := strings.LastIndex(symbol, ".")
+
+	if dot < 0 {
+		return false
+	}
+
+	_, err := strconv.Atoi(symbol[dot+1:])
+	return err != nil
+}
+
+func isSymbol(symbol string) bool {
+	if symbol == "" || (symbol[0] >= '0' && symbol[0] <= '9') {
+		return false
+	}
+
+	for _, char := range symbol {
+		valid := char == '_' || char == '.' || char == '$' || char == ':' ||
+			(char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+		if !valid {
+			return false
+		}
+	}
+
+	return true
+}
+
+// collectLabels strips comments and blank lines and assigns every label the
+// address of the instruction that follows it.
+func (assembler *Assembler) collectLabels(source string) {
+	for index, line := range strings.Split(source, "\n") {
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+
+		trimmed := strings.TrimLeft(line, " \t")
+		inst := instruction{
+			column: len(line) - len(trimmed) + 1,
+			line:   index + 1,
+			text:   strings.TrimSpace(trimmed),
+		}
+
+		if inst.text == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(inst.text, "(") {
+			assembler.instructions = append(assembler.instructions, inst)
+			continue
+		}
+
+		label := strings.TrimSuffix(strings.TrimPrefix(inst.text, "("), ")")
+
+		if !strings.HasSuffix(inst.text, ")") || !isSymbol(label) {
+			assembler.addError(inst, INVALID_SYMBOL, "'%s' is not a valid label", inst.text)
+		} else if _, ok := assembler.symbols[label]; ok {
+			assembler.addError(inst, DUPLICATE_LABEL, "label '%s' has already been declared", label)
+		} else {
+			assembler.symbols[label] = len(assembler.instructions)
+		}
+	}
+}
+
+func (assembler *Assembler) assembleAddress(inst instruction) string {
+	value := inst.text[1:]
+
+	if value != "" && value[0] >= '0' && value[0] <= '9' {
+		address, err := strconv.Atoi(value)
+
+		if err != nil || address > 32767 {
+			assembler.addError(inst, INVALID_ADDRESS, "'%s' is not a valid address", value)
+			return ""
+		}
+
+		return fmt.Sprintf("%016b", address)
+	}
+
+	if !isSymbol(value) {
+		assembler.addError(inst, INVALID_SYMBOL, "'%s' is not a valid symbol", value)
+		return ""
+	}
+
+	address, ok := assembler.symbols[value]
+
+	if !ok && assembler.strict && isLabelLike(value) {
+		assembler.addError(inst, UNDEFINED_LABEL, "label '%s' is not declared", value)
+		return ""
+	}
+
+	if !ok {
+		address = assembler.nextVariable
+		assembler.symbols[value] = address
+		assembler.nextVariable++
+	}
+
+	return fmt.Sprintf("%016b", address)
+}
+
+func (assembler *Assembler) assembleComputation(inst instruction) string {
+	// Whitespace is not significant inside a C-instruction.
+	dest, comp, jump := "", strings.Join(strings.Fields(inst.text), ""), ""
+
+	if index := strings.Index(comp, "="); index >= 0 {
+		dest, comp = comp[:index], comp[index+1:]
+	}
+
+	if index := strings.Index(comp, ";"); index >= 0 {
+		comp, jump = comp[:index], comp[index+1:]
+	}
+
+	destCode, destOk := destCodes[dest]
+	compCode, compOk := compCodes[comp]
+	jumpCode, jumpOk := jumpCodes[jump]
+
+	switch {
+	case !destOk:
+		assembler.addError(inst, INVALID_INSTRUCTION, "'%s' is not a valid destination", dest)
+	case !compOk:
+		assembler.addError(inst, INVALID_INSTRUCTION, "'%s' is not a valid computation", comp)
+	case !jumpOk:
+		assembler.addError(inst, INVALID_INSTRUCTION, "'%s' is not a valid jump", jump)
+	default:
+		return "111" + compCode + destCode + jumpCode
+	}
+
+	return ""
+}
+
+// Assemble translates Hack assembly into the text format of '.hack' files:
+// one instruction per line, written as 16 binary digits. filename is only
+// used to locate errors.
+func (assembler *Assembler) Assemble(filename, source string) (string, error) {
+	assembler.errors = nil
+	assembler.filename = filename
+	assembler.instructions = nil
+	assembler.nextVariable = 16
+	assembler.symbols = map[string]int{}
+
+	for symbol, address := range predefinedSymbols {
+		assembler.symbols[symbol] = address
+	}
+
+	assembler.collectLabels(source)
+
+	if len(assembler.instructions) > 32768 {
+		return "", fmt.Errorf("(%s): the program has %d instructions, more than the 32768 that fit in ROM", filename, len(assembler.instructions))
+	}
+
+	var output strings.Builder
+
+	for _, inst := range assembler.instructions {
+		var code string
+
+		if strings.HasPrefix(inst.text, "@") {
+			code = assembler.assembleAddress(inst)
+		} else {
+			code = assembler.assembleComputation(inst)
+		}
+
+		output.WriteString(code)
+		output.WriteString("\n")
+	}
+
+	if err := assembler.errors.Err(); err != nil {
+		// Labels are checked by the first pass, everything else by the second.
+		sort.SliceStable(assembler.errors, func(i, j int) bool {
+			return assembler.errors[i].Line < assembler.errors[j].Line
+		})
+		return "", err
+	}
+
+	return output.String(), nil
+}
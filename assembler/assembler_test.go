@@ -0,0 +1,153 @@
+package assembler_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/assembler"
+)
+
+func TestAssemble(t *testing.T) {
+	// Computes RAM[2] = max(RAM[0], RAM[1]).
+	source := `// Max.asm
+   @R0
+   D=M              // D = first number
+   @R1
+   D=D-M            // D = first number - second number
+   @OUTPUT_FIRST
+   D;JGT            // if D>0 (first is greater) goto output_first
+   @R1
+   D=M              // D = second number
+   @OUTPUT_D
+   0;JMP            // goto output_d
+(OUTPUT_FIRST)
+   @R0
+   D=M              // D = first number
+(OUTPUT_D)
+   @R2
+   M=D              // M[2] = D (greatest number)
+(INFINITE_LOOP)
+   @INFINITE_LOOP
+   0;JMP            // infinite loop
+   @counter
+   AM = M + 1
+   @SCREEN
+   @counter
+   @other
+`
+	expected := []string{
+		"0000000000000000",
+		"1111110000010000",
+		"0000000000000001",
+		"1111010011010000",
+		"0000000000001010",
+		"1110001100000001",
+		"0000000000000001",
+		"1111110000010000",
+		"0000000000001100",
+		"1110101010000111",
+		"0000000000000000",
+		"1111110000010000",
+		"0000000000000010",
+		"1110001100001000",
+		"0000000000001110",
+		"1110101010000111",
+		"0000000000010000",
+		"1111110111101000",
+		"0100000000000000",
+		"0000000000010000",
+		"0000000000010001",
+	}
+
+	hack, err := NewAssembler().Assemble("Max.asm", source)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := strings.Split(strings.TrimSuffix(hack, "\n"), "\n"); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), hack)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	source := `(LOOP)
+@40000
+  D=Q
+(LOOP)
+@1abc
+AMX=D
+D;JXX
+(BAD LABEL)
+`
+	_, err := NewAssembler().Assemble("Bad.asm", source)
+	errorList, ok := err.(ErrorList)
+
+	if !ok {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+
+	expected := []string{
+		"2:1 INVALID_ADDRESS",
+		"3:3 INVALID_INSTRUCTION",
+		"4:1 DUPLICATE_LABEL",
+		"5:1 INVALID_ADDRESS",
+		"6:1 INVALID_INSTRUCTION",
+		"7:1 INVALID_INSTRUCTION",
+		"8:1 INVALID_SYMBOL",
+	}
+	actual := []string{}
+
+	for _, assemblerError := range errorList {
+		actual = append(actual, fmt.Sprintf("%d:%d %s", assemblerError.Line, assemblerError.Column, assemblerError.Kind))
+	}
+
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	if message := errorList[0].Error(); message != "<Bad.asm:2:1>\tError: '40000' is not a valid address" {
+		t.Errorf("Unexpected error message %q", message)
+	}
+}
+
+func TestAssembleStrictSymbols(t *testing.T) {
+	source := `@Main.loop
+0;JMP
+@Main$ret.0
+0;JMP
+@Main.3
+@counter
+(Main.end)
+@Main.end
+`
+	if _, err := NewAssembler().Assemble("Main.asm", source); err != nil {
+		t.Fatalf("Expected undeclared labels to be variables by default, got %v", err)
+	}
+
+	_, err := NewAssembler(WithStrictSymbols()).Assemble("Main.asm", source)
+	errorList, ok := err.(ErrorList)
+
+	if !ok {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+
+	expected := []string{
+		"1:1 UNDEFINED_LABEL",
+		"3:1 UNDEFINED_LABEL",
+	}
+	actual := []string{}
+
+	for _, assemblerError := range errorList {
+		actual = append(actual, fmt.Sprintf("%d:%d %s", assemblerError.Line, assemblerError.Column, assemblerError.Kind))
+	}
+
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	if message := errorList[0].Error(); message != "<Main.asm:1:1>\tError: label 'Main.loop' is not declared" {
+		t.Errorf("Unexpected error message %q", message)
+	}
+}
@@ -0,0 +1,561 @@
+package astjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+Every node is encoded as a JSON object whose "kind" field names its Go type
+(e.g. "BinaryExpr", "IfStmt"), which lets the Stmt and Expr interfaces be
+decoded back. A document wraps the class with the version of the encoding:
+
+	{ "version": 1, "class": { "kind": "Class", "name": { "kind": "Ident", "name": "Main" }, ... } }
+
+Empty lists are always written as [] and absent optional nodes (the value of
+a bare 'return') as null. Nodes parsed from source also carry an optional
+"span" field with the line, column and byte offset of their first character
+and of the character right after them; the filename is only recorded once,
+on the class. Classes, subroutines and variables parsed with their comments
+carry an optional "doc" field with the text of their doc comment.
+*/
+
+// Version is bumped whenever the encoding changes incompatibly.
+const Version = 1
+
+type object = map[string]any
+
+type DecodeError struct {
+	message string
+}
+
+func (e *DecodeError) Error() string {
+	return e.message
+}
+
+type location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+type span struct {
+	Start location `json:"start"`
+	End   location `json:"end"`
+}
+
+type document struct {
+	Version int             `json:"version"`
+	Class   json.RawMessage `json:"class"`
+}
+
+// withDoc adds the doc comment of a declaration to its encoding, unless it has none.
+func withDoc(encoded object, doc string) object {
+	if doc != "" {
+		encoded["doc"] = doc
+	}
+
+	return encoded
+}
+
+// withSpan adds the span of node to its encoding, unless the node has no position.
+func withSpan(encoded object, node types.Node) object {
+	start, end := node.Pos(), node.End()
+
+	if start.IsValid() {
+		encoded["span"] = span{
+			Start: location{Line: start.Line, Column: start.Column, Offset: start.Offset},
+			End:   location{Line: end.Line, Column: end.Column, Offset: end.Offset},
+		}
+	}
+
+	return encoded
+}
+
+func encodeIdent(ident types.Ident) object {
+	return withSpan(object{"kind": "Ident", "name": ident.Name}, ident)
+}
+
+func encodeVars(vars []types.VarDecl) []any {
+	nodes := []any{}
+
+	for _, decl := range vars {
+		nodes = append(nodes, withDoc(withSpan(object{
+			"kind":       "VarDecl",
+			"name":       decl.Name,
+			"symbolKind": decl.Kind,
+			"type":       decl.Type,
+		}, decl), decl.Doc))
+	}
+
+	return nodes
+}
+
+func encodeExprs(exprs []types.Expr) []any {
+	nodes := []any{}
+
+	for _, expr := range exprs {
+		nodes = append(nodes, encodeExpr(expr))
+	}
+
+	return nodes
+}
+
+func encodeExpr(expr types.Expr) any {
+	if expr == nil {
+		return nil
+	}
+
+	return withSpan(encodeExprFields(expr), expr)
+}
+
+func encodeExprFields(expr types.Expr) object {
+	switch expr := expr.(type) {
+	case types.BinaryExpr:
+		return object{"kind": "BinaryExpr", "operator": expr.Operator, "left": encodeExpr(expr.Left), "right": encodeExpr(expr.Right)}
+
+	case types.CallExpr:
+		return object{"kind": "CallExpr", "callee": encodeExpr(expr.Callee), "arguments": encodeExprs(expr.Arguments)}
+
+	case types.Ident:
+		return object{"kind": "Ident", "name": expr.Name}
+
+	case types.IndexExpr:
+		return object{"kind": "IndexExpr", "object": encodeIdent(expr.Object), "indexer": encodeExpr(expr.Indexer)}
+
+	case types.Literal:
+		return object{"kind": "Literal", "type": expr.Type, "value": expr.Value}
+
+	case types.LogicalExpr:
+		return object{"kind": "LogicalExpr", "operator": expr.Operator, "left": encodeExpr(expr.Left), "right": encodeExpr(expr.Right)}
+
+	case types.MemberExpr:
+		return object{"kind": "MemberExpr", "object": encodeIdent(expr.Object), "property": encodeIdent(expr.Property)}
+
+	case types.ParenExpr:
+		return object{"kind": "ParenExpr", "expression": encodeExpr(expr.Expression)}
+
+	case types.UnaryExpr:
+		return object{"kind": "UnaryExpr", "operator": expr.Operator, "operand": encodeExpr(expr.Operand)}
+	}
+
+	panic(fmt.Sprintf("astjson: unsupported expression %T", expr))
+}
+
+func encodeBlock(block types.BlockStmt) object {
+	return withSpan(object{"kind": "BlockStmt", "statements": encodeStmts(block.Statements)}, block)
+}
+
+func encodeStmts(stmts []types.Stmt) []any {
+	nodes := []any{}
+
+	for _, stmt := range stmts {
+		nodes = append(nodes, encodeStmt(stmt))
+	}
+
+	return nodes
+}
+
+func encodeStmt(stmt types.Stmt) any {
+	return withSpan(encodeStmtFields(stmt), stmt)
+}
+
+func encodeStmtFields(stmt types.Stmt) object {
+	switch stmt := stmt.(type) {
+	case types.BlockStmt:
+		return encodeBlock(stmt)
+
+	case types.DoStmt:
+		return object{"kind": "DoStmt", "expression": encodeExpr(stmt.Expression)}
+
+	case types.IfStmt:
+		return object{"kind": "IfStmt", "condition": encodeExpr(stmt.Condition), "then": encodeBlock(stmt.ThenStmt), "else": encodeBlock(stmt.ElseStmt)}
+
+	case types.LetStmt:
+		return object{"kind": "LetStmt", "target": encodeExpr(stmt.Target), "value": encodeExpr(stmt.Value)}
+
+	case types.ReturnStmt:
+		return object{"kind": "ReturnStmt", "expression": encodeExpr(stmt.Expression)}
+
+	case types.WhileStmt:
+		return object{"kind": "WhileStmt", "condition": encodeExpr(stmt.Condition), "body": encodeBlock(stmt.Body)}
+	}
+
+	panic(fmt.Sprintf("astjson: unsupported statement %T", stmt))
+}
+
+func encodeSubroutine(subroutine types.SubroutineDecl) object {
+	params := []any{}
+
+	for _, param := range subroutine.Params {
+		params = append(params, withSpan(object{"kind": "Parameter", "name": param.Name, "type": param.Type}, param))
+	}
+
+	return withDoc(withSpan(object{
+		"kind":       "SubroutineDecl",
+		"name":       encodeIdent(subroutine.Name),
+		"symbolKind": subroutine.Kind,
+		"type":       subroutine.Type,
+		"params":     params,
+		"body": withSpan(object{
+			"kind":       "SubroutineBody",
+			"vars":       encodeVars(subroutine.Body.Vars),
+			"statements": encodeStmts(subroutine.Body.Statements),
+		}, subroutine.Body),
+	}, subroutine), subroutine.Doc)
+}
+
+func encodeClass(class types.Class) object {
+	subroutines := []any{}
+
+	for _, subroutine := range class.Subroutines {
+		subroutines = append(subroutines, encodeSubroutine(subroutine))
+	}
+
+	encoded := withSpan(object{
+		"kind":        "Class",
+		"name":        encodeIdent(class.Name),
+		"vars":        encodeVars(class.Vars),
+		"subroutines": subroutines,
+	}, class)
+	withDoc(encoded, class.Doc)
+
+	if filename := class.Pos().Filename; filename != "" {
+		encoded["filename"] = filename
+	}
+
+	return encoded
+}
+
+// Marshal returns the versioned JSON encoding of class.
+func Marshal(class types.Class) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			message, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			err = errors.New(message)
+		}
+	}()
+
+	return json.MarshalIndent(object{"version": Version, "class": encodeClass(class)}, "", "  ")
+}
+
+type decoder struct {
+	filename string
+}
+
+func (d *decoder) emitError(format string, args ...any) {
+	panic(&DecodeError{message: fmt.Sprintf("astjson: %s", fmt.Sprintf(format, args...))})
+}
+
+// fields splits a node into its fields and checks that it has the expected kind.
+func (d *decoder) fields(data json.RawMessage, kinds ...string) (string, map[string]json.RawMessage) {
+	var node map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &node); err != nil || node == nil {
+		d.emitError("expected a %v node, got %s", kinds, data)
+	}
+
+	var kind string
+	if err := json.Unmarshal(node["kind"], &kind); err != nil {
+		d.emitError("node without a \"kind\": %s", data)
+	}
+
+	for _, expected := range kinds {
+		if kind == expected {
+			return kind, node
+		}
+	}
+
+	d.emitError("expected a %v node, got %q", kinds, kind)
+	return "", nil
+}
+
+func (d *decoder) string(node map[string]json.RawMessage, key string) string {
+	var value string
+
+	if err := json.Unmarshal(node[key], &value); err != nil {
+		d.emitError("field %q must be a string", key)
+	}
+
+	return value
+}
+
+func (d *decoder) list(node map[string]json.RawMessage, key string) []json.RawMessage {
+	var values []json.RawMessage
+
+	if err := json.Unmarshal(node[key], &values); err != nil {
+		d.emitError("field %q must be a list", key)
+	}
+
+	return values
+}
+
+// doc returns the optional doc comment of a declaration.
+func (d *decoder) doc(node map[string]json.RawMessage) string {
+	if d.isNull(node["doc"]) {
+		return ""
+	}
+
+	return d.string(node, "doc")
+}
+
+func (d *decoder) isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
+
+func (d *decoder) oneOf(key, value string, allowed ...string) string {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return value
+		}
+	}
+
+	d.emitError("invalid %s %q", key, value)
+	return ""
+}
+
+func (d *decoder) span(node map[string]json.RawMessage) types.Span {
+	var decoded span
+
+	if d.isNull(node["span"]) {
+		return types.Span{}
+	}
+
+	if err := json.Unmarshal(node["span"], &decoded); err != nil {
+		d.emitError("invalid span: %s", node["span"])
+	}
+
+	position := func(loc location) types.Position {
+		return types.Position{Filename: d.filename, Line: loc.Line, Column: loc.Column, Offset: loc.Offset}
+	}
+
+	return types.Span{StartPos: position(decoded.Start), EndPos: position(decoded.End)}
+}
+
+func (d *decoder) decodeIdent(data json.RawMessage) types.Ident {
+	_, node := d.fields(data, "Ident")
+	return types.Ident{Span: d.span(node), Name: d.string(node, "name")}
+}
+
+func (d *decoder) decodeVars(node map[string]json.RawMessage) (vars []types.VarDecl) {
+	for _, data := range d.list(node, "vars") {
+		_, decl := d.fields(data, "VarDecl")
+		kind := d.oneOf("symbolKind", d.string(decl, "symbolKind"), string(types.Field), string(types.Static), string(types.Var))
+
+		vars = append(vars, types.VarDecl{
+			Span: d.span(decl),
+			Doc:  d.doc(decl),
+			Name: d.string(decl, "name"),
+			Kind: types.SymbolKind(kind),
+			Type: d.string(decl, "type"),
+		})
+	}
+
+	return vars
+}
+
+func (d *decoder) decodeExprs(node map[string]json.RawMessage, key string) (exprs []types.Expr) {
+	for _, data := range d.list(node, key) {
+		exprs = append(exprs, d.decodeExpr(data))
+	}
+
+	return exprs
+}
+
+func (d *decoder) decodeCall(data json.RawMessage) types.CallExpr {
+	_, node := d.fields(data, "CallExpr")
+	var callee types.Expr
+
+	if kind, calleeNode := d.fields(node["callee"], "Ident", "MemberExpr"); kind == "Ident" {
+		callee = d.decodeIdent(node["callee"])
+	} else {
+		callee = types.MemberExpr{
+			Span:     d.span(calleeNode),
+			Object:   d.decodeIdent(calleeNode["object"]),
+			Property: d.decodeIdent(calleeNode["property"]),
+		}
+	}
+
+	return types.CallExpr{Span: d.span(node), Callee: callee, Arguments: d.decodeExprs(node, "arguments")}
+}
+
+func (d *decoder) decodeExpr(data json.RawMessage) types.Expr {
+	kind, node := d.fields(
+		data,
+		"BinaryExpr", "CallExpr", "Ident", "IndexExpr", "Literal",
+		"LogicalExpr", "MemberExpr", "ParenExpr", "UnaryExpr",
+	)
+
+	switch kind {
+	case "BinaryExpr":
+		operator := d.oneOf("operator", d.string(node, "operator"), "+", "-", "*", "/", "<", ">", "=")
+		return types.BinaryExpr{
+			Span:     d.span(node),
+			Operator: types.BinaryOperator(operator),
+			Left:     d.decodeExpr(node["left"]),
+			Right:    d.decodeExpr(node["right"]),
+		}
+
+	case "CallExpr":
+		return d.decodeCall(data)
+
+	case "Ident":
+		return d.decodeIdent(data)
+
+	case "IndexExpr":
+		return types.IndexExpr{Span: d.span(node), Object: d.decodeIdent(node["object"]), Indexer: d.decodeExpr(node["indexer"])}
+
+	case "Literal":
+		literalType := d.oneOf(
+			"literal type",
+			d.string(node, "type"),
+			string(types.BooleanLiteral), string(types.IntegerLiteral), string(types.NullLiteral),
+			string(types.StringLiteral), string(types.ThisLiteral),
+		)
+		return types.Literal{Span: d.span(node), Type: types.LiteralType(literalType), Value: d.string(node, "value")}
+
+	case "LogicalExpr":
+		operator := d.oneOf("operator", d.string(node, "operator"), "&", "|")
+		return types.LogicalExpr{
+			Span:     d.span(node),
+			Operator: types.LogicalOperator(operator),
+			Left:     d.decodeExpr(node["left"]),
+			Right:    d.decodeExpr(node["right"]),
+		}
+
+	case "MemberExpr":
+		return types.MemberExpr{Span: d.span(node), Object: d.decodeIdent(node["object"]), Property: d.decodeIdent(node["property"])}
+
+	case "ParenExpr":
+		return types.ParenExpr{Span: d.span(node), Expression: d.decodeExpr(node["expression"])}
+
+	default:
+		operator := d.oneOf("operator", d.string(node, "operator"), "-", "~")
+		return types.UnaryExpr{Span: d.span(node), Operator: types.UnaryOperator(operator), Operand: d.decodeExpr(node["operand"])}
+	}
+}
+
+func (d *decoder) decodeBlock(data json.RawMessage) types.BlockStmt {
+	_, node := d.fields(data, "BlockStmt")
+	return types.BlockStmt{Span: d.span(node), Statements: d.decodeStmts(node)}
+}
+
+func (d *decoder) decodeStmts(node map[string]json.RawMessage) (stmts []types.Stmt) {
+	for _, data := range d.list(node, "statements") {
+		stmts = append(stmts, d.decodeStmt(data))
+	}
+
+	return stmts
+}
+
+func (d *decoder) decodeStmt(data json.RawMessage) types.Stmt {
+	kind, node := d.fields(data, "BlockStmt", "DoStmt", "IfStmt", "LetStmt", "ReturnStmt", "WhileStmt")
+
+	switch kind {
+	case "BlockStmt":
+		return d.decodeBlock(data)
+
+	case "DoStmt":
+		return types.DoStmt{Span: d.span(node), Expression: d.decodeCall(node["expression"])}
+
+	case "IfStmt":
+		return types.IfStmt{
+			Span:      d.span(node),
+			Condition: d.decodeExpr(node["condition"]),
+			ThenStmt:  d.decodeBlock(node["then"]),
+			ElseStmt:  d.decodeBlock(node["else"]),
+		}
+
+	case "LetStmt":
+		target := node["target"]
+		d.fields(target, "Ident", "IndexExpr")
+		return types.LetStmt{Span: d.span(node), Target: d.decodeExpr(target), Value: d.decodeExpr(node["value"])}
+
+	case "ReturnStmt":
+		stmt := types.ReturnStmt{Span: d.span(node)}
+		if !d.isNull(node["expression"]) {
+			stmt.Expression = d.decodeExpr(node["expression"])
+		}
+		return stmt
+
+	default:
+		return types.WhileStmt{Span: d.span(node), Condition: d.decodeExpr(node["condition"]), Body: d.decodeBlock(node["body"])}
+	}
+}
+
+func (d *decoder) decodeSubroutine(data json.RawMessage) types.SubroutineDecl {
+	_, node := d.fields(data, "SubroutineDecl")
+	_, body := d.fields(node["body"], "SubroutineBody")
+	kind := d.oneOf("symbolKind", d.string(node, "symbolKind"), string(types.Constructor), string(types.Function), string(types.Method))
+
+	subroutine := types.SubroutineDecl{
+		Span: d.span(node),
+		Doc:  d.doc(node),
+		Name: d.decodeIdent(node["name"]),
+		Kind: types.SymbolKind(kind),
+		Type: d.string(node, "type"),
+		Body: types.SubroutineBody{
+			Span:       d.span(body),
+			Statements: d.decodeStmts(body),
+			Vars:       d.decodeVars(body),
+		},
+	}
+
+	for _, paramData := range d.list(node, "params") {
+		_, param := d.fields(paramData, "Parameter")
+		subroutine.Params = append(subroutine.Params, types.Parameter{Span: d.span(param), Name: d.string(param, "name"), Type: d.string(param, "type")})
+	}
+
+	return subroutine
+}
+
+func (d *decoder) decodeClass(data json.RawMessage) (class types.Class) {
+	_, node := d.fields(data, "Class")
+
+	if !d.isNull(node["filename"]) {
+		d.filename = d.string(node, "filename")
+	}
+
+	class.Span = d.span(node)
+	class.Doc = d.doc(node)
+	class.Name = d.decodeIdent(node["name"])
+	class.Vars = d.decodeVars(node)
+
+	for _, subroutine := range d.list(node, "subroutines") {
+		class.Subroutines = append(class.Subroutines, d.decodeSubroutine(subroutine))
+	}
+
+	return class
+}
+
+// Unmarshal reconstructs a class from its versioned JSON encoding.
+func Unmarshal(data []byte) (class types.Class, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var decodeError *DecodeError
+			if e, ok := r.(error); ok && errors.As(e, &decodeError) {
+				err = decodeError
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	var doc document
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return class, err
+	}
+
+	if doc.Version != Version {
+		return class, &DecodeError{message: fmt.Sprintf("astjson: unsupported version %d, expected %d", doc.Version, Version)}
+	}
+
+	return new(decoder).decodeClass(doc.Class), nil
+}
@@ -0,0 +1,113 @@
+package astjson_test
+
+import (
+	"bytes"
+	"path"
+	"reflect"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/astjson"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	. "github.com/MlkMahmud/jack-compiler/parser"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func TestRoundTrip(t *testing.T) {
+	files := []string{"Array", "Square", "SquareGame"}
+	lexer := lexer.NewLexer()
+	parser := NewParser()
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			tokens, err := lexer.Tokenize(path.Join(TEST_DATA_PATH, file+".jack"))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			class, err := parser.Parse(tokens)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := Marshal(class)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := Unmarshal(data)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(class, decoded) {
+				t.Errorf("Expected decoded class to equal the parsed class")
+			}
+		})
+	}
+}
+
+func TestRoundTripWithDocComments(t *testing.T) {
+	// Doc comments are only kept by a lexer that keeps comments.
+	tokens, err := lexer.NewLexer(lexer.WithComments()).Tokenize(path.Join(TEST_DATA_PATH, "Square.jack"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := NewParser().Parse(tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if class.Doc == "" || class.Subroutines[0].Doc == "" {
+		t.Fatalf("Expected the class and its constructor to have doc comments")
+	}
+
+	data, err := Marshal(class)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(data, []byte(`"doc":`)) {
+		t.Errorf("Expected the doc comments to be encoded")
+	}
+
+	decoded, err := Unmarshal(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(class, decoded) {
+		t.Errorf("Expected decoded class to equal the parsed class")
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := map[string]string{
+		"UnsupportedVersion": `{"version": 99, "class": {}}`,
+		"MissingKind":        `{"version": 1, "class": {"name": {"kind": "Ident", "name": "Main"}}}`,
+		"UnknownStatement": `{"version": 1, "class": {"kind": "Class", "name": {"kind": "Ident", "name": "Main"}, "vars": [], "subroutines": [
+			{"kind": "SubroutineDecl", "name": {"kind": "Ident", "name": "main"}, "symbolKind": "function", "type": "void", "params": [],
+			 "body": {"kind": "SubroutineBody", "vars": [], "statements": [{"kind": "GotoStmt"}]}}]}}`,
+		"InvalidOperator": `{"version": 1, "class": {"kind": "Class", "name": {"kind": "Ident", "name": "Main"}, "vars": [], "subroutines": [
+			{"kind": "SubroutineDecl", "name": {"kind": "Ident", "name": "main"}, "symbolKind": "function", "type": "int", "params": [],
+			 "body": {"kind": "SubroutineBody", "vars": [], "statements": [{"kind": "ReturnStmt", "expression":
+			   {"kind": "BinaryExpr", "operator": "%", "left": {"kind": "Ident", "name": "a"}, "right": {"kind": "Ident", "name": "b"}}}]}}]}}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Unmarshal([]byte(data)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+Jack is weakly typed: the VM only knows 16-bit words, so 'true' is -1, 'null'
+is 0 and an object is the address of its fields. The checker works with the
+declared type names ('int', 'char', 'boolean', 'void' or a class name) and
+has two modes:
+
+  - Lenient mirrors what the language lets through: int, char and boolean are
+    interchangeable, null is assignable to any type and Array is
+    interchangeable with any class, since it is the usual way to handle raw
+    memory.
+  - Strict only allows a type to be assigned to itself, null to class types
+    and any object to an Array, which is how memory is released with
+    'Memory.deAlloc(this)'. Characters can still be compared with integers,
+    since Jack has no character literals.
+
+In both modes a primitive cannot be indexed and a void subroutine cannot be
+used as a value. Expressions whose type cannot be known, such as the element
+of an Array or the result of a call into a class outside of the program, are
+compatible with every type.
+*/
+
+type Mode int
+
+const (
+	Lenient Mode = iota
+	Strict
+)
+
+func (mode Mode) String() string {
+	return []string{"lenient", "strict"}[mode]
+}
+
+type DiagnosticKind int
+
+const (
+	INVALID_CONDITION DiagnosticKind = iota
+	INVALID_INDEX
+	INVALID_OPERAND
+	INVALID_RETURN
+	TYPE_MISMATCH
+	VOID_VALUE
+)
+
+func (kind DiagnosticKind) String() string {
+	return []string{
+		"INVALID_CONDITION", "INVALID_INDEX", "INVALID_OPERAND",
+		"INVALID_RETURN", "TYPE_MISMATCH", "VOID_VALUE",
+	}[kind]
+}
+
+type Diagnostic struct {
+	End     types.Position
+	Kind    DiagnosticKind
+	Message string
+	Pos     types.Position
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf(
+		"(%s):[%d:%d]: Type error: %s",
+		d.Pos.Filename,
+		d.Pos.Line,
+		d.Pos.Column,
+		d.Message,
+	)
+}
+
+// DiagnosticList is the list of every diagnostic reported for a class, in source order.
+type DiagnosticList []*Diagnostic
+
+func (list DiagnosticList) Error() string {
+	messages := make([]string, 0, len(list))
+
+	for _, diagnostic := range list {
+		messages = append(messages, diagnostic.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Err returns the list as an error, or nil when it is empty.
+func (list DiagnosticList) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+const (
+	booleanType = "boolean"
+	charType    = "char"
+	intType     = "int"
+	voidType    = "void"
+	// nullType is the type of the 'null' literal.
+	nullType = "null"
+	// unknownType is the type of expressions that cannot be typed.
+	unknownType = ""
+)
+
+func isPrimitive(typeName string) bool {
+	return typeName == booleanType || typeName == charType || typeName == intType
+}
+
+func isClass(typeName string) bool {
+	return typeName != unknownType && typeName != nullType && typeName != voidType && !isPrimitive(typeName)
+}
+
+type Checker struct {
+	class       types.Class
+	classes     map[string]types.Class
+	diagnostics DiagnosticList
+	mode        Mode
+	subroutine  types.SubroutineDecl
+}
+
+func NewChecker(mode Mode) *Checker {
+	return &Checker{mode: mode}
+}
+
+func (checker *Checker) report(node types.Node, kind DiagnosticKind, format string, args ...any) {
+	checker.diagnostics = append(checker.diagnostics, &Diagnostic{
+		End:     node.End(),
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+		Pos:     node.Pos(),
+	})
+}
+
+// assignable reports whether a value of type from can be stored where a value of type to is expected.
+func (checker *Checker) assignable(from, to string) bool {
+	if from == unknownType || to == unknownType || from == to {
+		return true
+	}
+
+	if from == nullType {
+		return checker.mode == Lenient || isClass(to)
+	}
+
+	if checker.mode == Strict {
+		return to == "Array" && isClass(from)
+	}
+
+	if isPrimitive(from) && isPrimitive(to) {
+		return true
+	}
+
+	return (from == "Array" && isClass(to)) || (to == "Array" && isClass(from))
+}
+
+// numeric reports whether a value of the given type can be used in arithmetic.
+func (checker *Checker) numeric(typeName string) bool {
+	if typeName == unknownType || typeName == intType {
+		return true
+	}
+	return checker.mode == Lenient && isPrimitive(typeName)
+}
+
+// ordered reports whether values of the given type can be compared with integers.
+func (checker *Checker) ordered(typeName string) bool {
+	return typeName == charType || checker.numeric(typeName)
+}
+
+func (checker *Checker) checkOperand(expr types.Expr, operator string, valid func(string) bool) string {
+	typeName := checker.typeOf(expr)
+
+	if !valid(typeName) {
+		checker.report(expr, INVALID_OPERAND, "operator '%s' cannot be applied to a value of type '%s'", operator, typeName)
+	}
+
+	return typeName
+}
+
+// lookupSubroutine finds the subroutine a call refers to. It returns false when
+// the class it belongs to is not part of the program.
+func (checker *Checker) lookupSubroutine(expr types.CallExpr) (types.SubroutineDecl, bool) {
+	var className, name string
+
+	switch callee := expr.Callee.(type) {
+	case types.Ident:
+		className, name = checker.class.Name.Name, callee.Name
+	case types.MemberExpr:
+		className, name = callee.Object.Name, callee.Property.Name
+		// 'foo.bar()' calls a method on the class of the variable 'foo'.
+		if callee.Object.Symbol != nil {
+			className = callee.Object.Symbol.Type
+		}
+	default:
+		return types.SubroutineDecl{}, false
+	}
+
+	class, ok := checker.classes[className]
+
+	if !ok {
+		return types.SubroutineDecl{}, false
+	}
+
+	for _, subroutine := range class.Subroutines {
+		if subroutine.Name.Name == name {
+			return subroutine, true
+		}
+	}
+
+	return types.SubroutineDecl{}, false
+}
+
+// checkCall checks the arguments of a call and returns the type it evaluates to.
+func (checker *Checker) checkCall(expr types.CallExpr) string {
+	subroutine, ok := checker.lookupSubroutine(expr)
+	argTypes := make([]string, 0, len(expr.Arguments))
+
+	for _, arg := range expr.Arguments {
+		argTypes = append(argTypes, checker.typeOf(arg))
+	}
+
+	if !ok {
+		return unknownType
+	}
+
+	// Wrong argument counts are reported when the program is linked.
+	if len(argTypes) == len(subroutine.Params) {
+		for index, param := range subroutine.Params {
+			if !checker.assignable(argTypes[index], param.Type) {
+				checker.report(
+					expr.Arguments[index],
+					TYPE_MISMATCH,
+					"cannot pass a value of type '%s' as argument '%s' of type '%s' of '%s'",
+					argTypes[index],
+					param.Name,
+					param.Type,
+					expr.Callee,
+				)
+			}
+		}
+	}
+
+	return subroutine.Type
+}
+
+// typeOf checks an expression used as a value and returns its type.
+func (checker *Checker) typeOf(expr types.Expr) string {
+	switch expr := expr.(type) {
+	case types.BinaryExpr:
+		switch expr.Operator {
+		case types.Equals:
+			left, right := checker.typeOf(expr.Left), checker.typeOf(expr.Right)
+			if !checker.assignable(left, right) && !checker.assignable(right, left) && !(checker.ordered(left) && checker.ordered(right)) {
+				checker.report(expr, TYPE_MISMATCH, "cannot compare a value of type '%s' with a value of type '%s'", left, right)
+			}
+			return booleanType
+
+		case types.LessThan, types.GreaterThan:
+			checker.checkOperand(expr.Left, string(expr.Operator), checker.ordered)
+			checker.checkOperand(expr.Right, string(expr.Operator), checker.ordered)
+			return booleanType
+		}
+
+		checker.checkOperand(expr.Left, string(expr.Operator), checker.numeric)
+		checker.checkOperand(expr.Right, string(expr.Operator), checker.numeric)
+		return intType
+
+	case types.CallExpr:
+		typeName := checker.checkCall(expr)
+		if typeName == voidType {
+			checker.report(expr, VOID_VALUE, "'%s' does not return a value", expr.Callee)
+			return unknownType
+		}
+		return typeName
+
+	case types.Ident:
+		if expr.Symbol == nil {
+			return unknownType
+		}
+		return expr.Symbol.Type
+
+	case types.IndexExpr:
+		checker.checkIndex(expr)
+		return unknownType
+
+	case types.Literal:
+		switch expr.Type {
+		case types.BooleanLiteral:
+			return booleanType
+		case types.IntegerLiteral:
+			return intType
+		case types.NullLiteral:
+			return nullType
+		case types.StringLiteral:
+			return "String"
+		case types.ThisLiteral:
+			return checker.class.Name.Name
+		}
+		return unknownType
+
+	case types.LogicalExpr:
+		// '&' and '|' are bitwise, so they also apply to integers.
+		bitwise := func(typeName string) bool {
+			return typeName == booleanType || checker.numeric(typeName)
+		}
+		left := checker.checkOperand(expr.Left, string(expr.Operator), bitwise)
+		right := checker.checkOperand(expr.Right, string(expr.Operator), bitwise)
+		if left == booleanType && right == booleanType {
+			return booleanType
+		}
+		if checker.mode == Strict && (left == booleanType || right == booleanType) {
+			checker.report(expr, TYPE_MISMATCH, "operator '%s' cannot mix a value of type '%s' with a value of type '%s'", expr.Operator, left, right)
+		}
+		if left == booleanType || right == booleanType {
+			return booleanType
+		}
+		return intType
+
+	case types.ParenExpr:
+		return checker.typeOf(expr.Expression)
+
+	case types.UnaryExpr:
+		if expr.Operator == types.BooleanNegation {
+			return checker.checkOperand(expr.Operand, string(expr.Operator), func(typeName string) bool {
+				return typeName == booleanType || checker.numeric(typeName)
+			})
+		}
+		checker.checkOperand(expr.Operand, string(expr.Operator), checker.numeric)
+		return intType
+	}
+
+	return unknownType
+}
+
+func (checker *Checker) checkIndex(expr types.IndexExpr) {
+	objectType := checker.typeOf(expr.Object)
+
+	if isPrimitive(objectType) || (checker.mode == Strict && objectType != "Array" && objectType != unknownType) {
+		checker.report(expr.Object, INVALID_INDEX, "'%s' of type '%s' cannot be indexed", expr.Object, objectType)
+	}
+
+	checker.checkOperand(expr.Indexer, "[]", checker.numeric)
+}
+
+func (checker *Checker) checkCondition(expr types.Expr) {
+	typeName := checker.typeOf(expr)
+
+	if typeName == booleanType || typeName == unknownType || (checker.mode == Lenient && isPrimitive(typeName)) {
+		return
+	}
+
+	checker.report(expr, INVALID_CONDITION, "a condition must be a boolean, got a value of type '%s'", typeName)
+}
+
+func (checker *Checker) checkReturn(stmt types.ReturnStmt) {
+	returnType := checker.subroutine.Type
+
+	if stmt.Expression == nil {
+		if returnType != voidType {
+			checker.report(stmt, INVALID_RETURN, "'%s' must return a value of type '%s'", checker.subroutine.Name, returnType)
+		}
+		return
+	}
+
+	valueType := checker.typeOf(stmt.Expression)
+
+	if returnType == voidType {
+		checker.report(stmt.Expression, INVALID_RETURN, "'%s' is void and cannot return a value", checker.subroutine.Name)
+	} else if !checker.assignable(valueType, returnType) {
+		checker.report(stmt.Expression, TYPE_MISMATCH, "cannot return a value of type '%s' from '%s' of type '%s'", valueType, checker.subroutine.Name, returnType)
+	}
+}
+
+func (checker *Checker) checkStatement(node types.Node) bool {
+	switch stmt := node.(type) {
+	case types.DoStmt:
+		// The value of the call, if any, is discarded.
+		checker.checkCall(stmt.Expression)
+
+	case types.IfStmt:
+		checker.checkCondition(stmt.Condition)
+
+	case types.LetStmt:
+		targetType := unknownType
+		switch target := stmt.Target.(type) {
+		case types.Ident:
+			targetType = checker.typeOf(target)
+		case types.IndexExpr:
+			checker.checkIndex(target)
+		}
+		if valueType := checker.typeOf(stmt.Value); !checker.assignable(valueType, targetType) {
+			checker.report(stmt.Value, TYPE_MISMATCH, "cannot assign a value of type '%s' to '%s' of type '%s'", valueType, stmt.Target, targetType)
+		}
+
+	case types.ReturnStmt:
+		checker.checkReturn(stmt)
+
+	case types.WhileStmt:
+		checker.checkCondition(stmt.Condition)
+
+	case types.Expr:
+		// Expressions are checked by the statement they belong to.
+		return false
+	}
+
+	return true
+}
+
+// Check type checks a class resolved by the resolver package. program holds
+// the classes it can call into; it may include the class itself.
+func (checker *Checker) Check(class types.Class, program []types.Class) DiagnosticList {
+	checker.class = class
+	checker.diagnostics = nil
+	checker.classes = map[string]types.Class{}
+
+	for _, other := range program {
+		checker.classes[other.Name.Name] = other
+	}
+	checker.classes[class.Name.Name] = class
+
+	for _, subroutine := range class.Subroutines {
+		checker.subroutine = subroutine
+		types.Inspect(subroutine.Body, checker.checkStatement)
+	}
+
+	checker.subroutine = types.SubroutineDecl{}
+	return checker.diagnostics
+}
@@ -0,0 +1,135 @@
+package checker_test
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/checker"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	. "github.com/MlkMahmud/jack-compiler/parser"
+	. "github.com/MlkMahmud/jack-compiler/resolver"
+	. "github.com/MlkMahmud/jack-compiler/types"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func resolveFile(t *testing.T, filePath string) Class {
+	tokens, err := lexer.NewLexer().Tokenize(filePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := NewParser().Parse(tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err = NewResolver().Resolve(class)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return class
+}
+
+func resolveSource(t *testing.T, source string) Class {
+	filePath := path.Join(t.TempDir(), "Main.jack")
+
+	if err := os.WriteFile(filePath, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return resolveFile(t, filePath)
+}
+
+func TestCheckValidProgram(t *testing.T) {
+	program := []Class{}
+
+	for _, file := range []string{"Square", "SquareGame"} {
+		program = append(program, resolveFile(t, path.Join(TEST_DATA_PATH, file+".jack")))
+	}
+
+	for _, mode := range []Mode{Lenient, Strict} {
+		for _, class := range program {
+			if diagnostics := NewChecker(mode).Check(class, program); len(diagnostics) > 0 {
+				t.Errorf("Expected %s to type check in %s mode, got:\n%s", class.Name, mode, diagnostics)
+			}
+		}
+	}
+}
+
+func TestCheckDiagnostics(t *testing.T) {
+	helper := resolveSource(t, `class Helper {
+  function void log(int n) { return; }
+  function int twice(int n) { return n + n; }
+}`)
+
+	tests := []struct {
+		name      string
+		body      string
+		lenient   []DiagnosticKind
+		strict    []DiagnosticKind
+		locations string
+	}{
+		{"int to char", "var char c; let c = 65;", nil, []DiagnosticKind{TYPE_MISMATCH}, "[4:25]"},
+		{"string to int", `var int x; let x = "a";`, []DiagnosticKind{TYPE_MISMATCH}, []DiagnosticKind{TYPE_MISMATCH}, "[4:24]"},
+		{"null to class", "var String s; let s = null;", nil, nil, "[]"},
+		{"null to int", "var int x; let x = null;", nil, []DiagnosticKind{TYPE_MISMATCH}, "[4:24]"},
+		{"index an int", "var int x; let x[0] = 1;", []DiagnosticKind{INVALID_INDEX}, []DiagnosticKind{INVALID_INDEX}, "[4:20]"},
+		{"index a String", "var String s; let s[0] = 1;", nil, []DiagnosticKind{INVALID_INDEX}, "[4:23]"},
+		{"int condition", "var int x; while (x) { let x = x - 1; }", nil, []DiagnosticKind{INVALID_CONDITION}, "[4:23]"},
+		{"object condition", "var String s; if (s) { }", []DiagnosticKind{INVALID_CONDITION}, []DiagnosticKind{INVALID_CONDITION}, "[4:23]"},
+		{"void in expression", "var int x; let x = 1 + Helper.log(2);", []DiagnosticKind{VOID_VALUE}, []DiagnosticKind{VOID_VALUE}, "[4:28]"},
+		{"void in do", "do Helper.log(Helper.twice(2));", nil, nil, "[]"},
+		{"argument type", `do Helper.log("a");`, []DiagnosticKind{TYPE_MISMATCH}, []DiagnosticKind{TYPE_MISMATCH}, "[4:19]"},
+		{"return a value from void", "return 1;", []DiagnosticKind{INVALID_RETURN}, []DiagnosticKind{INVALID_RETURN}, "[4:12]"},
+		{"arithmetic on a class", "var String s; var int x; let x = s + 1;", []DiagnosticKind{INVALID_OPERAND}, []DiagnosticKind{INVALID_OPERAND}, "[4:38]"},
+		{"bitwise and on int", "var int x; let x = x & 1;", nil, nil, "[]"},
+		{"unknown class", "var int x; let x = Foo.bar();", nil, nil, "[]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			class := resolveSource(t, fmt.Sprintf(`class Main {
+  field int count;
+  method void run() {
+    %s
+    return;
+  }
+}`, test.body))
+
+			program := []Class{helper, class}
+			modes := []struct {
+				mode     Mode
+				expected []DiagnosticKind
+			}{
+				{Lenient, test.lenient},
+				{Strict, test.strict},
+			}
+
+			for _, mode := range modes {
+				diagnostics := NewChecker(mode.mode).Check(class, program)
+				kinds := []DiagnosticKind{}
+				locations := []string{}
+
+				for _, diagnostic := range diagnostics {
+					kinds = append(kinds, diagnostic.Kind)
+					locations = append(locations, fmt.Sprintf("%d:%d", diagnostic.Pos.Line, diagnostic.Pos.Column))
+				}
+
+				if fmt.Sprint(kinds) != fmt.Sprint(mode.expected) {
+					t.Errorf("Expected %v in %s mode, got %v", mode.expected, mode.mode, diagnostics)
+				}
+
+				if len(mode.expected) > 0 && fmt.Sprint(locations) != test.locations {
+					t.Errorf("Expected diagnostics at %s in %s mode, got %v", test.locations, mode.mode, locations)
+				}
+			}
+		})
+	}
+}
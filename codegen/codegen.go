@@ -0,0 +1,369 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+VM COMMANDS
+
+arithmetic:      'add' | 'sub' | 'neg' | 'eq' | 'gt' | 'lt' | 'and' | 'or' | 'not'
+
+memory access:   ('push' | 'pop') segment index
+
+segment:         'argument' | 'local' | 'static' | 'constant' | 'this' | 'that' | 'pointer' | 'temp'
+
+program flow:    ('label' | 'goto' | 'if-goto') label
+
+function:        'function' functionName nVars | 'call' functionName nArgs | 'return'
+*/
+
+type Segment string
+
+const (
+	ArgumentSegment Segment = "argument"
+	ConstantSegment Segment = "constant"
+	LocalSegment    Segment = "local"
+	PointerSegment  Segment = "pointer"
+	StaticSegment   Segment = "static"
+	TempSegment     Segment = "temp"
+	ThatSegment     Segment = "that"
+	ThisSegment     Segment = "this"
+)
+
+var segments = map[types.SymbolKind]Segment{
+	types.Argument: ArgumentSegment,
+	types.Field:    ThisSegment,
+	types.Static:   StaticSegment,
+	types.Var:      LocalSegment,
+}
+
+var binaryCommands = map[types.BinaryOperator]string{
+	types.Addition:       "add",
+	types.Subraction:     "sub",
+	types.Multiplication: "call Math.multiply 2",
+	types.Division:       "call Math.divide 2",
+	types.LessThan:       "lt",
+	types.GreaterThan:    "gt",
+	types.Equals:         "eq",
+}
+
+var logicalCommands = map[types.LogicalOperator]string{
+	types.And: "and",
+	types.Or:  "or",
+}
+
+var unaryCommands = map[types.UnaryOperator]string{
+	types.ArithmeticNegation: "neg",
+	types.BooleanNegation:    "not",
+}
+
+type CodeGenError struct {
+	message string
+}
+
+func (e *CodeGenError) Error() string {
+	return e.message
+}
+
+type CodeGenerator struct {
+	class      types.Class
+	ifCount    int
+	output     strings.Builder
+	whileCount int
+}
+
+func NewCodeGenerator() *CodeGenerator {
+	return new(CodeGenerator)
+}
+
+func (generator *CodeGenerator) emitError(format string, args ...any) {
+	panic(&CodeGenError{message: fmt.Sprintf(
+		"(%s): Compile error: %s",
+		generator.class.Name,
+		fmt.Sprintf(format, args...),
+	)})
+}
+
+func (generator *CodeGenerator) write(format string, args ...any) {
+	generator.output.WriteString(fmt.Sprintf(format, args...))
+	generator.output.WriteString("\n")
+}
+
+func (generator *CodeGenerator) writePush(segment Segment, index int) {
+	generator.write("push %s %d", segment, index)
+}
+
+func (generator *CodeGenerator) writePop(segment Segment, index int) {
+	generator.write("pop %s %d", segment, index)
+}
+
+func (generator *CodeGenerator) resolveSymbol(ident types.Ident) types.Symbol {
+	if ident.Symbol == nil {
+		generator.emitError("'%s' is not defined", ident.Name)
+	}
+
+	return *ident.Symbol
+}
+
+func (generator *CodeGenerator) countFields() (count int) {
+	for _, decl := range generator.class.Vars {
+		if decl.Kind == types.Field {
+			count++
+		}
+	}
+	return count
+}
+
+func (generator *CodeGenerator) generateIdent(expr types.Ident) {
+	symbol := generator.resolveSymbol(expr)
+	generator.writePush(segments[symbol.Kind], symbol.Position)
+}
+
+func (generator *CodeGenerator) generateLiteral(expr types.Literal) {
+	switch expr.Type {
+	case types.BooleanLiteral:
+		generator.writePush(ConstantSegment, 0)
+		if expr.Value == "true" {
+			generator.write("not")
+		}
+
+	case types.IntegerLiteral:
+		generator.write("push constant %s", expr.Value)
+
+	case types.NullLiteral:
+		generator.writePush(ConstantSegment, 0)
+
+	case types.StringLiteral:
+		chars := []rune(expr.Value)
+		generator.writePush(ConstantSegment, len(chars))
+		generator.write("call String.new 1")
+
+		for _, char := range chars {
+			generator.writePush(ConstantSegment, int(char))
+			generator.write("call String.appendChar 2")
+		}
+
+	case types.ThisLiteral:
+		generator.writePush(PointerSegment, 0)
+
+	default:
+		generator.emitError("unsupported literal '%s'", expr.Value)
+	}
+}
+
+func (generator *CodeGenerator) generateIndexAddress(expr types.IndexExpr) {
+	// The address of 'arr[i]' is the base address stored in 'arr' plus the offset 'i'.
+	generator.generateIdent(expr.Object)
+	generator.generateExpression(expr.Indexer)
+	generator.write("add")
+}
+
+func (generator *CodeGenerator) generateIndexExpression(expr types.IndexExpr) {
+	generator.generateIndexAddress(expr)
+	generator.writePop(PointerSegment, 1)
+	generator.writePush(ThatSegment, 0)
+}
+
+func (generator *CodeGenerator) generateCallExpression(expr types.CallExpr) {
+	var functionName string
+	argCount := len(expr.Arguments)
+
+	switch callee := expr.Callee.(type) {
+	case types.Ident:
+		// An unqualified call is always a method call on the current object.
+		generator.writePush(PointerSegment, 0)
+		functionName = fmt.Sprintf("%s.%s", generator.class.Name, callee.Name)
+		argCount++
+
+	case types.MemberExpr:
+		if symbol := callee.Object.Symbol; symbol != nil {
+			// 'obj.method()' pushes 'obj' as the hidden first argument.
+			generator.writePush(segments[symbol.Kind], symbol.Position)
+			functionName = fmt.Sprintf("%s.%s", symbol.Type, callee.Property.Name)
+			argCount++
+		} else {
+			functionName = fmt.Sprintf("%s.%s", callee.Object.Name, callee.Property.Name)
+		}
+
+	default:
+		generator.emitError("invalid callee '%s'", expr.Callee)
+	}
+
+	for _, arg := range expr.Arguments {
+		generator.generateExpression(arg)
+	}
+
+	generator.write("call %s %d", functionName, argCount)
+}
+
+func (generator *CodeGenerator) generateExpression(expr types.Expr) {
+	switch expr := expr.(type) {
+	case types.BinaryExpr:
+		generator.generateExpression(expr.Left)
+		generator.generateExpression(expr.Right)
+		generator.write(binaryCommands[expr.Operator])
+
+	case types.CallExpr:
+		generator.generateCallExpression(expr)
+
+	case types.Ident:
+		generator.generateIdent(expr)
+
+	case types.IndexExpr:
+		generator.generateIndexExpression(expr)
+
+	case types.Literal:
+		generator.generateLiteral(expr)
+
+	case types.LogicalExpr:
+		generator.generateExpression(expr.Left)
+		generator.generateExpression(expr.Right)
+		generator.write(logicalCommands[expr.Operator])
+
+	case types.ParenExpr:
+		generator.generateExpression(expr.Expression)
+
+	case types.UnaryExpr:
+		generator.generateExpression(expr.Operand)
+		generator.write(unaryCommands[expr.Operator])
+
+	default:
+		generator.emitError("unsupported expression '%s'", expr)
+	}
+}
+
+func (generator *CodeGenerator) generateDoStatement(stmt types.DoStmt) {
+	generator.generateCallExpression(stmt.Expression)
+	// Discard the return value, every subroutine returns one.
+	generator.writePop(TempSegment, 0)
+}
+
+func (generator *CodeGenerator) generateIfStatement(stmt types.IfStmt) {
+	elseLabel := fmt.Sprintf("IF_ELSE%d", generator.ifCount)
+	endLabel := fmt.Sprintf("IF_END%d", generator.ifCount)
+	generator.ifCount++
+
+	generator.generateExpression(stmt.Condition)
+	generator.write("not")
+	generator.write("if-goto %s", elseLabel)
+	generator.generateStatements(stmt.ThenStmt.Statements)
+	generator.write("goto %s", endLabel)
+	generator.write("label %s", elseLabel)
+	generator.generateStatements(stmt.ElseStmt.Statements)
+	generator.write("label %s", endLabel)
+}
+
+func (generator *CodeGenerator) generateLetStatement(stmt types.LetStmt) {
+	switch target := stmt.Target.(type) {
+	case types.Ident:
+		symbol := generator.resolveSymbol(target)
+		generator.generateExpression(stmt.Value)
+		generator.writePop(segments[symbol.Kind], symbol.Position)
+
+	case types.IndexExpr:
+		// The value is stashed in 'temp 0' because evaluating it may clobber 'pointer 1'.
+		generator.generateIndexAddress(target)
+		generator.generateExpression(stmt.Value)
+		generator.writePop(TempSegment, 0)
+		generator.writePop(PointerSegment, 1)
+		generator.writePush(TempSegment, 0)
+		generator.writePop(ThatSegment, 0)
+
+	default:
+		generator.emitError("invalid assignment target '%s'", stmt.Target)
+	}
+}
+
+func (generator *CodeGenerator) generateReturnStatement(stmt types.ReturnStmt) {
+	if stmt.Expression == nil {
+		// 'void' subroutines still have to leave a value on the stack.
+		generator.writePush(ConstantSegment, 0)
+	} else {
+		generator.generateExpression(stmt.Expression)
+	}
+	generator.write("return")
+}
+
+func (generator *CodeGenerator) generateWhileStatement(stmt types.WhileStmt) {
+	expLabel := fmt.Sprintf("WHILE_EXP%d", generator.whileCount)
+	endLabel := fmt.Sprintf("WHILE_END%d", generator.whileCount)
+	generator.whileCount++
+
+	generator.write("label %s", expLabel)
+	generator.generateExpression(stmt.Condition)
+	generator.write("not")
+	generator.write("if-goto %s", endLabel)
+	generator.generateStatements(stmt.Body.Statements)
+	generator.write("goto %s", expLabel)
+	generator.write("label %s", endLabel)
+}
+
+func (generator *CodeGenerator) generateStatements(stmts []types.Stmt) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case types.BlockStmt:
+			generator.generateStatements(stmt.Statements)
+		case types.DoStmt:
+			generator.generateDoStatement(stmt)
+		case types.IfStmt:
+			generator.generateIfStatement(stmt)
+		case types.LetStmt:
+			generator.generateLetStatement(stmt)
+		case types.ReturnStmt:
+			generator.generateReturnStatement(stmt)
+		case types.WhileStmt:
+			generator.generateWhileStatement(stmt)
+		default:
+			generator.emitError("unsupported statement '%s'", stmt)
+		}
+	}
+}
+
+func (generator *CodeGenerator) generateSubroutine(subroutine types.SubroutineDecl) {
+	generator.ifCount = 0
+	generator.whileCount = 0
+
+	generator.write("function %s.%s %d", generator.class.Name, subroutine.Name, len(subroutine.Body.Vars))
+
+	switch subroutine.Kind {
+	case types.Constructor:
+		// Allocate one word per field and anchor 'this' at the new block.
+		generator.writePush(ConstantSegment, generator.countFields())
+		generator.write("call Memory.alloc 1")
+		generator.writePop(PointerSegment, 0)
+
+	case types.Method:
+		generator.writePush(ArgumentSegment, 0)
+		generator.writePop(PointerSegment, 0)
+	}
+
+	generator.generateStatements(subroutine.Body.Statements)
+}
+
+// Generate compiles a class whose identifiers have been annotated by the resolver.
+func (generator *CodeGenerator) Generate(class types.Class) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var codeGenError *CodeGenError
+			if e, ok := r.(error); ok && errors.As(e, &codeGenError) {
+				err = codeGenError
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	generator.class = class
+	generator.output.Reset()
+
+	for _, subroutine := range class.Subroutines {
+		generator.generateSubroutine(subroutine)
+	}
+
+	return generator.output.String(), nil
+}
@@ -0,0 +1,70 @@
+package codegen_test
+
+import (
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/codegen"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	. "github.com/MlkMahmud/jack-compiler/parser"
+	. "github.com/MlkMahmud/jack-compiler/resolver"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func readFileContent(filename string) string {
+	file, _ := os.Open(filename)
+	bytes, err := io.ReadAll(file)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(bytes)
+}
+
+func TestCodeGenerator(t *testing.T) {
+	files := []string{"Array", "Square", "SquareGame"}
+	lexer := lexer.NewLexer()
+	parser := NewParser()
+	resolver := NewResolver()
+	generator := NewCodeGenerator()
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			filePath := path.Join(TEST_DATA_PATH, strings.Join([]string{file, "jack"}, "."))
+			cmpFilePath := path.Join(TEST_DATA_PATH, "expected", strings.Join([]string{file, "vm"}, "."))
+
+			tokens, err := lexer.Tokenize(filePath)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			class, err := parser.Parse(tokens)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			class, err = resolver.Resolve(class)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := generator.Generate(class)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if expected := readFileContent(cmpFilePath); expected != actual {
+				t.Errorf("Expected generated code for %s to match content of %s", filePath, cmpFilePath)
+			}
+		})
+	}
+}
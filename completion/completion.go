@@ -0,0 +1,240 @@
+package completion
+
+import (
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/helpers"
+	"github.com/MlkMahmud/jack-compiler/symboltable"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+Completions depend on what precedes the name being typed, if any:
+
+	Foo.   the functions and constructors of class Foo
+	foo.   the methods of the class of variable foo
+	;  {  } at the start of a statement, the statement keywords
+	       anywhere else in a subroutine, its arguments and local variables,
+	       the fields (except in functions) and statics of its class, the
+	       methods it can call without an object and every class
+
+The class being edited usually has syntax errors. The parser keeps the
+subroutine in which the input ends, so its variables are still known.
+*/
+
+type Kind string
+
+const (
+	CLASS   Kind = "class"
+	KEYWORD Kind = "keyword"
+)
+
+// Item is a name that can complete the name being typed. The Kind of a
+// subroutine or a variable is its types.SymbolKind.
+type Item struct {
+	Detail string
+	Kind   Kind
+	Label  string
+}
+
+var statementKeywords = []string{"let", "do", "if", "while", "return"}
+
+// before reports whether a is at or before b.
+func before(a, b types.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column <= b.Column)
+}
+
+// Signature returns the declaration of a subroutine, such as 'method int
+// Foo.bar(int x)', or 'method int bar(int x)' when className is empty.
+func Signature(className string, subroutine types.SubroutineDecl) string {
+	params := make([]string, 0, len(subroutine.Params))
+
+	for _, param := range subroutine.Params {
+		params = append(params, param.Type+" "+param.Name)
+	}
+
+	name := subroutine.Name.Name
+
+	if className != "" {
+		name = className + "." + name
+	}
+
+	return string(subroutine.Kind) + " " + subroutine.Type + " " + name + "(" + strings.Join(params, ", ") + ")"
+}
+
+// Scope builds the table of the variables visible in a subroutine of a
+// class. Unlike the resolver, it keeps the first declaration of a name
+// declared twice, as the class is being edited.
+func Scope(class types.Class, subroutine types.SubroutineDecl) *symboltable.SymbolTable {
+	classTable := symboltable.New(nil)
+	table := symboltable.New(classTable)
+
+	define := func(table *symboltable.SymbolTable, name string, kind types.SymbolKind, symbolType string) {
+		if !table.Has(name) {
+			table.Define(name, kind, symbolType)
+		}
+	}
+
+	for _, decl := range class.Vars {
+		define(classTable, decl.Name, decl.Kind, decl.Type)
+	}
+
+	if subroutine.Kind == types.Method {
+		table.Reserve(types.Argument)
+	}
+
+	for _, param := range subroutine.Params {
+		define(table, param.Name, types.Argument, param.Type)
+	}
+
+	for _, decl := range subroutine.Body.Vars {
+		define(table, decl.Name, types.Var, decl.Type)
+	}
+
+	return table
+}
+
+// enclosing returns the subroutine of class around pos.
+func enclosing(class types.Class, pos types.Position) (types.SubroutineDecl, bool) {
+	for index := len(class.Subroutines) - 1; index >= 0; index-- {
+		subroutine := class.Subroutines[index]
+
+		if !before(subroutine.Body.Pos(), pos) || !subroutine.Body.Pos().IsValid() {
+			continue
+		}
+
+		// The last subroutine of a class cut short by the end of the input has no end.
+		unterminated := !class.End().IsValid() && index == len(class.Subroutines)-1
+
+		if before(pos, subroutine.End()) || unterminated {
+			return subroutine, true
+		}
+
+		return types.SubroutineDecl{}, false
+	}
+
+	return types.SubroutineDecl{}, false
+}
+
+// Complete returns the completions at pos in a class being edited. tokens
+// are the tokens of its file and classes every class the program can call,
+// including the OS classes.
+func Complete(class types.Class, tokens []types.Token, classes []types.Class, pos types.Position) []Item {
+	subroutine, ok := enclosing(class, pos)
+
+	if !ok {
+		return nil
+	}
+
+	// preceding holds the tokens that end before the cursor.
+	preceding := tokens
+
+	for index, token := range tokens {
+		if !before(token.End(), pos) {
+			preceding = tokens[:index]
+			break
+		}
+	}
+
+	prefix := ""
+
+	if count := len(preceding); count > 0 {
+		last := preceding[count-1]
+
+		if end := last.End(); end.Line == pos.Line && end.Column == pos.Column && (last.TokenType == types.IDENTIFIER || last.TokenType == types.KEYWORD) {
+			prefix = last.Lexeme
+			preceding = preceding[:count-1]
+		}
+	}
+
+	items := []Item{}
+	add := func(item Item) {
+		if strings.HasPrefix(item.Label, prefix) {
+			items = append(items, item)
+		}
+	}
+
+	table := Scope(class, subroutine)
+	count := len(preceding)
+
+	if count >= 2 && helpers.IsOneOfSymbols(preceding[count-1], []string{"."}) && preceding[count-2].TokenType == types.IDENTIFIER {
+		name := preceding[count-2].Lexeme
+		className, onObject := name, false
+
+		if symbol, ok := table.Lookup(name); ok {
+			className, onObject = symbol.Type, true
+		}
+
+		for _, callee := range classes {
+			if callee.Name.Name != className {
+				continue
+			}
+
+			for _, decl := range callee.Subroutines {
+				if isMethod := decl.Kind == types.Method; isMethod == onObject {
+					add(Item{Detail: Signature(className, decl), Kind: Kind(decl.Kind), Label: decl.Name.Name})
+				}
+			}
+		}
+
+		return items
+	}
+
+	if count == 0 || helpers.IsOneOfSymbols(preceding[count-1], []string{";", "{", "}"}) {
+		for _, keyword := range statementKeywords {
+			add(Item{Kind: KEYWORD, Label: keyword})
+		}
+		return items
+	}
+
+	for _, kind := range []types.SymbolKind{types.Var, types.Argument, types.Field, types.Static} {
+		if kind == types.Field && subroutine.Kind == types.Function {
+			continue
+		}
+
+		for _, scope := range []*symboltable.SymbolTable{table, table.Enclosing} {
+			for _, symbol := range sortedSymbols(scope, kind) {
+				add(Item{Detail: symbol.Type, Kind: Kind(kind), Label: symbol.Name})
+			}
+		}
+	}
+
+	if subroutine.Kind != types.Function {
+		for _, decl := range class.Subroutines {
+			if decl.Kind == types.Method {
+				add(Item{Detail: Signature(class.Name.Name, decl), Kind: Kind(decl.Kind), Label: decl.Name.Name})
+			}
+		}
+	}
+
+	for _, callee := range classes {
+		add(Item{Kind: CLASS, Label: callee.Name.Name})
+	}
+
+	return items
+}
+
+// sortedSymbols returns the symbols of a kind declared in table, in the
+// order they were declared.
+func sortedSymbols(table *symboltable.SymbolTable, kind types.SymbolKind) []types.Symbol {
+	symbols := make([]types.Symbol, table.Count(kind))
+	found := make([]bool, len(symbols))
+
+	for _, symbol := range table.Values {
+		if symbol.Kind == kind {
+			symbols[symbol.Position] = symbol
+			found[symbol.Position] = true
+		}
+	}
+
+	declared := symbols[:0]
+
+	for index, symbol := range symbols {
+		// The hidden 'this' argument of a method has no symbol.
+		if found[index] {
+			declared = append(declared, symbol)
+		}
+	}
+
+	return declared
+}
@@ -0,0 +1,120 @@
+package completion_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/completion"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/stdlib"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+const COUNTER = `class Counter {
+  field int count;
+  static int instances;
+
+  constructor Counter new(int start) {
+    let count = start;
+    return this;
+  }
+
+  method void increment(int by) {
+    var boolean done;
+    let count = count + by;
+    return;
+  }
+
+  function int total() {
+    return instances;
+  }
+}`
+
+func parse(t *testing.T, source string) (types.Class, []types.Token) {
+	t.Helper()
+	tokens, err := lexer.NewLexer().TokenizeReader("Test.jack", strings.NewReader(source))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sources being edited have syntax errors.
+	class, _ := parser.NewParser().Parse(tokens)
+	return class, tokens
+}
+
+// complete returns the labels of the completions at the '|' of source.
+func complete(t *testing.T, source string) string {
+	t.Helper()
+	cursor := strings.Index(source, "|")
+	source = strings.Replace(source, "|", "", 1)
+	line := strings.Count(source[:cursor], "\n") + 1
+	column := cursor - strings.LastIndex(source[:cursor], "\n")
+
+	class, tokens := parse(t, source)
+	counter, _ := parse(t, COUNTER)
+	classes, err := stdlib.Classes()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	classes = append([]types.Class{class, counter}, classes...)
+	labels := []string{}
+
+	for _, item := range Complete(class, tokens, classes, types.Position{Line: line, Column: column}) {
+		labels = append(labels, item.Label)
+	}
+
+	return fmt.Sprint(labels)
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Functions", "class Main {\n  function void main() {\n    do Counter.|", "[new total]"},
+		{"FunctionPrefix", "class Main {\n  function void main() {\n    do Counter.t|\n  }\n}", "[total]"},
+		{"Methods", "class Main {\n  function void main() {\n    var Counter c;\n    do c.|\n    return;\n  }\n}", "[increment]"},
+		{"OSFunctions", "class Main {\n  function void main() {\n    do Output.print|", "[printChar printString printInt println]"},
+		{"StatementStart", "class Main {\n  function void main() {\n    var int x;\n    |", "[let do if while return]"},
+		{"StatementPrefix", "class Main {\n  function void main() {\n    if (true) {\n      w|\n    }\n  }\n}", "[while]"},
+		{"Variables", "class Main {\n  field int size;\n  static Main instance;\n  method void run(int a, int b) {\n    var int i, j;\n    let i = |", "[i j a b size instance run Main Counter Array Keyboard Math Memory Output Screen String Sys]"},
+		{"VariablePrefix", "class Main {\n  field int size;\n  static Main instance;\n  method void run(int a, int b) {\n    var int i, j;\n    let i = s|;\n  }\n}", "[size]"},
+		{"FunctionScope", "class Main {\n  field int size;\n  static int count;\n  function void main(int a) {\n    let a = c|", "[count]"},
+		{"OutsideSubroutines", "class Main {\n  field int size;\n  |", "[]"},
+		// The subroutine being edited is followed by the rest of the class.
+		{"MidFileFunctions", "class Main {\n  function void main() {\n    do Counter.|\n  }\n  method void draw() {\n    return;\n  }\n}", "[new total]"},
+		{"MidFileVariables", "class Main {\n  method void run() {\n    var int loc, x;\n    let loc = l|\n  }\n  method void draw() {\n    return;\n  }\n}", "[loc]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := complete(t, test.source); actual != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	subroutine := types.SubroutineDecl{
+		Kind:   types.Method,
+		Name:   types.Ident{Name: "bar"},
+		Params: []types.Parameter{{Name: "x", Type: "int"}, {Name: "s", Type: "String"}},
+		Type:   "int",
+	}
+
+	if actual := Signature("Foo", subroutine); actual != "method int Foo.bar(int x, String s)" {
+		t.Errorf("Unexpected signature %q", actual)
+	}
+
+	// The class is omitted where it is implied, as on the page of the class.
+	if actual := Signature("", subroutine); actual != "method int bar(int x, String s)" {
+		t.Errorf("Unexpected signature %q", actual)
+	}
+}
@@ -0,0 +1,267 @@
+package cpu
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/assembler"
+	"github.com/MlkMahmud/jack-compiler/screen"
+)
+
+/*
+HACK CPU
+
+The CPU executes one instruction of the ROM per cycle:
+
+	A-instruction:   0vvv vvvv vvvv vvvv   A = v
+	C-instruction:   111a cccc ccdd djjj   dest = comp; jump
+
+The six 'c' bits drive the ALU, whose inputs are D and either A (a = 0) or
+M (a = 1), the RAM word at address A:
+
+	zx  x = 0        zy  y = 0        f   out = x + y, else out = x & y
+	nx  x = !x       ny  y = !y       no  out = !out
+
+The 'd' bits store the result in A, D and M, and the 'j' bits jump to A
+when the result is negative, zero or positive.
+
+A program halts by jumping to itself, usually with '(END) @END 0;JMP', which
+the CPU detects to stop running it.
+*/
+
+const (
+	ROM_SIZE = 32768
+	RAM_SIZE = 32768
+)
+
+// LoadError reports a line of a '.hack' file that is not an instruction.
+type LoadError struct {
+	Filename string
+	Line     int
+	Message  string
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf(
+		"(%s):[%d]: Load error: %s",
+		e.Filename,
+		e.Line,
+		e.Message,
+	)
+}
+
+// CPUError reports an instruction that cannot be executed.
+type CPUError struct {
+	PC      int
+	Message string
+}
+
+func (e *CPUError) Error() string {
+	return fmt.Sprintf("[PC=%d]: CPU error: %s", e.PC, e.Message)
+}
+
+type CPU struct {
+	A   int16
+	D   int16
+	PC  int16
+	RAM [RAM_SIZE]int16
+	ROM [ROM_SIZE]uint16
+	// Cycles is the number of instructions executed since the last reset.
+	Cycles int
+	halted bool
+	// size is the number of instructions of the loaded program.
+	size int
+}
+
+func NewCPU() *CPU {
+	return new(CPU)
+}
+
+// Load loads a program in the text format of '.hack' files. filename is only
+// used to locate errors.
+func (cpu *CPU) Load(filename, source string) error {
+	cpu.ROM = [ROM_SIZE]uint16{}
+	cpu.size = 0
+
+	for index, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		}
+
+		if cpu.size >= ROM_SIZE {
+			return &LoadError{Filename: filename, Line: index + 1, Message: "the program does not fit in ROM"}
+		}
+
+		var instruction uint16
+
+		if len(line) != 16 || strings.Trim(line, "01") != "" {
+			return &LoadError{Filename: filename, Line: index + 1, Message: fmt.Sprintf("'%s' is not a 16 bit binary instruction", line)}
+		}
+
+		for _, bit := range line {
+			instruction = instruction<<1 | uint16(bit-'0')
+		}
+
+		cpu.ROM[cpu.size] = instruction
+		cpu.size++
+	}
+
+	cpu.Reset()
+	return nil
+}
+
+// LoadFile loads a '.hack' file, or assembles and loads a '.asm' file.
+func (cpu *CPU) LoadFile(path string) error {
+	source, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	code := string(source)
+
+	if strings.HasSuffix(path, ".asm") {
+		if code, err = assembler.NewAssembler().Assemble(path, code); err != nil {
+			return err
+		}
+	}
+
+	return cpu.Load(path, code)
+}
+
+// Reset restarts the program, without clearing the RAM.
+func (cpu *CPU) Reset() {
+	cpu.A, cpu.D, cpu.PC = 0, 0, 0
+	cpu.Cycles = 0
+	cpu.halted = false
+}
+
+// Halted returns true once the program has jumped to itself.
+func (cpu *CPU) Halted() bool {
+	return cpu.halted
+}
+
+// Screen returns the screen memory map, the 8K words of the RAM from
+// screen.BASE that hold the 512 by 256 pixels of the screen.
+func (cpu *CPU) Screen() []int16 {
+	return cpu.RAM[screen.BASE:screen.KBD]
+}
+
+func (cpu *CPU) alu(comp uint16, x, y int16) int16 {
+	if comp&0b100000 != 0 {
+		x = 0
+	}
+	if comp&0b010000 != 0 {
+		x = ^x
+	}
+	if comp&0b001000 != 0 {
+		y = 0
+	}
+	if comp&0b000100 != 0 {
+		y = ^y
+	}
+
+	out := x & y
+
+	if comp&0b000010 != 0 {
+		out = x + y
+	}
+	if comp&0b000001 != 0 {
+		out = ^out
+	}
+
+	return out
+}
+
+// memory returns the address of M, the RAM word at address A.
+func (cpu *CPU) memory() (int, error) {
+	if cpu.A < 0 {
+		return 0, &CPUError{PC: int(cpu.PC), Message: fmt.Sprintf("address %d is outside of the RAM", uint16(cpu.A))}
+	}
+	return int(cpu.A), nil
+}
+
+// Step executes the instruction at PC.
+func (cpu *CPU) Step() error {
+	pc := cpu.PC
+
+	if pc < 0 || int(pc) >= cpu.size {
+		cpu.halted = true
+		return &CPUError{PC: int(pc), Message: "execution ran past the last instruction"}
+	}
+
+	instruction := cpu.ROM[pc]
+	cpu.Cycles++
+
+	if instruction&0x8000 == 0 {
+		cpu.A = int16(instruction)
+		cpu.PC++
+		return nil
+	}
+
+	comp := (instruction >> 6) & 0b111111
+	dest := (instruction >> 3) & 0b111
+	jump := instruction & 0b111
+	y := cpu.A
+
+	if instruction&0x1000 != 0 || dest&0b001 != 0 {
+		address, err := cpu.memory()
+
+		if err != nil {
+			cpu.halted = true
+			return err
+		}
+
+		if instruction&0x1000 != 0 {
+			y = cpu.RAM[address]
+		}
+	}
+
+	out := cpu.alu(comp, cpu.D, y)
+	target := cpu.A
+
+	// M is written at the address A had before the instruction.
+	if dest&0b001 != 0 {
+		cpu.RAM[cpu.A] = out
+	}
+	if dest&0b100 != 0 {
+		cpu.A = out
+	}
+	if dest&0b010 != 0 {
+		cpu.D = out
+	}
+
+	jumps := (jump&0b100 != 0 && out < 0) || (jump&0b010 != 0 && out == 0) || (jump&0b001 != 0 && out > 0)
+
+	if !jumps {
+		cpu.PC++
+		return nil
+	}
+
+	// A jump to itself, or to the A-instruction that loads its own address, never ends.
+	if target == pc || (target >= 0 && target == pc-1 && cpu.ROM[target] == uint16(target)) {
+		cpu.halted = true
+	}
+
+	cpu.PC = target
+	return nil
+}
+
+// Run executes the program until it halts. A positive maxCycles bounds the
+// number of instructions executed, to stop programs that never halt.
+func (cpu *CPU) Run(maxCycles int) error {
+	for !cpu.halted {
+		if maxCycles > 0 && cpu.Cycles >= maxCycles {
+			return &CPUError{PC: int(cpu.PC), Message: fmt.Sprintf("the program did not halt within %d cycles", maxCycles)}
+		}
+
+		if err := cpu.Step(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
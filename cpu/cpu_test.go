@@ -0,0 +1,233 @@
+package cpu_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/assembler"
+	"github.com/MlkMahmud/jack-compiler/codegen"
+	. "github.com/MlkMahmud/jack-compiler/cpu"
+	"github.com/MlkMahmud/jack-compiler/program"
+	"github.com/MlkMahmud/jack-compiler/vm"
+	"github.com/MlkMahmud/jack-compiler/vmtranslator"
+)
+
+func load(t *testing.T, asm string) *CPU {
+	t.Helper()
+	hack, err := assembler.NewAssembler().Assemble("Test.asm", asm)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpu := NewCPU()
+
+	if err := cpu.Load("Test.hack", hack); err != nil {
+		t.Fatal(err)
+	}
+
+	return cpu
+}
+
+func TestMultiply(t *testing.T) {
+	// RAM[2] = RAM[0] * RAM[1]
+	cpu := load(t, `
+  @R2
+  M=0
+(LOOP)
+  @R1
+  D=M
+  @END
+  D;JEQ
+  @R0
+  D=M
+  @R2
+  M=D+M
+  @R1
+  M=M-1
+  @LOOP
+  0;JMP
+(END)
+  @END
+  0;JMP
+`)
+
+	cpu.RAM[0], cpu.RAM[1] = 6, 7
+
+	if err := cpu.Run(1000); err != nil {
+		t.Fatal(err)
+	}
+
+	if !cpu.Halted() || cpu.RAM[2] != 42 {
+		t.Errorf("Expected the program to halt with RAM[2] = 42, got %d", cpu.RAM[2])
+	}
+
+	if expected, actual := 2+7*12+4+2, cpu.Cycles; expected != actual {
+		t.Errorf("Expected the program to halt after %d cycles, got %d", expected, actual)
+	}
+}
+
+func TestComputations(t *testing.T) {
+	// D is 12, A is 100 and M is -5.
+	tests := map[string]int16{
+		"0": 0, "1": 1, "-1": -1, "D": 12, "A": 100, "M": -5,
+		"!D": ^12, "!A": ^100, "!M": 4, "-D": -12, "-A": -100, "-M": 5,
+		"D+1": 13, "A+1": 101, "M+1": -4, "D-1": 11, "A-1": 99, "M-1": -6,
+		"D+A": 112, "D+M": 7, "D-A": -88, "D-M": 17, "A-D": 88, "M-D": -17,
+		"D&A": 12 & 100, "D&M": 12 & -5, "D|A": 12 | 100, "D|M": 12 | -5,
+	}
+
+	for comp, expected := range tests {
+		cpu := load(t, "@12\nD=A\n@100\nAMD="+comp+"\n")
+		cpu.RAM[100] = -5
+
+		for i := 0; i < 4; i++ {
+			if err := cpu.Step(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// M is written at the address A had before the instruction.
+		if cpu.D != expected || cpu.A != expected || cpu.RAM[100] != expected {
+			t.Errorf("Expected %s to be %d, got A = %d, D = %d and M = %d", comp, expected, cpu.A, cpu.D, cpu.RAM[100])
+		}
+	}
+}
+
+func TestJumps(t *testing.T) {
+	tests := map[string][3]bool{
+		"JGT": {false, false, true}, "JEQ": {false, true, false}, "JGE": {false, true, true},
+		"JLT": {true, false, false}, "JNE": {true, false, true}, "JLE": {true, true, false},
+		"JMP": {true, true, true},
+	}
+
+	for jump, expected := range tests {
+		for index, value := range []int16{-1, 0, 1} {
+			cpu := load(t, "@10\n0;"+jump+"\n")
+			cpu.Step()
+			cpu.D = value
+			cpu.ROM[1] = cpu.ROM[1]&^(0b111111<<6) | 0b001100<<6 // D
+
+			if err := cpu.Step(); err != nil {
+				t.Fatal(err)
+			}
+
+			if jumped := cpu.PC == 10; jumped != expected[index] {
+				t.Errorf("Expected D=%d;%s to jump: %t", value, jump, expected[index])
+			}
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cpu := NewCPU()
+	var loadError *LoadError
+
+	if err := cpu.Load("Test.hack", "0000000000000001\n\n000000000000001\n"); !errors.As(err, &loadError) || loadError.Line != 3 {
+		t.Errorf("Expected a load error on line 3, got %v", err)
+	}
+
+	var cpuError *CPUError
+
+	// @32767, A=A+1 overflows A into a negative address.
+	cpu = load(t, "@32767\nA=A+1\nM=1\n")
+
+	if err := cpu.Run(100); !errors.As(err, &cpuError) || cpuError.PC != 2 {
+		t.Errorf("Expected an error at PC 2, got %v", err)
+	}
+
+	cpu = load(t, "@0\nD=A\n")
+
+	if err := cpu.Run(100); !errors.As(err, &cpuError) || cpuError.PC != 2 {
+		t.Errorf("Expected the program to run past its end at PC 2, got %v", err)
+	}
+
+	cpu = load(t, "(LOOP)\n@0\nD=A\n@LOOP\n0;JMP\n")
+
+	if err := cpu.Run(100); !errors.As(err, &cpuError) || cpu.Cycles != 100 {
+		t.Errorf("Expected the program to stop after 100 cycles, got %v", err)
+	}
+}
+
+// TestPipeline compiles a Jack program down to Hack machine code and checks
+// that the CPU computes what the VM interpreter does.
+func TestPipeline(t *testing.T) {
+	code := `class Main {
+  static int calls;
+
+  function int main() {
+    var int i, sum;
+    let i = 0;
+    while (i < 10) {
+      let sum = sum + Main.fib(i);
+      let i = i + 1;
+    }
+    if ((sum = 88) & (calls > 100)) {
+      return sum;
+    }
+    return -1;
+  }
+
+  function int fib(int n) {
+    let calls = calls + 1;
+    if (n < 2) {
+      return n;
+    }
+    return Main.fib(n - 1) + Main.fib(n - 2);
+  }
+}`
+
+	prog := program.New()
+
+	if err := prog.AddSource("Main.jack", code); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prog.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	vmCode, err := codegen.NewCodeGenerator().Generate(prog.Classes[0])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sys.init stores the result in temp 0, RAM[5], and halts.
+	sys := "function Sys.init 0\ncall Main.main 0\npop temp 0\nlabel END\ngoto END\n"
+	asm, err := vmtranslator.NewTranslator().Translate([]vmtranslator.File{{Name: "Main.vm", Code: vmCode}, {Name: "Sys.vm", Code: sys}}, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpu := load(t, asm)
+
+	if err := cpu.Run(1000000); err != nil {
+		t.Fatal(err)
+	}
+
+	machine := vm.NewMachine()
+
+	if err := machine.Load([]vm.File{{Name: "Main.vm", Code: vmCode}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := machine.Run(1000000); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := machine.Top()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := cpu.RAM[5]; expected != 88 || expected != actual {
+		t.Errorf("Expected the CPU to compute %d like the VM, got %d", expected, actual)
+	}
+
+	if cpu.RAM[0] != 261 {
+		t.Errorf("Expected the stack to be back to 261 after Sys.init's frame, got %d", cpu.RAM[0])
+	}
+}
@@ -0,0 +1,45 @@
+package diag
+
+import (
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+// Diagnostic is an error found at a span of a source file. The errors of
+// the lexer, the parser and the assembler embed it and describe it in their
+// own words.
+type Diagnostic struct {
+	Column   int
+	End      types.Position
+	Filename string
+	Line     int
+	Message  string
+}
+
+// Pos returns the position the diagnostic starts at.
+func (d Diagnostic) Pos() types.Position {
+	return types.Position{Filename: d.Filename, Line: d.Line, Column: d.Column}
+}
+
+// List is the list of every error found in a file or a program, in source
+// order.
+type List[T error] []T
+
+func (list List[T]) Error() string {
+	messages := make([]string, 0, len(list))
+
+	for _, err := range list {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Err returns the list as an error, or nil when it is empty.
+func (list List[T]) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
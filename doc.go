@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/jackdoc"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+func printDocHelpMessage() {
+	log.SetFlags(0)
+	log.Fatalln(("usage:\n go run . doc --src <dirName>\t\tWrites the HTML and Markdown documentation of the .jack files in <dirName> to <dirName>/doc\n go run . doc --src <fileName.jack>\tDocuments the specified .jack file\n go run . doc --src <src> --out <dir>\tWrites the documentation to <dir> instead"))
+}
+
+// generateDocs documents the classes of a directory from their doc comments.
+func generateDocs(args []string) {
+	var source string
+	var out string
+	flags := flag.NewFlagSet("doc", flag.ExitOnError)
+	flags.StringVar(&source, "src", "", "Path to a '.jack' file or a directory containing '.jack' files.")
+	flags.StringVar(&out, "out", "", "Directory the documentation is written to, 'doc' next to the sources by default.")
+	flags.Parse(args)
+
+	info, err := os.Stat(source)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	files := []string{source}
+	dir := filepath.Dir(source)
+
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(source, "*.jack")); err != nil {
+			log.Fatal(err)
+		}
+		sort.Strings(files)
+		dir = source
+	} else if !strings.HasSuffix(source, ".jack") {
+		printDocHelpMessage()
+	}
+
+	if out == "" {
+		out = filepath.Join(dir, "doc")
+	}
+
+	classes := []types.Class{}
+	failed := false
+	lexer := lexer.NewLexer(lexer.WithComments())
+	parser := parser.NewParser()
+
+	for _, file := range files {
+		tokens, err := lexer.Tokenize(file)
+
+		if err != nil {
+			log.Println(err)
+			failed = true
+			continue
+		}
+
+		class, err := parser.Parse(tokens)
+
+		if err != nil {
+			log.Println(err)
+			failed = true
+			continue
+		}
+
+		classes = append(classes, class)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+
+	if err := jackdoc.NewSite(classes).Write(out); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Documented %d classes in %s\n", len(classes), out)
+}
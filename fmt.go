@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/jackfmt"
+)
+
+func printFmtHelpMessage() {
+	log.SetFlags(0)
+	log.Fatalln(("usage:\n go run . fmt --src <fileName.jack>\tRewrites the specified .jack file in the canonical style\n go run . fmt --src <dirName>\t\tRewrites every .jack file in the specified directory\n go run . fmt -l --src <src>\t\tLists the files that are not formatted, without rewriting them\n go run . fmt -d --src <src>\t\tPrints the changes formatting would make, without rewriting files"))
+}
+
+// formatSources formats '.jack' files in place. With '-l' or '-d' nothing is
+// written, and it exits with status 1 when a file is not formatted, for CI.
+func formatSources(args []string) {
+	var source string
+	var list, diff bool
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	flags.StringVar(&source, "src", "", "Path to a '.jack' file or a directory containing '.jack' files.")
+	flags.BoolVar(&list, "l", false, "List the files whose formatting differs from the canonical style.")
+	flags.BoolVar(&diff, "d", false, "Print the differences between the files and their formatted version.")
+	flags.Parse(args)
+
+	info, err := os.Stat(source)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	files := []string{source}
+
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(source, "*.jack")); err != nil {
+			log.Fatal(err)
+		}
+		sort.Strings(files)
+	} else if !strings.HasSuffix(source, ".jack") {
+		printFmtHelpMessage()
+	}
+
+	failed, unformatted := false, false
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+
+		if err != nil {
+			log.Println(err)
+			failed = true
+			continue
+		}
+
+		formatted, err := jackfmt.Source(file, content)
+
+		if err != nil {
+			log.Println(err)
+			failed = true
+			continue
+		}
+
+		if bytes.Equal(content, formatted) {
+			continue
+		}
+
+		unformatted = true
+
+		if list {
+			os.Stdout.WriteString(file + "\n")
+		}
+
+		if diff {
+			os.Stdout.Write(jackfmt.Diff(file, content, formatted))
+		}
+
+		if !list && !diff {
+			if err := os.WriteFile(file, formatted, 0644); err != nil {
+				log.Println(err)
+				failed = true
+			}
+		}
+	}
+
+	if failed || (unformatted && (list || diff)) {
+		os.Exit(1)
+	}
+}
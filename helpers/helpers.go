@@ -36,6 +36,19 @@ func IsLiteralType(token types.Token) bool {
 		Contains([]string{"true", "false", "null", "this"}, token.Lexeme)
 }
 
+// WithoutComments returns the tokens that are not comments.
+func WithoutComments(tokens []types.Token) []types.Token {
+	filtered := make([]types.Token, 0, len(tokens))
+
+	for _, token := range tokens {
+		if token.TokenType != types.COMMENT {
+			filtered = append(filtered, token)
+		}
+	}
+
+	return filtered
+}
+
 func Contains[T comparable](arr []T, elem T) bool {
 	for _, value := range arr {
 		if value == elem {
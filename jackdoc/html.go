@@ -0,0 +1,123 @@
+package jackdoc
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const STYLE = `body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+code, pre { font-family: monospace; background: #f4f4f4; }
+pre { padding: 0.5em; overflow-x: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; vertical-align: top; }
+h3 { margin-top: 2em; }`
+
+// paragraphs returns a description as HTML paragraphs.
+func paragraphs(doc string) string {
+	if doc == "" {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		fmt.Fprintf(&builder, "<p>%s</p>\n", html.EscapeString(strings.TrimSpace(paragraph)))
+	}
+
+	return builder.String()
+}
+
+// htmlType links a type to the page of its class, when the site has one.
+func (site *Site) htmlType(typeName string) string {
+	if site.documented[typeName] {
+		return fmt.Sprintf(`<a href="%s.html"><code>%s</code></a>`, html.EscapeString(typeName), html.EscapeString(typeName))
+	}
+	return "<code>" + html.EscapeString(typeName) + "</code>"
+}
+
+func page(title, body string) string {
+	return fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n%s</body>\n</html>\n", html.EscapeString(title), STYLE, body)
+}
+
+// HTMLIndex returns the HTML index of the classes of the site.
+func (site *Site) HTMLIndex() string {
+	var builder strings.Builder
+
+	builder.WriteString("<h1>Classes</h1>\n<table>\n<tr><th>Class</th><th>Description</th></tr>\n")
+
+	for _, class := range site.Classes {
+		fmt.Fprintf(&builder, "<tr><td>%s</td><td>%s</td></tr>\n", site.htmlType(class.Name), html.EscapeString(summary(class.Doc)))
+	}
+
+	builder.WriteString("</table>\n")
+	return page("Classes", builder.String())
+}
+
+func (site *Site) htmlVariables(builder *strings.Builder, title string, variables []Variable) {
+	if len(variables) == 0 {
+		return
+	}
+
+	fmt.Fprintf(builder, "<h2>%s</h2>\n<table>\n<tr><th>Name</th><th>Type</th><th>Description</th></tr>\n", title)
+
+	for _, variable := range variables {
+		fmt.Fprintf(builder, "<tr><td><code>%s</code></td><td>%s</td><td>%s</td></tr>\n", html.EscapeString(variable.Name), site.htmlType(variable.Type), html.EscapeString(variable.Doc))
+	}
+
+	builder.WriteString("</table>\n")
+}
+
+func (site *Site) htmlSubroutines(builder *strings.Builder, title string, subroutines []Subroutine) {
+	if len(subroutines) == 0 {
+		return
+	}
+
+	fmt.Fprintf(builder, "<h2>%s</h2>\n", title)
+
+	for _, subroutine := range subroutines {
+		name := html.EscapeString(subroutine.Name)
+		fmt.Fprintf(builder, "<h3 id=\"%s\">%s</h3>\n<pre><code>%s</code></pre>\n", name, name, html.EscapeString(subroutine.Signature))
+		builder.WriteString(paragraphs(subroutine.Doc))
+
+		if len(subroutine.Params) > 0 {
+			builder.WriteString("<h4>Parameters</h4>\n<ul>\n")
+
+			for _, param := range subroutine.Params {
+				fmt.Fprintf(builder, "<li><code>%s</code> %s", html.EscapeString(param.Name), site.htmlType(param.Type))
+
+				if param.Doc != "" {
+					builder.WriteString(": " + html.EscapeString(param.Doc))
+				}
+
+				builder.WriteString("</li>\n")
+			}
+
+			builder.WriteString("</ul>\n")
+		}
+
+		if subroutine.Type != "void" {
+			fmt.Fprintf(builder, "<h4>Returns</h4>\n<p>%s", site.htmlType(subroutine.Type))
+
+			if subroutine.Return != "" {
+				builder.WriteString(": " + html.EscapeString(subroutine.Return))
+			}
+
+			builder.WriteString("</p>\n")
+		}
+	}
+}
+
+// HTML returns the HTML page of a class of the site.
+func (site *Site) HTML(class Class) string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "<p><a href=\"index.html\">All classes</a></p>\n<h1>class %s</h1>\n", html.EscapeString(class.Name))
+	builder.WriteString(paragraphs(class.Doc))
+	site.htmlVariables(&builder, "Fields", class.Fields)
+	site.htmlVariables(&builder, "Statics", class.Statics)
+	site.htmlSubroutines(&builder, "Constructors", class.Constructors)
+	site.htmlSubroutines(&builder, "Methods", class.Methods)
+	site.htmlSubroutines(&builder, "Functions", class.Functions)
+	return page("class "+class.Name, builder.String())
+}
@@ -0,0 +1,221 @@
+package jackdoc
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/completion"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+DOC COMMENTS
+
+The doc comment of a declaration is the comment starting with '/**' right
+before it. Once its delimiters and the '*' starting its lines are removed,
+its text is a description, in paragraphs separated by blank lines, followed
+by tags that document the parts of a subroutine:
+
+	Moves the square by the given offsets.
+	Does nothing when the square would leave the screen.
+
+	@param dx the horizontal offset, in pixels
+	@param dy the vertical offset, in pixels
+	@return whether the square moved
+
+A tag runs until the next tag or blank line. Other tags, such as '@see', are
+kept in the description.
+
+A site documents every class of a directory with a page per class, listing
+its fields, statics, constructors, methods and functions, and an index. It
+is written both as HTML (index.html, Foo.html) and as Markdown (README.md,
+Foo.md), whose links work when browsing the directory in a repository.
+*/
+
+type Variable struct {
+	Doc  string
+	Name string
+	Type string
+}
+
+type Param struct {
+	Doc  string
+	Name string
+	Type string
+}
+
+type Subroutine struct {
+	// Doc is the description of the doc comment, without its tags.
+	Doc       string
+	Kind      types.SymbolKind
+	Name      string
+	Params    []Param
+	Return    string
+	Signature string
+	Type      string
+}
+
+type Class struct {
+	Constructors []Subroutine
+	Doc          string
+	Fields       []Variable
+	Functions    []Subroutine
+	Methods      []Subroutine
+	Name         string
+	Statics      []Variable
+}
+
+type Site struct {
+	// Classes are sorted by name.
+	Classes []Class
+	// documented holds the name of every class of the site, which types link to.
+	documented map[string]bool
+}
+
+// comment is a doc comment split into its description and tags.
+type comment struct {
+	description string
+	params      map[string]string
+	returns     string
+}
+
+// tag is a '@param' or '@return' tag of a doc comment.
+type tag struct {
+	kind string
+	name string
+	text string
+}
+
+func parseComment(doc string) comment {
+	description := []string{}
+	tags := []tag{}
+	// inTag is set while the lines continue the last tag.
+	inTag := false
+
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		fields := strings.Fields(line)
+
+		switch {
+		case len(fields) > 1 && fields[0] == "@param":
+			text := strings.TrimSpace(strings.TrimPrefix(line, "@param"))
+			tags = append(tags, tag{kind: "@param", name: fields[1], text: strings.TrimSpace(strings.TrimPrefix(text, fields[1]))})
+			inTag = true
+
+		case len(fields) > 0 && (fields[0] == "@return" || fields[0] == "@returns"):
+			tags = append(tags, tag{kind: "@return", text: strings.TrimSpace(strings.TrimPrefix(line, fields[0]))})
+			inTag = true
+
+		case line == "":
+			inTag = false
+			description = append(description, "")
+
+		case inTag:
+			tags[len(tags)-1].text = strings.TrimSpace(tags[len(tags)-1].text + " " + line)
+
+		default:
+			description = append(description, line)
+		}
+	}
+
+	parsed := comment{description: strings.TrimSpace(strings.Join(description, "\n")), params: map[string]string{}}
+
+	for _, tag := range tags {
+		if tag.kind == "@param" {
+			parsed.params[tag.name] = tag.text
+		} else {
+			parsed.returns = tag.text
+		}
+	}
+
+	return parsed
+}
+
+func newSubroutine(decl types.SubroutineDecl) Subroutine {
+	parsed := parseComment(decl.Doc)
+	subroutine := Subroutine{
+		Doc:       parsed.description,
+		Kind:      decl.Kind,
+		Name:      decl.Name.Name,
+		Return:    parsed.returns,
+		Signature: completion.Signature("", decl),
+		Type:      decl.Type,
+	}
+
+	for _, param := range decl.Params {
+		subroutine.Params = append(subroutine.Params, Param{Doc: parsed.params[param.Name], Name: param.Name, Type: param.Type})
+	}
+
+	return subroutine
+}
+
+func NewClass(decl types.Class) Class {
+	class := Class{Doc: parseComment(decl.Doc).description, Name: decl.Name.Name}
+
+	for _, decl := range decl.Vars {
+		variable := Variable{Doc: parseComment(decl.Doc).description, Name: decl.Name, Type: decl.Type}
+
+		if decl.Kind == types.Static {
+			class.Statics = append(class.Statics, variable)
+		} else {
+			class.Fields = append(class.Fields, variable)
+		}
+	}
+
+	for _, decl := range decl.Subroutines {
+		switch subroutine := newSubroutine(decl); decl.Kind {
+		case types.Constructor:
+			class.Constructors = append(class.Constructors, subroutine)
+		case types.Method:
+			class.Methods = append(class.Methods, subroutine)
+		default:
+			class.Functions = append(class.Functions, subroutine)
+		}
+	}
+
+	return class
+}
+
+// NewSite documents classes parsed with their comments.
+func NewSite(classes []types.Class) *Site {
+	site := &Site{documented: map[string]bool{}}
+
+	for _, class := range classes {
+		site.Classes = append(site.Classes, NewClass(class))
+		site.documented[class.Name.Name] = true
+	}
+
+	sort.Slice(site.Classes, func(i, j int) bool {
+		return site.Classes[i].Name < site.Classes[j].Name
+	})
+
+	return site
+}
+
+// Write writes the HTML and Markdown pages of the site to dir, which is
+// created if needed.
+func (site *Site) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"index.html": site.HTMLIndex(),
+		"README.md":  site.MarkdownIndex(),
+	}
+
+	for _, class := range site.Classes {
+		files[class.Name+".html"] = site.HTML(class)
+		files[class.Name+".md"] = site.Markdown(class)
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
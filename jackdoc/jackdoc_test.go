@@ -0,0 +1,155 @@
+package jackdoc_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/jackdoc"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+const POINT = `/**
+ * A point of the screen.
+ *
+ * Points are immutable.
+ */
+class Point {
+  /** The coordinates, in pixels. */
+  field int x, y;
+  static int count;
+
+  /**
+   * Creates a point.
+   * @param ax the abscissa, which
+   *   must be positive
+   * @param ay the ordinate
+   * @return the new point
+   */
+  constructor Point new(int ax, int ay) {
+    let x = ax;
+    let y = ay;
+    return this;
+  }
+
+  /** Returns whether x < y | y < x. @see Math */
+  method boolean differs() { return ~(x = y); }
+
+  /** Moves a point.
+   * @param p the point */
+  function void move(Point p) { return; }
+}
+`
+
+func parse(t *testing.T, source string) types.Class {
+	t.Helper()
+	tokens, err := lexer.NewLexer(lexer.WithComments()).TokenizeReader("Point.jack", strings.NewReader(source))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := parser.NewParser().Parse(tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return class
+}
+
+func TestNewClass(t *testing.T) {
+	class := NewClass(parse(t, POINT))
+
+	if class.Doc != "A point of the screen.\n\nPoints are immutable." {
+		t.Errorf("Unexpected class doc %q", class.Doc)
+	}
+
+	expectedFields := []Variable{{Doc: "The coordinates, in pixels.", Name: "x", Type: "int"}, {Doc: "The coordinates, in pixels.", Name: "y", Type: "int"}}
+
+	if !reflect.DeepEqual(class.Fields, expectedFields) || len(class.Statics) != 1 {
+		t.Errorf("Unexpected variables %+v %+v", class.Fields, class.Statics)
+	}
+
+	expected := Subroutine{
+		Doc:  "Creates a point.",
+		Kind: types.Constructor,
+		Name: "new",
+		Params: []Param{
+			{Doc: "the abscissa, which must be positive", Name: "ax", Type: "int"},
+			{Doc: "the ordinate", Name: "ay", Type: "int"},
+		},
+		Return:    "the new point",
+		Signature: "constructor Point new(int ax, int ay)",
+		Type:      "Point",
+	}
+
+	if len(class.Constructors) != 1 || !reflect.DeepEqual(class.Constructors[0], expected) {
+		t.Errorf("Expected %+v, got %+v", expected, class.Constructors)
+	}
+
+	if len(class.Methods) != 1 || class.Methods[0].Doc != "Returns whether x < y | y < x. @see Math" {
+		t.Errorf("Unexpected methods %+v", class.Methods)
+	}
+
+	if len(class.Functions) != 1 || class.Functions[0].Doc != "Moves a point." || class.Functions[0].Params[0].Doc != "the point" {
+		t.Errorf("Unexpected functions %+v", class.Functions)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	site := NewSite([]types.Class{parse(t, POINT)})
+	markdown := site.Markdown(site.Classes[0])
+
+	for _, expected := range []string{
+		"# class Point\n\nA point of the screen.\n\nPoints are immutable.\n",
+		"## Fields\n\n| Name | Type | Description |\n| --- | --- | --- |\n| `x` | `int` | The coordinates, in pixels. |\n",
+		"## Statics\n",
+		"### new\n\n```jack\nconstructor Point new(int ax, int ay)\n```\n\nCreates a point.\n\n**Parameters**\n\n- `ax` `int`: the abscissa, which must be positive\n",
+		"**Returns** [`Point`](Point.md): the new point\n",
+		"- `p` [`Point`](Point.md): the point\n",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("Expected the page to contain %q, got:\n%s", expected, markdown)
+		}
+	}
+
+	if index := site.MarkdownIndex(); !strings.Contains(index, "| [`Point`](Point.md) | A point of the screen. |") {
+		t.Errorf("Unexpected index:\n%s", index)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	site := NewSite([]types.Class{parse(t, POINT)})
+	page := site.HTML(site.Classes[0])
+
+	for _, expected := range []string{
+		"<title>class Point</title>",
+		"<p>A point of the screen.</p>\n<p>Points are immutable.</p>\n",
+		`<h3 id="differs">differs</h3>`,
+		"<p>Returns whether x &lt; y | y &lt; x. @see Math</p>",
+		`<li><code>p</code> <a href="Point.html"><code>Point</code></a>: the point</li>`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected the page to contain %q, got:\n%s", expected, page)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "doc")
+
+	if err := NewSite([]types.Class{parse(t, POINT)}).Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"index.html", "Point.html", "README.md", "Point.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
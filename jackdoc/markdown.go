@@ -0,0 +1,109 @@
+package jackdoc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// summary returns the first paragraph of a description, on a single line.
+func summary(doc string) string {
+	paragraph, _, _ := strings.Cut(doc, "\n\n")
+	return strings.Join(strings.Fields(paragraph), " ")
+}
+
+// cell escapes text for a cell of a Markdown table.
+func cell(text string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(text), " "), "|", "\\|")
+}
+
+// markdownType links a type to the page of its class, when the site has one.
+func (site *Site) markdownType(typeName string) string {
+	if site.documented[typeName] {
+		return fmt.Sprintf("[`%s`](%s.md)", typeName, typeName)
+	}
+	return "`" + typeName + "`"
+}
+
+// MarkdownIndex returns the Markdown index of the classes of the site.
+func (site *Site) MarkdownIndex() string {
+	var builder strings.Builder
+
+	builder.WriteString("# Classes\n\n| Class | Description |\n| --- | --- |\n")
+
+	for _, class := range site.Classes {
+		fmt.Fprintf(&builder, "| %s | %s |\n", site.markdownType(class.Name), cell(summary(class.Doc)))
+	}
+
+	return builder.String()
+}
+
+func (site *Site) markdownVariables(builder *strings.Builder, title string, variables []Variable) {
+	if len(variables) == 0 {
+		return
+	}
+
+	fmt.Fprintf(builder, "\n## %s\n\n| Name | Type | Description |\n| --- | --- | --- |\n", title)
+
+	for _, variable := range variables {
+		fmt.Fprintf(builder, "| `%s` | %s | %s |\n", variable.Name, site.markdownType(variable.Type), cell(variable.Doc))
+	}
+}
+
+func (site *Site) markdownSubroutines(builder *strings.Builder, title string, subroutines []Subroutine) {
+	if len(subroutines) == 0 {
+		return
+	}
+
+	fmt.Fprintf(builder, "\n## %s\n", title)
+
+	for _, subroutine := range subroutines {
+		fmt.Fprintf(builder, "\n### %s\n\n```jack\n%s\n```\n", subroutine.Name, subroutine.Signature)
+
+		if subroutine.Doc != "" {
+			builder.WriteString("\n" + subroutine.Doc + "\n")
+		}
+
+		if len(subroutine.Params) > 0 {
+			builder.WriteString("\n**Parameters**\n\n")
+
+			for _, param := range subroutine.Params {
+				fmt.Fprintf(builder, "- `%s` %s", param.Name, site.markdownType(param.Type))
+
+				if param.Doc != "" {
+					builder.WriteString(": " + param.Doc)
+				}
+
+				builder.WriteString("\n")
+			}
+		}
+
+		if subroutine.Type != "void" {
+			fmt.Fprintf(builder, "\n**Returns** %s", site.markdownType(subroutine.Type))
+
+			if subroutine.Return != "" {
+				builder.WriteString(": " + subroutine.Return)
+			}
+
+			builder.WriteString("\n")
+		}
+	}
+}
+
+// Markdown returns the Markdown page of a class of the site.
+func (site *Site) Markdown(class Class) string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "# class %s\n", class.Name)
+
+	if class.Doc != "" {
+		builder.WriteString("\n" + class.Doc + "\n")
+	}
+
+	site.markdownVariables(&builder, "Fields", class.Fields)
+	site.markdownVariables(&builder, "Statics", class.Statics)
+	site.markdownSubroutines(&builder, "Constructors", class.Constructors)
+	site.markdownSubroutines(&builder, "Methods", class.Methods)
+	site.markdownSubroutines(&builder, "Functions", class.Functions)
+	builder.WriteString("\n[All classes](README.md)\n")
+	return builder.String()
+}
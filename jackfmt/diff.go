@@ -0,0 +1,158 @@
+package jackfmt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// CONTEXT is the number of unchanged lines shown around each change.
+const CONTEXT = 3
+
+type edit struct {
+	// kind is ' ' for a line of both texts, '-' for a line of the old text
+	// only and '+' for a line of the new text only.
+	kind byte
+	line string
+}
+
+func splitLines(text []byte) []string {
+	lines := strings.SplitAfter(string(text), "\n")
+
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// edits returns the shortest edit script from a to b, from their longest
+// common subsequence of lines.
+func edits(a, b []string) []edit {
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	script := []edit{}
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			script = append(script, edit{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && common[i+1][j] >= common[i][j+1]):
+			script = append(script, edit{'-', a[i]})
+			i++
+		default:
+			script = append(script, edit{'+', b[j]})
+			j++
+		}
+	}
+
+	return script
+}
+
+// Diff returns the differences between the old and new text of a file in
+// unified format, or nil when they are equal.
+func Diff(name string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	var output bytes.Buffer
+	script := edits(splitLines(old), splitLines(new))
+	fmt.Fprintf(&output, "--- %s\n+++ %s\n", name, name)
+
+	// oldLine and newLine are the lines of each text at the start of the script.
+	for start, oldLine, newLine := 0, 1, 1; start < len(script); {
+		// Find the next change, and the unchanged lines shown before it.
+		first := start
+
+		for first < len(script) && script[first].kind == ' ' {
+			first++
+		}
+
+		if first == len(script) {
+			break
+		}
+
+		from := first - CONTEXT
+
+		if from < start {
+			from = start
+		}
+
+		// A hunk ends after CONTEXT unchanged lines that are not followed by
+		// another change within CONTEXT lines.
+		end, unchanged := first, 0
+
+		for end < len(script) {
+			if script[end].kind != ' ' {
+				unchanged = 0
+			} else if unchanged == 2*CONTEXT {
+				break
+			} else {
+				unchanged++
+			}
+			end++
+		}
+
+		if unchanged > CONTEXT {
+			end -= unchanged - CONTEXT
+		}
+
+		for _, e := range script[start:from] {
+			if e.kind != '+' {
+				oldLine++
+			}
+			if e.kind != '-' {
+				newLine++
+			}
+		}
+
+		oldCount, newCount := 0, 0
+
+		for _, e := range script[from:end] {
+			if e.kind != '+' {
+				oldCount++
+			}
+			if e.kind != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&output, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+
+		for _, e := range script[from:end] {
+			output.WriteByte(e.kind)
+			output.WriteString(e.line)
+
+			if !strings.HasSuffix(e.line, "\n") {
+				output.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		oldLine += oldCount
+		newLine += newCount
+		start = end
+	}
+
+	return output.Bytes()
+}
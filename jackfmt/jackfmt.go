@@ -0,0 +1,365 @@
+package jackfmt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/helpers"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+CANONICAL STYLE
+
+	// Comment.
+	class Foo {
+	    field int x, y; // one declaration per line, as grouped in the source
+	    static boolean flag;
+
+	    method void bar(int a, char b) {
+	        var int i;
+
+	        let x = (a + 1) * -b;
+	        if (~flag) {
+	            do Output.printInt(x);
+	        } else {
+	            return;
+	        }
+	        return;
+	    }
+	}
+
+Blocks are indented by four spaces and open on the line of their statement.
+Binary operators are surrounded by spaces, unary operators are not, and a
+comma is followed by a space. Subroutines are separated by a blank line;
+elsewhere, a run of blank lines is kept as a single blank line.
+
+Comments are kept: a comment that ends a line of code stays at the end of
+that line, any other comment, including one within the code of a line, is
+written on its own line before the code that follows it. The lines of a
+block comment keep their text, and are re-indented when they start with '*'.
+*/
+
+const INDENT = "    "
+
+type printer struct {
+	buffer   bytes.Buffer
+	comments []types.Token
+	indent   int
+	// lastLine is the source line of the last thing written.
+	lastLine int
+	// blank forces a blank line before the next line, open prevents one
+	// as the next line is the first of a block.
+	blank bool
+	open  bool
+	// trailed is set when the last line ends with a comment.
+	trailed bool
+	// tokens are the tokens of the class, used to find the groups of its
+	// declarations.
+	tokens []types.Token
+}
+
+// Source formats the source of a class. filename is only used to locate
+// errors; source with syntax errors is not formatted.
+func Source(filename string, source []byte) ([]byte, error) {
+	tokens, err := lexer.NewLexer(lexer.WithComments()).TokenizeReader(filename, bytes.NewReader(source))
+
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := parser.NewParser().Parse(tokens)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &printer{tokens: helpers.WithoutComments(tokens)}
+
+	for _, token := range tokens {
+		if token.TokenType == types.COMMENT {
+			p.comments = append(p.comments, token)
+		}
+	}
+
+	if class.Name.Name != "" {
+		p.class(class)
+	}
+
+	p.flush(types.Position{Offset: len(source) + 1})
+	return p.buffer.Bytes(), nil
+}
+
+// comment writes a comment on its own line. The continuation lines of a
+// block comment that start with '*' are aligned under its first '*'.
+func (p *printer) comment(comment types.Token) {
+	lines := strings.Split(comment.Lexeme, "\n")
+
+	for index, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+
+		if index > 0 {
+			if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "*") {
+				line = " " + trimmed
+			}
+		}
+
+		if line == "" {
+			p.buffer.WriteString("\n")
+		} else {
+			p.buffer.WriteString(strings.Repeat(INDENT, p.indent) + line + "\n")
+		}
+	}
+}
+
+// separate writes the blank line that precedes something starting on line.
+func (p *printer) separate(line int) {
+	if !p.open && (p.blank || line > p.lastLine+1) && p.buffer.Len() > 0 {
+		p.buffer.WriteString("\n")
+	}
+
+	p.blank, p.open, p.trailed = false, false, false
+}
+
+// flush writes the comments that start before pos on their own lines.
+func (p *printer) flush(pos types.Position) {
+	for len(p.comments) > 0 && p.comments[0].Offset < pos.Offset {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		p.separate(comment.LineNum)
+		p.comment(comment)
+		p.lastLine = comment.End().Line
+	}
+}
+
+// line writes the text of the source from start to end on a line of its
+// own, preceded by the comments within that text and followed by the
+// comments that end that source line.
+func (p *printer) line(start, end types.Position, text string) {
+	p.flush(end)
+	p.separate(start.Line)
+	p.buffer.WriteString(strings.Repeat(INDENT, p.indent) + text)
+	p.lastLine = end.Line
+
+	for len(p.comments) > 0 && p.comments[0].LineNum == end.Line && !p.codeBetween(end, p.comments[0].Pos()) {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		p.buffer.WriteString(" " + strings.TrimRight(comment.Lexeme, " \t\r"))
+		p.lastLine = comment.End().Line
+		p.trailed = true
+	}
+
+	p.buffer.WriteString("\n")
+}
+
+// codeBetween reports whether a token of code sits between start and end.
+func (p *printer) codeBetween(start, end types.Position) bool {
+	for _, token := range p.tokens {
+		if token.Offset >= start.Offset && token.Offset < end.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+// continuation writes the comments before pos that continue the comment
+// ending the last line, on the lines that follow it.
+func (p *printer) continuation(pos types.Position) {
+	for p.trailed && len(p.comments) > 0 && p.comments[0].LineNum == p.lastLine+1 && p.comments[0].Offset < pos.Offset {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		p.separate(comment.LineNum)
+		p.comment(comment)
+		p.lastLine = comment.End().Line
+		p.trailed = true
+	}
+}
+
+// next returns the position immediately after pos, on the same line.
+func next(pos types.Position) types.Position {
+	pos.Column++
+	pos.Offset++
+	return pos
+}
+
+// previous returns the position of the character before pos, on the same line.
+func previous(pos types.Position) types.Position {
+	pos.Column--
+	pos.Offset--
+	return pos
+}
+
+// closeBlock writes the comments left in a block, before its closing
+// brace. Blank lines are dropped before the brace.
+func (p *printer) closeBlock(end types.Position) {
+	p.flush(previous(end))
+	p.open = true
+	p.indent--
+}
+
+func (p *printer) class(class types.Class) {
+	p.line(class.Pos(), class.Name.End(), fmt.Sprintf("class %s {", class.Name))
+	p.indent++
+	p.open = true
+	p.vars(class.Vars)
+
+	for _, subroutine := range class.Subroutines {
+		p.continuation(subroutine.Pos())
+
+		if p.buffer.Len() > 0 && !p.open {
+			p.blank = true
+		}
+		p.subroutine(subroutine)
+	}
+
+	p.closeBlock(class.End())
+	p.line(previous(class.End()), class.End(), "}")
+}
+
+// vars writes declarations grouped as in the source: the variables of a
+// group are separated by commas, groups by semicolons.
+func (p *printer) vars(decls []types.VarDecl) {
+	for start := 0; start < len(decls); {
+		end := start + 1
+
+		for end < len(decls) && p.sameGroup(decls[end-1], decls[end]) {
+			end++
+		}
+
+		names := []string{}
+
+		for _, decl := range decls[start:end] {
+			names = append(names, decl.Name)
+		}
+
+		// The declaration starts with its keyword, two tokens before its first name.
+		p.line(p.tokenBefore(decls[start].Pos(), 2), next(decls[end-1].End()), fmt.Sprintf("%s %s %s;", decls[start].Kind, decls[start].Type, strings.Join(names, ", ")))
+		start = end
+	}
+}
+
+// tokenBefore returns the position of the token count tokens before pos.
+func (p *printer) tokenBefore(pos types.Position, count int) types.Position {
+	for index, token := range p.tokens {
+		if token.Offset == pos.Offset && index >= count {
+			return p.tokens[index-count].Pos()
+		}
+	}
+	return pos
+}
+
+// sameGroup reports whether two consecutive declarations are separated by a comma.
+func (p *printer) sameGroup(a, b types.VarDecl) bool {
+	for _, token := range p.tokens {
+		if token.Offset >= a.End().Offset && token.Offset < b.Pos().Offset {
+			return helpers.IsOneOfSymbols(token, []string{","})
+		}
+	}
+	return false
+}
+
+func (p *printer) subroutine(subroutine types.SubroutineDecl) {
+	params := []string{}
+
+	for _, param := range subroutine.Params {
+		params = append(params, fmt.Sprintf("%s %s", param.Type, param.Name))
+	}
+
+	header := fmt.Sprintf("%s %s %s(%s) {", subroutine.Kind, subroutine.Type, subroutine.Name, strings.Join(params, ", "))
+	p.line(subroutine.Pos(), next(subroutine.Body.Pos()), header)
+	p.indent++
+	p.open = true
+	p.vars(subroutine.Body.Vars)
+	p.statements(subroutine.Body.Statements)
+	p.closeBlock(subroutine.Body.End())
+	p.line(previous(subroutine.Body.End()), subroutine.Body.End(), "}")
+}
+
+func (p *printer) statements(stmts []types.Stmt) {
+	for _, stmt := range stmts {
+		p.statement(stmt)
+	}
+}
+
+// block writes the statements of a block opened by the line that was just
+// written, up to its closing brace, which is not written.
+func (p *printer) block(block types.BlockStmt) {
+	p.indent++
+	p.open = true
+	p.statements(block.Statements)
+	p.closeBlock(block.End())
+}
+
+func (p *printer) statement(stmt types.Stmt) {
+	switch stmt := stmt.(type) {
+	case types.DoStmt:
+		p.line(stmt.Pos(), stmt.End(), fmt.Sprintf("do %s;", expression(stmt.Expression)))
+
+	case types.LetStmt:
+		p.line(stmt.Pos(), stmt.End(), fmt.Sprintf("let %s = %s;", expression(stmt.Target), expression(stmt.Value)))
+
+	case types.ReturnStmt:
+		if stmt.Expression == nil {
+			p.line(stmt.Pos(), stmt.End(), "return;")
+		} else {
+			p.line(stmt.Pos(), stmt.End(), fmt.Sprintf("return %s;", expression(stmt.Expression)))
+		}
+
+	case types.IfStmt:
+		p.line(stmt.Pos(), next(stmt.ThenStmt.Pos()), fmt.Sprintf("if (%s) {", expression(stmt.Condition)))
+		p.block(stmt.ThenStmt)
+
+		// An empty else block leaves no trace in the AST, and is dropped.
+		if stmt.ElseStmt.Pos().IsValid() {
+			p.line(previous(stmt.ThenStmt.End()), next(stmt.ElseStmt.Pos()), "} else {")
+			p.block(stmt.ElseStmt)
+			p.line(previous(stmt.ElseStmt.End()), stmt.ElseStmt.End(), "}")
+		} else {
+			p.line(previous(stmt.ThenStmt.End()), stmt.ThenStmt.End(), "}")
+		}
+
+	case types.WhileStmt:
+		p.line(stmt.Pos(), next(stmt.Body.Pos()), fmt.Sprintf("while (%s) {", expression(stmt.Condition)))
+		p.block(stmt.Body)
+		p.line(previous(stmt.Body.End()), stmt.Body.End(), "}")
+	}
+}
+
+func expression(expr types.Expr) string {
+	switch expr := expr.(type) {
+	case types.BinaryExpr:
+		return fmt.Sprintf("%s %s %s", expression(expr.Left), expr.Operator, expression(expr.Right))
+
+	case types.LogicalExpr:
+		return fmt.Sprintf("%s %s %s", expression(expr.Left), expr.Operator, expression(expr.Right))
+
+	case types.UnaryExpr:
+		return fmt.Sprintf("%s%s", expr.Operator, expression(expr.Operand))
+
+	case types.ParenExpr:
+		return fmt.Sprintf("(%s)", expression(expr.Expression))
+
+	case types.IndexExpr:
+		return fmt.Sprintf("%s[%s]", expr.Object, expression(expr.Indexer))
+
+	case types.CallExpr:
+		args := []string{}
+
+		for _, arg := range expr.Arguments {
+			args = append(args, expression(arg))
+		}
+
+		return fmt.Sprintf("%s(%s)", expr.Callee, strings.Join(args, ", "))
+
+	case types.Literal:
+		if expr.Type == types.StringLiteral {
+			return `"` + expr.Value + `"`
+		}
+		return expr.Value
+	}
+
+	return fmt.Sprint(expr)
+}
@@ -0,0 +1,185 @@
+package jackfmt_test
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/codegen"
+	. "github.com/MlkMahmud/jack-compiler/jackfmt"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/resolver"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func compile(t *testing.T, source []byte) string {
+	t.Helper()
+	tokens, err := lexer.NewLexer().TokenizeReader("Test.jack", bytes.NewReader(source))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := parser.NewParser().Parse(tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if class, err = resolver.NewResolver().Resolve(class); err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := codegen.NewCodeGenerator().Generate(class)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code
+}
+
+func TestSource(t *testing.T) {
+	source := `// Header.
+class   Foo{
+  field int x,y;   // position
+  static boolean flag;
+  /** Moves. */
+  method void bar(int a,char b){var int i;
+
+
+    let x=(a+1)*-b;   if(~flag){do Output.printInt(x);}
+    else{ /* nothing */
+      return ;}
+    let y = Foo.baz(x, "a  b", y[1]);
+    return;
+  }
+  function int baz(int a, String s, Array c) { return a; }
+  function void qux(int x) {
+    do Output.printInt(x /* arg */, 2);
+    if (x) { let x = 2; } else { /* c */ }
+    while (x < 3) { let x = x + 1; /* inline */ let x = x; }
+    return;
+  }
+}
+`
+	expected := `// Header.
+class Foo {
+    field int x, y; // position
+    static boolean flag;
+
+    /** Moves. */
+    method void bar(int a, char b) {
+        var int i;
+
+        let x = (a + 1) * -b;
+        if (~flag) {
+            do Output.printInt(x);
+        } else { /* nothing */
+            return;
+        }
+        let y = Foo.baz(x, "a  b", y[1]);
+        return;
+    }
+
+    function int baz(int a, String s, Array c) {
+        return a;
+    }
+
+    function void qux(int x) {
+        /* arg */
+        do Output.printInt(x, 2);
+        if (x) {
+            let x = 2;
+        } else { /* c */
+        }
+        while (x < 3) {
+            let x = x + 1; /* inline */
+            let x = x;
+        }
+        return;
+    }
+}
+`
+	actual, err := Source("Foo.jack", []byte(source))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(actual) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, actual)
+	}
+	if again, err := Source("Foo.jack", actual); err != nil || !bytes.Equal(again, actual) {
+		t.Errorf("Formatting is not idempotent:\n%s", Diff("Foo.jack", actual, again))
+	}
+}
+
+func TestTestData(t *testing.T) {
+	for _, file := range []string{"Array", "Square", "SquareGame"} {
+		t.Run(file, func(t *testing.T) {
+			source, err := os.ReadFile(path.Join(TEST_DATA_PATH, file+".jack"))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			formatted, err := Source(file+".jack", source)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if again, err := Source(file+".jack", formatted); err != nil || !bytes.Equal(again, formatted) {
+				t.Errorf("Formatting is not idempotent:\n%s", Diff(file+".jack", formatted, again))
+			}
+
+			if compile(t, formatted) != compile(t, source) {
+				t.Error("Formatting changed the compiled code")
+			}
+
+			for _, comment := range [][]byte{[]byte("// waits for the key to be released"), []byte("// q key")} {
+				if bytes.Contains(source, comment) && !bytes.Contains(formatted, comment) {
+					t.Errorf("Comment %q was dropped", comment)
+				}
+			}
+		})
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	if _, err := Source("Foo.jack", []byte("class Foo {\n  function void f() {\n    let x = ;\n  }\n}\n")); err == nil {
+		t.Error("Expected a syntax error")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n")
+	new := []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n")
+	expected := `--- Foo.jack
++++ Foo.jack
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+
+	if actual := string(Diff("Foo.jack", old, new)); actual != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	if Diff("Foo.jack", old, old) != nil {
+		t.Error("Expected no difference")
+	}
+}
@@ -0,0 +1,872 @@
+package jackos
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/MlkMahmud/jack-compiler/screen"
+	"github.com/MlkMahmud/jack-compiler/vm"
+)
+
+/*
+The OS implements the classes of the Jack OS natively, as builtins of the VM
+interpreter. The machine only dispatches to them when the loaded VM code does
+not define the function, so a program can still ship its own implementation
+of any class. The native classes share their heap, so a program that
+implements Memory itself should implement Array and String too.
+
+HEAP
+
+Blocks are allocated first-fit from RAM[2048-16383], and the free list is
+kept by the OS instead of in the heap itself. Strings are heap blocks laid
+out as:
+
+	[maxLength, length, char 0, char 1, ..., char maxLength-1]
+
+SCREEN
+
+The screen is the 512 by 256 bitmap of the Hack computer, mapped to
+RAM[16384-24575]: pixel (x, y) is bit x%16 of RAM[16384 + 32*y + x/16].
+
+OUTPUT AND KEYBOARD
+
+Text is written to an io.Writer instead of being drawn on the screen, so
+moveCursor only checks its arguments. 'newline' (128) is written as '\n' and
+'backspace' (129) as '\b'. keyPressed returns the value of the keyboard
+register RAM[24576], and the other keyboard functions either read lines from
+an io.Reader or, like the Jack OS, wait for keys to be pressed and released
+on the register.
+
+Errors are reported with the codes of the Jack OS, e.g. 'ERR3' for a
+division by zero, and halt the machine.
+*/
+
+const (
+	HEAP_END     = screen.BASE
+	TEXT_ROWS    = 23
+	TEXT_COLUMNS = 64
+
+	// The characters of the Jack character set that are not ASCII.
+	NEWLINE   = 128
+	BACKSPACE = 129
+)
+
+// OSError is an error the Jack OS reports with Sys.error.
+type OSError struct {
+	Code    int
+	Message string
+}
+
+func (e *OSError) Error() string {
+	return fmt.Sprintf("ERR%d: %s", e.Code, e.Message)
+}
+
+func osError(code int, format string, args ...any) error {
+	return &OSError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+type block struct {
+	address int
+	size    int
+}
+
+type OS struct {
+	// Echo makes the keyboard display what it reads, as the Jack OS does.
+	// It can be turned off when the input is already echoed by a terminal.
+	Echo bool
+	// allocated maps the address of every allocated block to its size.
+	allocated map[int]int
+	color     bool
+	free      []block
+	input     *bufio.Reader
+	// key is the key pressed on the keyboard register, read once it is released.
+	key    int16
+	line   []byte
+	output io.Writer
+	// reading is true while a line is being read.
+	reading bool
+}
+
+// New returns an OS that writes its output to output and reads the keyboard
+// from input. With a nil input, the keyboard is read from the keyboard
+// register, which the program's environment must set.
+func New(output io.Writer, input io.Reader) *OS {
+	system := &OS{
+		Echo:   true,
+		output: output,
+	}
+
+	if input != nil {
+		system.input = bufio.NewReader(input)
+	}
+
+	system.reset()
+	return system
+}
+
+func (system *OS) reset() {
+	system.allocated = map[int]int{}
+	system.color = true
+	system.key = 0
+	system.reading = false
+	system.free = []block{{address: vm.HEAP_BASE, size: HEAP_END - vm.HEAP_BASE}}
+}
+
+// Register resets the OS and makes it handle the calls of a machine to the OS
+// classes the machine does not load itself.
+func (system *OS) Register(machine *vm.Machine) {
+	system.reset()
+
+	for name, builtin := range system.Builtins() {
+		machine.Register(name, builtin)
+	}
+}
+
+// Builtins returns the implementation of every subroutine of the Jack OS, by
+// VM function name.
+func (system *OS) Builtins() map[string]vm.Builtin {
+	none := func(machine *vm.Machine, args []int16) (int16, error) {
+		return 0, nil
+	}
+
+	return map[string]vm.Builtin{
+		"Array.new":     system.arrayNew,
+		"Array.dispose": system.memoryDeAlloc,
+
+		"Keyboard.init":       none,
+		"Keyboard.keyPressed": keyboardKeyPressed,
+		"Keyboard.readChar":   system.keyboardReadChar,
+		"Keyboard.readLine":   system.keyboardReadLine,
+		"Keyboard.readInt":    system.keyboardReadInt,
+
+		"Math.init":     none,
+		"Math.abs":      mathAbs,
+		"Math.multiply": mathMultiply,
+		"Math.divide":   mathDivide,
+		"Math.sqrt":     mathSqrt,
+		"Math.max":      mathMax,
+		"Math.min":      mathMin,
+
+		"Memory.init":    system.memoryInit,
+		"Memory.peek":    memoryPeek,
+		"Memory.poke":    memoryPoke,
+		"Memory.alloc":   system.memoryAlloc,
+		"Memory.deAlloc": system.memoryDeAlloc,
+
+		"Output.init":        none,
+		"Output.moveCursor":  outputMoveCursor,
+		"Output.printChar":   system.outputPrintChar,
+		"Output.printString": system.outputPrintString,
+		"Output.printInt":    system.outputPrintInt,
+		"Output.println":     system.outputPrintln,
+		"Output.backSpace":   system.outputBackSpace,
+
+		"Screen.init":          system.screenInit,
+		"Screen.clearScreen":   screenClearScreen,
+		"Screen.setColor":      system.screenSetColor,
+		"Screen.drawPixel":     system.screenDrawPixel,
+		"Screen.drawLine":      system.screenDrawLine,
+		"Screen.drawRectangle": system.screenDrawRectangle,
+		"Screen.drawCircle":    system.screenDrawCircle,
+
+		"String.new":           system.stringNew,
+		"String.dispose":       system.memoryDeAlloc,
+		"String.length":        stringLength,
+		"String.charAt":        stringCharAt,
+		"String.setCharAt":     stringSetCharAt,
+		"String.appendChar":    stringAppendChar,
+		"String.eraseLastChar": stringEraseLastChar,
+		"String.intValue":      stringIntValue,
+		"String.setInt":        stringSetInt,
+		"String.backSpace":     character(BACKSPACE),
+		"String.doubleQuote":   character('"'),
+		"String.newLine":       character(NEWLINE),
+
+		"Sys.halt":  sysHalt,
+		"Sys.error": system.sysError,
+		"Sys.wait":  sysWait,
+	}
+}
+
+/* Math */
+
+func mathAbs(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] < 0 {
+		return -args[0], nil
+	}
+	return args[0], nil
+}
+
+func mathMultiply(machine *vm.Machine, args []int16) (int16, error) {
+	return args[0] * args[1], nil
+}
+
+func mathDivide(machine *vm.Machine, args []int16) (int16, error) {
+	if args[1] == 0 {
+		return 0, osError(3, "division by zero")
+	}
+	return args[0] / args[1], nil
+}
+
+func mathSqrt(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] < 0 {
+		return 0, osError(4, "cannot compute the square root of a negative number")
+	}
+
+	root := 0
+
+	for (root+1)*(root+1) <= int(args[0]) {
+		root++
+	}
+
+	return int16(root), nil
+}
+
+func mathMax(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] > args[1] {
+		return args[0], nil
+	}
+	return args[1], nil
+}
+
+func mathMin(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] < args[1] {
+		return args[0], nil
+	}
+	return args[1], nil
+}
+
+/* Memory */
+
+func (system *OS) alloc(size int) (int16, error) {
+	for index, free := range system.free {
+		if free.size < size {
+			continue
+		}
+
+		if free.size == size {
+			system.free = append(system.free[:index], system.free[index+1:]...)
+		} else {
+			system.free[index] = block{address: free.address + size, size: free.size - size}
+		}
+
+		system.allocated[free.address] = size
+		return int16(free.address), nil
+	}
+
+	return 0, osError(6, "heap overflow, no block of %d words is free", size)
+}
+
+func (system *OS) deAlloc(address int) error {
+	size, ok := system.allocated[address]
+
+	if !ok {
+		return fmt.Errorf("%d is not the address of an allocated block", address)
+	}
+
+	delete(system.allocated, address)
+	system.free = append(system.free, block{address: address, size: size})
+
+	sort.Slice(system.free, func(i, j int) bool {
+		return system.free[i].address < system.free[j].address
+	})
+
+	// Merge the block with its free neighbours.
+	merged := system.free[:1]
+
+	for _, free := range system.free[1:] {
+		if last := &merged[len(merged)-1]; last.address+last.size == free.address {
+			last.size += free.size
+		} else {
+			merged = append(merged, free)
+		}
+	}
+
+	system.free = merged
+	return nil
+}
+
+func (system *OS) memoryInit(machine *vm.Machine, args []int16) (int16, error) {
+	system.reset()
+	return 0, nil
+}
+
+// ramAddress checks that an address is within the RAM.
+func ramAddress(arg int16) (int, error) {
+	address := int(uint16(arg))
+
+	if address >= vm.RAM_SIZE {
+		return 0, fmt.Errorf("address %d is outside of the RAM", address)
+	}
+
+	return address, nil
+}
+
+func memoryPeek(machine *vm.Machine, args []int16) (int16, error) {
+	address, err := ramAddress(args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	return machine.RAM[address], nil
+}
+
+func memoryPoke(machine *vm.Machine, args []int16) (int16, error) {
+	address, err := ramAddress(args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	machine.RAM[address] = args[1]
+	return 0, nil
+}
+
+func (system *OS) memoryAlloc(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] <= 0 {
+		return 0, osError(5, "allocated memory size must be positive, got %d", args[0])
+	}
+	return system.alloc(int(args[0]))
+}
+
+// memoryDeAlloc also disposes arrays and strings, whose first argument is
+// the object.
+func (system *OS) memoryDeAlloc(machine *vm.Machine, args []int16) (int16, error) {
+	return 0, system.deAlloc(int(args[0]))
+}
+
+func (system *OS) arrayNew(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] <= 0 {
+		return 0, osError(2, "array size must be positive, got %d", args[0])
+	}
+	return system.alloc(int(args[0]))
+}
+
+/* String */
+
+// stringAddress checks that a string stays within the RAM.
+func stringAddress(this int16) (int, error) {
+	address := int(uint16(this))
+
+	if address+2 > vm.RAM_SIZE {
+		return 0, fmt.Errorf("%d is not the address of a string", address)
+	}
+
+	return address, nil
+}
+
+func (system *OS) newString(machine *vm.Machine, value string) (int16, error) {
+	this, err := system.stringNew(machine, []int16{int16(len(value))})
+
+	if err != nil {
+		return 0, err
+	}
+
+	for index := 0; index < len(value); index++ {
+		machine.RAM[int(this)+2+index] = int16(value[index])
+	}
+
+	machine.RAM[int(this)+1] = int16(len(value))
+	return this, nil
+}
+
+// readString returns the characters of a string object.
+func readString(machine *vm.Machine, this int16) (string, error) {
+	address, err := stringAddress(this)
+
+	if err != nil {
+		return "", err
+	}
+
+	length := int(machine.RAM[address+1])
+
+	if length < 0 || address+2+length > vm.RAM_SIZE {
+		return "", fmt.Errorf("%d is not the address of a string", address)
+	}
+
+	chars := make([]byte, length)
+
+	for index := range chars {
+		chars[index] = byte(machine.RAM[address+2+index])
+	}
+
+	return string(chars), nil
+}
+
+func (system *OS) stringNew(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] < 0 {
+		return 0, osError(14, "maximum length must be non-negative, got %d", args[0])
+	}
+
+	this, err := system.alloc(int(args[0]) + 2)
+
+	if err != nil {
+		return 0, err
+	}
+
+	machine.RAM[this] = args[0]
+	machine.RAM[this+1] = 0
+	return this, nil
+}
+
+func stringLength(machine *vm.Machine, args []int16) (int16, error) {
+	address, err := stringAddress(args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	return machine.RAM[address+1], nil
+}
+
+func stringCharAt(machine *vm.Machine, args []int16) (int16, error) {
+	address, err := stringAddress(args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	if args[1] < 0 || args[1] >= machine.RAM[address+1] {
+		return 0, osError(15, "string index %d is out of range", args[1])
+	}
+
+	return machine.RAM[address+2+int(args[1])], nil
+}
+
+func stringSetCharAt(machine *vm.Machine, args []int16) (int16, error) {
+	address, err := stringAddress(args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	if args[1] < 0 || args[1] >= machine.RAM[address+1] {
+		return 0, osError(16, "string index %d is out of range", args[1])
+	}
+
+	machine.RAM[address+2+int(args[1])] = args[2]
+	return 0, nil
+}
+
+func stringAppendChar(machine *vm.Machine, args []int16) (int16, error) {
+	address, err := stringAddress(args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	length := machine.RAM[address+1]
+
+	if length >= machine.RAM[address] {
+		return 0, osError(17, "string is full")
+	}
+
+	machine.RAM[address+2+int(length)] = args[1]
+	machine.RAM[address+1]++
+	return args[0], nil
+}
+
+func stringEraseLastChar(machine *vm.Machine, args []int16) (int16, error) {
+	address, err := stringAddress(args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	if machine.RAM[address+1] <= 0 {
+		return 0, osError(18, "string is empty")
+	}
+
+	machine.RAM[address+1]--
+	return 0, nil
+}
+
+// intValue parses an optional '-' followed by digits, ignoring anything after them.
+func intValue(value string) int16 {
+	result, sign := int16(0), int16(1)
+
+	for index := 0; index < len(value); index++ {
+		char := value[index]
+
+		if index == 0 && char == '-' {
+			sign = -1
+		} else if char >= '0' && char <= '9' {
+			result = result*10 + int16(char-'0')
+		} else {
+			break
+		}
+	}
+
+	return sign * result
+}
+
+func stringIntValue(machine *vm.Machine, args []int16) (int16, error) {
+	value, err := readString(machine, args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	return intValue(value), nil
+}
+
+func stringSetInt(machine *vm.Machine, args []int16) (int16, error) {
+	address, err := stringAddress(args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	value := strconv.Itoa(int(args[1]))
+
+	if len(value) > int(machine.RAM[address]) {
+		return 0, osError(19, "string cannot hold %s", value)
+	}
+
+	for index := 0; index < len(value); index++ {
+		machine.RAM[address+2+index] = int16(value[index])
+	}
+
+	machine.RAM[address+1] = int16(len(value))
+	return 0, nil
+}
+
+func character(char int16) vm.Builtin {
+	return func(machine *vm.Machine, args []int16) (int16, error) {
+		return char, nil
+	}
+}
+
+/* Output */
+
+func (system *OS) write(char int16) error {
+	var err error
+
+	switch char {
+	case NEWLINE:
+		_, err = io.WriteString(system.output, "\n")
+	case BACKSPACE:
+		_, err = io.WriteString(system.output, "\b")
+	default:
+		_, err = system.output.Write([]byte{byte(char)})
+	}
+
+	return err
+}
+
+func outputMoveCursor(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] < 0 || args[0] >= TEXT_ROWS || args[1] < 0 || args[1] >= TEXT_COLUMNS {
+		return 0, osError(20, "cursor position (%d, %d) is out of range", args[0], args[1])
+	}
+	return 0, nil
+}
+
+func (system *OS) outputPrintChar(machine *vm.Machine, args []int16) (int16, error) {
+	return 0, system.write(args[0])
+}
+
+func (system *OS) outputPrintString(machine *vm.Machine, args []int16) (int16, error) {
+	value, err := readString(machine, args[0])
+
+	// Each character is printed as printChar would, String.newLine() as a newline.
+	for index := 0; err == nil && index < len(value); index++ {
+		err = system.write(int16(value[index]))
+	}
+
+	return 0, err
+}
+
+func (system *OS) outputPrintInt(machine *vm.Machine, args []int16) (int16, error) {
+	_, err := io.WriteString(system.output, strconv.Itoa(int(args[0])))
+	return 0, err
+}
+
+func (system *OS) outputPrintln(machine *vm.Machine, args []int16) (int16, error) {
+	return 0, system.write(NEWLINE)
+}
+
+func (system *OS) outputBackSpace(machine *vm.Machine, args []int16) (int16, error) {
+	return 0, system.write(BACKSPACE)
+}
+
+/* Keyboard */
+
+func keyboardKeyPressed(machine *vm.Machine, args []int16) (int16, error) {
+	return machine.RAM[screen.KBD], nil
+}
+
+// readChar reads the next character of the input, in the Jack character set.
+// Without an input reader, it waits for a key to be pressed and released on
+// the keyboard register instead, as the Jack OS does.
+func (system *OS) readChar(machine *vm.Machine) (int16, error) {
+	if system.input == nil {
+		key := machine.RAM[screen.KBD]
+
+		if system.key == 0 || key != 0 {
+			if system.key == 0 {
+				system.key = key
+			}
+			return 0, vm.ErrWait
+		}
+
+		char := system.key
+		system.key = 0
+		return char, nil
+	}
+
+	char, err := system.input.ReadByte()
+
+	if err == io.EOF {
+		return 0, fmt.Errorf("the keyboard input has ended")
+	} else if err != nil {
+		return 0, err
+	}
+
+	if char == '\n' {
+		return NEWLINE, nil
+	}
+
+	return int16(char), nil
+}
+
+// readLine displays the message and reads a line, without its 'newline'. The
+// line is kept between calls, so that reading it can wait for the keyboard.
+func (system *OS) readLine(machine *vm.Machine, message int16) (string, error) {
+	if !system.reading {
+		if _, err := system.outputPrintString(machine, []int16{message}); err != nil {
+			return "", err
+		}
+
+		system.line = []byte{}
+		system.reading = true
+	}
+
+	for {
+		char, err := system.readChar(machine)
+
+		if err != nil {
+			return "", err
+		}
+
+		if system.Echo {
+			if err := system.write(char); err != nil {
+				return "", err
+			}
+		}
+
+		switch char {
+		case NEWLINE:
+			system.reading = false
+			return string(system.line), nil
+		case BACKSPACE:
+			if len(system.line) > 0 {
+				system.line = system.line[:len(system.line)-1]
+			}
+		default:
+			if char != '\r' {
+				system.line = append(system.line, byte(char))
+			}
+		}
+	}
+}
+
+func (system *OS) keyboardReadChar(machine *vm.Machine, args []int16) (int16, error) {
+	char, err := system.readChar(machine)
+
+	if err == nil && system.Echo {
+		err = system.write(char)
+	}
+
+	return char, err
+}
+
+func (system *OS) keyboardReadLine(machine *vm.Machine, args []int16) (int16, error) {
+	line, err := system.readLine(machine, args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	return system.newString(machine, line)
+}
+
+func (system *OS) keyboardReadInt(machine *vm.Machine, args []int16) (int16, error) {
+	line, err := system.readLine(machine, args[0])
+
+	if err != nil {
+		return 0, err
+	}
+
+	return intValue(line), nil
+}
+
+/* Screen */
+
+func (system *OS) screenInit(machine *vm.Machine, args []int16) (int16, error) {
+	system.color = true
+	return 0, nil
+}
+
+func screenClearScreen(machine *vm.Machine, args []int16) (int16, error) {
+	for address := screen.BASE; address < screen.KBD; address++ {
+		machine.RAM[address] = 0
+	}
+	return 0, nil
+}
+
+func (system *OS) screenSetColor(machine *vm.Machine, args []int16) (int16, error) {
+	system.color = args[0] != 0
+	return 0, nil
+}
+
+func onScreen(x, y int) bool {
+	return x >= 0 && x < screen.WIDTH && y >= 0 && y < screen.HEIGHT
+}
+
+// drawPixel draws a pixel in the current color, ignoring pixels off the screen.
+func (system *OS) drawPixel(machine *vm.Machine, x, y int) {
+	if !onScreen(x, y) {
+		return
+	}
+
+	address := screen.BASE + y*screen.ROW_WORDS + x/16
+	mask := int16(uint16(1) << (x % 16))
+
+	if system.color {
+		machine.RAM[address] |= mask
+	} else {
+		machine.RAM[address] &^= mask
+	}
+}
+
+func (system *OS) screenDrawPixel(machine *vm.Machine, args []int16) (int16, error) {
+	x, y := int(args[0]), int(args[1])
+
+	if !onScreen(x, y) {
+		return 0, osError(7, "pixel (%d, %d) is off the screen", x, y)
+	}
+
+	system.drawPixel(machine, x, y)
+	return 0, nil
+}
+
+func (system *OS) screenDrawLine(machine *vm.Machine, args []int16) (int16, error) {
+	x1, y1, x2, y2 := int(args[0]), int(args[1]), int(args[2]), int(args[3])
+
+	if !onScreen(x1, y1) || !onScreen(x2, y2) {
+		return 0, osError(8, "line from (%d, %d) to (%d, %d) is off the screen", x1, y1, x2, y2)
+	}
+
+	// Bresenham's algorithm, in all eight octants.
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := sign(x2-x1), sign(y2-y1)
+	diff := dx + dy
+
+	for {
+		system.drawPixel(machine, x1, y1)
+
+		if x1 == x2 && y1 == y2 {
+			return 0, nil
+		}
+
+		// A diagonal step moves along both axes.
+		double := 2 * diff
+
+		if double >= dy {
+			diff += dy
+			x1 += sx
+		}
+
+		if double <= dx {
+			diff += dx
+			y1 += sy
+		}
+	}
+}
+
+func (system *OS) fill(machine *vm.Machine, x1, y1, x2, y2 int) {
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			system.drawPixel(machine, x, y)
+		}
+	}
+}
+
+func (system *OS) screenDrawRectangle(machine *vm.Machine, args []int16) (int16, error) {
+	x1, y1, x2, y2 := int(args[0]), int(args[1]), int(args[2]), int(args[3])
+
+	if !onScreen(x1, y1) || !onScreen(x2, y2) || x1 > x2 || y1 > y2 {
+		return 0, osError(9, "rectangle from (%d, %d) to (%d, %d) is not valid", x1, y1, x2, y2)
+	}
+
+	system.fill(machine, x1, y1, x2, y2)
+	return 0, nil
+}
+
+func (system *OS) screenDrawCircle(machine *vm.Machine, args []int16) (int16, error) {
+	x, y, r := int(args[0]), int(args[1]), int(args[2])
+
+	if !onScreen(x, y) {
+		return 0, osError(12, "circle center (%d, %d) is off the screen", x, y)
+	}
+
+	if r < 0 || r > 181 {
+		return 0, osError(13, "circle radius %d is out of range", r)
+	}
+
+	for dy := -r; dy <= r; dy++ {
+		// The half width of the row, the integer part of sqrt(r² - dy²).
+		half := 0
+
+		for (half+1)*(half+1) <= r*r-dy*dy {
+			half++
+		}
+
+		system.fill(machine, x-half, y+dy, x+half, y+dy)
+	}
+
+	return 0, nil
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func sign(value int) int {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	}
+	return 0
+}
+
+/* Sys */
+
+func sysHalt(machine *vm.Machine, args []int16) (int16, error) {
+	machine.Halt()
+	return 0, nil
+}
+
+func (system *OS) sysError(machine *vm.Machine, args []int16) (int16, error) {
+	if _, err := fmt.Fprintf(system.output, "ERR%d", args[0]); err != nil {
+		return 0, err
+	}
+	return 0, osError(int(args[0]), "the program called Sys.error")
+}
+
+// sysWait returns right away, as the machine does not run in real time.
+func sysWait(machine *vm.Machine, args []int16) (int16, error) {
+	if args[0] < 0 {
+		return 0, osError(1, "duration must be non-negative, got %d", args[0])
+	}
+	return 0, nil
+}
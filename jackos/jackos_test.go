@@ -0,0 +1,241 @@
+package jackos_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/codegen"
+	. "github.com/MlkMahmud/jack-compiler/jackos"
+	"github.com/MlkMahmud/jack-compiler/program"
+	"github.com/MlkMahmud/jack-compiler/screen"
+	"github.com/MlkMahmud/jack-compiler/vm"
+)
+
+// run compiles a Main class and runs it with the native OS.
+func run(t *testing.T, code string, input string) (*vm.Machine, string, error) {
+	t.Helper()
+	prog := program.New()
+
+	if err := prog.AddSource("Main.jack", code); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prog.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	vmCode, err := codegen.NewCodeGenerator().Generate(prog.Classes[0])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	machine := vm.NewMachine()
+	New(&output, strings.NewReader(input)).Register(machine)
+
+	if err := machine.Load([]vm.File{{Name: "Main.vm", Code: vmCode}}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = machine.Run(1000000)
+	return machine, output.String(), err
+}
+
+func TestOutput(t *testing.T) {
+	_, output, err := run(t, `class Main {
+  function void main() {
+    var String s, t;
+    var Array a;
+    let s = String.new(6);
+    do s.setInt(-1234);
+    do s.appendChar(53);
+    do Output.printString(s);
+    do Output.printChar(String.newLine());
+    do Output.printInt(s.intValue() / 3);
+    do Output.println();
+    do Output.printString("len=");
+    do Output.printInt(s.length());
+    do Output.printChar(String.doubleQuote());
+    do s.eraseLastChar();
+    do s.setCharAt(0, 43);
+    do Output.printString(s);
+    do Output.printChar(s.charAt(1));
+    do Output.println();
+    let a = Array.new(3);
+    let a[2] = Math.sqrt(1000) + Math.max(3, Math.abs(-7)) + Math.min(2, -2);
+    do Output.printInt(a[2]);
+    let t = String.new(2);
+    do t.appendChar(String.newLine());
+    do t.appendChar(33);
+    do Output.printString(t);
+    do a.dispose();
+    do s.dispose();
+    do Sys.wait(100);
+    return;
+  }
+}`, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "-12345\n-4115\nlen=6\"+12341\n36\n!"; output != expected {
+		t.Errorf("Expected the program to print:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func TestKeyboard(t *testing.T) {
+	_, output, err := run(t, `class Main {
+  function void main() {
+    var String name;
+    var int age;
+    let name = Keyboard.readLine("Name? ");
+    let age = Keyboard.readInt("Age? ");
+    do Output.printString(name);
+    do Output.printChar(32);
+    do Output.printInt(age + 1);
+    do Output.printChar(Keyboard.readChar());
+    do Output.printInt(Keyboard.keyPressed());
+    return;
+  }
+}`, "Ada\n36 years\n!")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "Name? Ada\nAge? 36 years\nAda 37!!0"; output != expected {
+		t.Errorf("Expected the program to print:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	machine, _, err := run(t, `class Main {
+  function int main() {
+    var Array a, b, c;
+    let a = Memory.alloc(10);
+    let b = Memory.alloc(5);
+    do Memory.deAlloc(a);
+    let c = Memory.alloc(4);
+    do Memory.poke(c, 99);
+    do Memory.poke(8000, Memory.peek(c) + 1);
+    return b - c;
+  }
+}`, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// c reuses the block a was freed from.
+	actual, err := machine.Top()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := int16(10); expected != actual {
+		t.Errorf("Expected b to follow c by %d words, got %d", expected, actual)
+	}
+
+	if expected, actual := int16(100), machine.RAM[8000]; expected != actual {
+		t.Errorf("Expected RAM[8000] to be %d, got %d", expected, actual)
+	}
+}
+
+func TestScreen(t *testing.T) {
+	machine, _, err := run(t, `class Main {
+  function void main() {
+    do Screen.drawRectangle(16, 0, 47, 1);
+    do Screen.setColor(false);
+    do Screen.drawPixel(17, 1);
+    do Screen.setColor(true);
+    do Screen.drawLine(0, 10, 3, 13);
+    do Screen.drawLine(0, 20, 9, 21);
+    do Screen.drawCircle(256, 128, 2);
+    return;
+  }
+}`, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pixel := func(x, y int) bool {
+		return machine.RAM[screen.BASE+y*screen.ROW_WORDS+x/16]&int16(uint16(1)<<(x%16)) != 0
+	}
+
+	if machine.RAM[screen.BASE+1] != -1 || machine.RAM[screen.BASE+2] != -1 || machine.RAM[screen.BASE+3] != 0 {
+		t.Errorf("Expected the first row of the rectangle to fill words 1 and 2")
+	}
+
+	if pixel(17, 1) || !pixel(16, 1) {
+		t.Errorf("Expected (17, 1) to be erased")
+	}
+
+	for i := 0; i < 4; i++ {
+		if !pixel(i, 10+i) {
+			t.Errorf("Expected the line to go through (%d, %d)", i, 10+i)
+		}
+	}
+
+	// A shallow line steps down once, halfway.
+	if row20, row21 := machine.RAM[screen.BASE+20*screen.ROW_WORDS], machine.RAM[screen.BASE+21*screen.ROW_WORDS]; row20 != 0x1f || row21 != 0x3e0 {
+		t.Errorf("Expected the shallow line to cover x 0-4 of row 20 and 5-9 of row 21, got %016b and %016b", uint16(row20), uint16(row21))
+	}
+
+	if !pixel(256, 126) || !pixel(258, 128) || pixel(258, 126) {
+		t.Errorf("Expected a circle of radius 2 around (256, 128)")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"do Output.printInt(1 / 0);", "ERR3"},
+		{"do Output.printInt(Math.sqrt(-1));", "ERR4"},
+		{"do Array.new(0);", "ERR2"},
+		{"do Memory.alloc(20000);", "ERR6"},
+		{"let s = \"ab\";\n    do s.charAt(2);", "ERR15"},
+		{"let s = \"ab\";\n    do s.appendChar(99);", "ERR17"},
+		{"do Screen.drawPixel(512, 0);", "ERR7"},
+		{"do Screen.drawRectangle(10, 0, 5, 5);", "ERR9"},
+		{"do Output.moveCursor(23, 0);", "ERR20"},
+		{"do Sys.error(42);", "ERR42"},
+		{"do Memory.deAlloc(3000);", "not the address of an allocated block"},
+		{"do Memory.peek(-1);", "address 65535 is outside of the RAM"},
+		{"do Memory.poke(32767 + 1, 0);", "address 32768 is outside of the RAM"},
+		{"do Keyboard.readChar();", "keyboard input has ended"},
+	}
+
+	for _, test := range tests {
+		_, _, err := run(t, "class Main {\n  function void main() {\n    var String s;\n    "+test.body+"\n    return;\n  }\n}", "")
+
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected '%s' to fail with %s, got %v", test.body, test.expected, err)
+		}
+	}
+}
+
+func TestHalt(t *testing.T) {
+	machine, output, err := run(t, `class Main {
+  function void main() {
+    do Output.printString("bye");
+    do Sys.halt();
+    while (true) {}
+    return;
+  }
+}`, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !machine.Halted() || machine.Returned() || output != "bye" {
+		t.Errorf("Expected the program to print 'bye' and halt, got '%s'", output)
+	}
+}
@@ -0,0 +1,199 @@
+package keyscript
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/screen"
+	"github.com/MlkMahmud/jack-compiler/vm"
+)
+
+/*
+KEY SCRIPTS
+
+A key script drives the keyboard register of a machine. Each line is an
+event: the number of VM commands executed before it happens, then either a
+key to hold down or text to type.
+
+	1000 132         holds down the key of code 132
+	1000 right       holds down a named key
+	5000 0           releases the key
+	6000 "42\n"      types "42" and 'newline'
+
+Typing holds every character down for HOLD_STEPS commands, then releases it
+for as long, which is enough for Keyboard.readChar to notice both. Text is
+quoted like a Go string, so '\n' is 'newline' and '\b' is 'backspace'.
+
+The named keys are the keys of the Jack character set that are not
+printable: newline, backspace, left, up, right, down, home, end, pageup,
+pagedown, insert, delete, esc and f1 to f12. 'release' is the same as 0.
+
+Events must be in order and cannot happen while text is being typed. '#'
+starts a comment.
+*/
+
+const HOLD_STEPS = 1000
+
+var namedKeys = map[string]int16{
+	"release": 0, "newline": 128, "backspace": 129, "left": 130, "up": 131,
+	"right": 132, "down": 133, "home": 134, "end": 135, "pageup": 136,
+	"pagedown": 137, "insert": 138, "delete": 139, "esc": 140,
+}
+
+func init() {
+	for index := 1; index <= 12; index++ {
+		namedKeys[fmt.Sprintf("f%d", index)] = int16(140 + index)
+	}
+}
+
+type ScriptError struct {
+	Filename string
+	Line     int
+	Message  string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf(
+		"(%s):[%d]: Script error: %s",
+		e.Filename,
+		e.Line,
+		e.Message,
+	)
+}
+
+// Event sets the keyboard register to Key once Step commands have run.
+type Event struct {
+	Step int
+	Key  int16
+}
+
+type Script struct {
+	Events []Event
+	// next is the index of the first event that has not happened yet.
+	next int
+}
+
+// key returns the Jack character of a typed character.
+func key(char rune) (int16, bool) {
+	switch {
+	case char == '\n':
+		return 128, true
+	case char == '\b':
+		return 129, true
+	case char >= 32 && char <= 126:
+		return int16(char), true
+	}
+	return 0, false
+}
+
+// Parse parses a key script. filename is only used to locate errors.
+func Parse(filename, source string) (*Script, error) {
+	script := &Script{}
+	// end is the step the previous event is over at.
+	end := 0
+
+	for index, line := range strings.Split(source, "\n") {
+		newError := func(format string, args ...any) error {
+			return &ScriptError{Filename: filename, Line: index + 1, Message: fmt.Sprintf(format, args...)}
+		}
+
+		line = strings.TrimSpace(line)
+
+		// Text can contain '#', it is only a comment outside of it.
+		if !strings.Contains(line, "\"") || strings.Index(line, "#") < strings.Index(line, "\"") {
+			if comment := strings.Index(line, "#"); comment >= 0 {
+				line = strings.TrimSpace(line[:comment])
+			}
+		}
+
+		if line == "" {
+			continue
+		}
+
+		separator := strings.IndexAny(line, " \t")
+
+		if separator < 0 {
+			return nil, newError("expected a step and an event, got '%s'", line)
+		}
+
+		step, err := strconv.Atoi(line[:separator])
+
+		if err != nil || step < 0 {
+			return nil, newError("'%s' is not a valid step", line[:separator])
+		}
+
+		if step < end {
+			return nil, newError("step %d happens before the previous event is over, at step %d", step, end)
+		}
+
+		event := strings.TrimSpace(line[separator:])
+
+		if strings.HasPrefix(event, "\"") {
+			quoted, err := strconv.QuotedPrefix(event)
+
+			if rest := strings.TrimSpace(event[len(quoted):]); err != nil || (rest != "" && !strings.HasPrefix(rest, "#")) {
+				return nil, newError("%s is not a valid string", event)
+			}
+
+			text, _ := strconv.Unquote(quoted)
+
+			for _, char := range text {
+				code, ok := key(char)
+
+				if !ok {
+					return nil, newError("%q cannot be typed", char)
+				}
+
+				script.Events = append(script.Events, Event{Step: step, Key: code}, Event{Step: step + HOLD_STEPS, Key: 0})
+				step += 2 * HOLD_STEPS
+			}
+
+			end = step
+			continue
+		}
+
+		code, ok := namedKeys[strings.ToLower(event)]
+
+		if !ok {
+			value, err := strconv.Atoi(event)
+
+			if err != nil || value < 0 || value > 32767 {
+				return nil, newError("'%s' is not a valid key", event)
+			}
+
+			code = int16(value)
+		}
+
+		script.Events = append(script.Events, Event{Step: step, Key: code})
+		end = step
+	}
+
+	return script, nil
+}
+
+// Load reads and parses a key script file.
+func Load(path string) (*Script, error) {
+	source, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(path, string(source))
+}
+
+// Apply sets the keyboard register of a machine to the key of the last event
+// that has happened. It is meant to be called after every step.
+func (script *Script) Apply(machine *vm.Machine) {
+	for script.next < len(script.Events) && script.Events[script.next].Step <= machine.Steps {
+		machine.RAM[screen.KBD] = script.Events[script.next].Key
+		script.next++
+	}
+}
+
+// Done returns true once every event has happened.
+func (script *Script) Done() bool {
+	return script.next >= len(script.Events)
+}
@@ -0,0 +1,166 @@
+package keyscript_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/codegen"
+	"github.com/MlkMahmud/jack-compiler/jackos"
+	. "github.com/MlkMahmud/jack-compiler/keyscript"
+	"github.com/MlkMahmud/jack-compiler/program"
+	"github.com/MlkMahmud/jack-compiler/screen"
+	"github.com/MlkMahmud/jack-compiler/vm"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func TestParse(t *testing.T) {
+	script, err := Parse("keys.txt", `# Moves right, then quits.
+10 right
+20	0 # released
+30 "a#\n"
+7000 F12
+7001 65
+`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Event{
+		{10, 132}, {20, 0},
+		{30, 97}, {30 + HOLD_STEPS, 0}, {30 + 2*HOLD_STEPS, 35}, {30 + 3*HOLD_STEPS, 0}, {30 + 4*HOLD_STEPS, 128}, {30 + 5*HOLD_STEPS, 0},
+		{7000, 152}, {7001, 65},
+	}
+
+	if fmt.Sprint(script.Events) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, script.Events)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		line   int
+	}{
+		{"10", 1},
+		{"x right", 1},
+		{"\n-1 right", 2},
+		{"10 shift", 1},
+		{"10 40000", 1},
+		{"10 \"abc", 1},
+		{"10 \"abc\" d", 1},
+		{"10 \"\\t\"", 1},
+		{"20 left\n10 right", 2},
+		{"10 \"ab\"\n2000 right", 2},
+	}
+
+	for _, test := range tests {
+		_, err := Parse("keys.txt", test.source)
+		var scriptError *ScriptError
+
+		if !errors.As(err, &scriptError) {
+			t.Errorf("Expected a script error for %q, got %v", test.source, err)
+		} else if scriptError.Line != test.line {
+			t.Errorf("Expected the error for %q to be on line %d, got %d", test.source, test.line, scriptError.Line)
+		}
+	}
+}
+
+// run runs a program on the native OS with its keyboard driven by a script.
+// main is the source of a Main class added to the files, if any.
+func run(t *testing.T, paths []string, main string, source string, maxSteps int) (*vm.Machine, string) {
+	t.Helper()
+	script, err := Parse("keys.txt", source)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prog := program.New()
+
+	for _, path := range paths {
+		if err := prog.AddFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if main != "" {
+		if err := prog.AddSource("Main.jack", main); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := prog.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []vm.File{}
+
+	for index, class := range prog.Classes {
+		vmCode, err := codegen.NewCodeGenerator().Generate(class)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files = append(files, vm.File{Name: prog.Paths[index], Code: vmCode})
+	}
+
+	var output bytes.Buffer
+	machine := vm.NewMachine()
+	jackos.New(&output, nil).Register(machine)
+
+	if err := machine.Load(files); err != nil {
+		t.Fatal(err)
+	}
+
+	script.Apply(machine)
+	err = machine.RunEvery(maxSteps, 1, func(machine *vm.Machine) error {
+		script.Apply(machine)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return machine, output.String()
+}
+
+func TestReadInt(t *testing.T) {
+	// Array.jack is the Main class of the Average program.
+	_, output := run(t, []string{path.Join(TEST_DATA_PATH, "Array.jack")}, "", "0 \"2\\n7\\n\"\n8000 \"9\\b8\\n\"", 100000)
+	expected := "HOW MANY NUMBERS? 2\nENTER THE NEXT NUMBER: 7\nENTER THE NEXT NUMBER: 9\b8\nTHE AVERAGE IS: 7\n"
+
+	if output != expected {
+		t.Errorf("Expected the program to print:\n%q\ngot:\n%q", expected, output)
+	}
+}
+
+func TestKeyPressed(t *testing.T) {
+	paths := []string{path.Join(TEST_DATA_PATH, "Square.jack"), path.Join(TEST_DATA_PATH, "SquareGame.jack")}
+
+	// SquareGame needs a Main class, which is not part of the test data.
+	main := "class Main {\n  function void main() {\n    var SquareGame game;\n    let game = SquareGame.new();\n    do game.run();\n    return;\n  }\n}\n"
+
+	// Holding the right arrow moves the square, 'Q' quits the game.
+	machine, _ := run(t, paths, main, "1000 right\n11000 release\n12000 \"Q\"", 100000)
+
+	if !machine.Halted() {
+		t.Fatalf("Expected the game to quit")
+	}
+
+	moved := false
+
+	for _, word := range machine.Screen()[2:32] {
+		moved = moved || word != 0
+	}
+
+	if machine.RAM[screen.BASE] == -1 || !moved {
+		t.Errorf("Expected the square to have moved right")
+	}
+}
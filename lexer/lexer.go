@@ -4,55 +4,111 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"regexp"
-	"runtime/debug"
+	"runtime"
 	"strings"
 
+	"github.com/MlkMahmud/jack-compiler/diag"
 	"github.com/MlkMahmud/jack-compiler/types"
 )
 
+type LexerErrorType int
+
+const (
+	UNTERMINATED_COMMENT LexerErrorType = iota
+	UNTERMINATED_STRING
+)
+
+func (errorType LexerErrorType) String() string {
+	return []string{
+		"UNTERMINATED_COMMENT", "UNTERMINATED_STRING",
+	}[errorType]
+}
+
 type LexerError struct {
-	message string
+	diag.Diagnostic
+	Kind LexerErrorType
 }
 
-func (e LexerError) Error() string {
-	return e.message
+func (e *LexerError) Error() string {
+	return fmt.Sprintf(
+		"<%s:%d:%d>\tError: %s",
+		e.Filename,
+		e.Line,
+		e.Column,
+		e.Message,
+	)
 }
 
 type Lexer struct {
-	colNum  int
-	lineNum int
-	source  *os.File
+	colNum   int
+	comments bool
+	filename string
+	lineNum  int
+	offset   int
+	source   io.Reader
+	// Position of the first character of the token being scanned.
+	tokenColNum  int
+	tokenLineNum int
+	tokenOffset  int
 }
 
-func NewLexer() *Lexer {
-	return new(Lexer)
+// Option configures optional lexer behaviour.
+type Option func(*Lexer)
+
+// WithComments makes the lexer emit a COMMENT token for every comment,
+// instead of discarding them.
+func WithComments() Option {
+	return func(lexer *Lexer) {
+		lexer.comments = true
+	}
 }
 
-func (lexer *Lexer) emitError(col, line int, message string) {
-	panic(&LexerError{message: fmt.Sprintf(
-		"<%s:%d:%d>\tError: %s",
-		lexer.source.Name(),
-		line,
-		col,
-		message,
-	)})
+func NewLexer(options ...Option) *Lexer {
+	lexer := new(Lexer)
+
+	for _, option := range options {
+		option(lexer)
+	}
+
+	return lexer
 }
 
-func (lexer *Lexer) appendToken(tokens *[]types.Token, entry types.Token) {
-	if len(entry.Lexeme) > 1 {
-		// Set the current token's colNum to the position of its first character.
-		entry.ColNum = lexer.colNum - len(entry.Lexeme)
-	} else {
-		entry.ColNum = lexer.colNum
+func (lexer *Lexer) emitError(errorType LexerErrorType, col, line int) {
+	var message string
+
+	switch errorType {
+	case UNTERMINATED_COMMENT:
+		message = "Unterminated multiline comment."
+	case UNTERMINATED_STRING:
+		message = "Unterminated string literal."
+	default:
+		panic(fmt.Sprintf("Error Type: [%d] is not a valid lexer error", errorType))
 	}
-	entry.Filename = lexer.source.Name()
-	entry.LineNum = lexer.lineNum
+
+	pos := types.Position{Filename: lexer.filename, Line: line, Column: col}
+
+	panic(&LexerError{
+		Diagnostic: diag.Diagnostic{Column: col, End: pos, Filename: lexer.filename, Line: line, Message: message},
+		Kind:       errorType,
+	})
+}
+
+func (lexer *Lexer) appendToken(tokens *[]types.Token, entry types.Token) {
+	entry.ColNum = lexer.tokenColNum
+	entry.Filename = lexer.filename
+	entry.LineNum = lexer.tokenLineNum
+	entry.Offset = lexer.tokenOffset
 	*tokens = append(*tokens, entry)
 }
 
+func (lexer *Lexer) appendComment(tokens *[]types.Token, comment string) {
+	if lexer.comments {
+		lexer.appendToken(tokens, types.Token{TokenType: types.COMMENT, Lexeme: comment})
+	}
+}
+
 func (lexer *Lexer) read() string {
 	buffer := make([]byte, 1)
 	bytes, err := lexer.source.Read(buffer)
@@ -64,6 +120,7 @@ func (lexer *Lexer) read() string {
 	}
 
 	char := string(buffer[:bytes])
+	lexer.offset += bytes
 
 	if char == "\n" {
 		lexer.colNum = 0
@@ -75,50 +132,67 @@ func (lexer *Lexer) read() string {
 	return char
 }
 
-func (lexer *Lexer) Tokenize(src string) []types.Token {
+func (lexer *Lexer) Tokenize(src string) (tokens []types.Token, err error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+
 	defer func() {
-		err := lexer.source.Close()
-		if err != nil {
-			log.Fatal(err)
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
+	}()
 
+	return lexer.TokenizeReader(src, file)
+}
+
+// TokenizeReader tokenizes source that is not read from a file on disk.
+// filename is only used to locate tokens and errors.
+func (lexer *Lexer) TokenizeReader(filename string, source io.Reader) (tokens []types.Token, err error) {
+	defer func() {
 		if r := recover(); r != nil {
-			var lexerError *LexerError
-			if errors.As(r.(error), &lexerError) {
-				fmt.Println(r)
-			} else {
-				debug.PrintStack()
+			// Lexer errors and I/O errors are returned, genuine bugs keep panicking.
+			var runtimeError runtime.Error
+			if e, ok := r.(error); ok && !errors.As(e, &runtimeError) {
+				tokens, err = nil, e
+				return
 			}
-			os.Exit(1)
+			panic(r)
 		}
 	}()
 
-	tokens := make([]types.Token, 0)
-	file, err := os.Open(src)
-	if err != nil {
-		log.Fatal(err)
-	}
-
+	tokens = make([]types.Token, 0)
 	lexer.colNum = 0
 	lexer.lineNum = 1
-	lexer.source = file
+	lexer.filename = filename
+	lexer.offset = 0
+	lexer.source = source
 	char := lexer.read()
 
 	for char != "\000" {
+		// 'char' has just been read, so the lexer is positioned on it.
+		lexer.tokenColNum = lexer.colNum
+		lexer.tokenLineNum = lexer.lineNum
+		lexer.tokenOffset = lexer.offset - 1
+
 		if char == "/" {
 			nextChar := lexer.read()
 			if nextChar == "/" {
 				// This is a single line comment
 				// Advance until we hit the next newline char or EOF
+				chars := []string{"//"}
 				newlineChar := lexer.read()
 
 				for {
 					if newlineChar == "\n" || newlineChar == "\000" {
 						break
 					}
+					chars = append(chars, newlineChar)
 					newlineChar = lexer.read()
 				}
 
+				lexer.appendComment(&tokens, strings.TrimRight(strings.Join(chars, ""), "\r"))
 				char = newlineChar
 			} else if nextChar == "*" {
 				// This is a multiline comment
@@ -128,14 +202,17 @@ func (lexer *Lexer) Tokenize(src string) []types.Token {
 				startLine := lexer.lineNum
 				asteriskChar := lexer.read()
 				forwardSlashChar := lexer.read()
+				chars := []string{"/*", asteriskChar, forwardSlashChar}
 
 				for fmt.Sprintf("%s%s", asteriskChar, forwardSlashChar) != "*/" {
 					if forwardSlashChar == "\000" {
-						lexer.emitError(startCol, startLine, "Unterminated multiline comment.")	
+						lexer.emitError(UNTERMINATED_COMMENT, startCol, startLine)
 					}
 					asteriskChar = forwardSlashChar
 					forwardSlashChar = lexer.read()
+					chars = append(chars, forwardSlashChar)
 				}
+				lexer.appendComment(&tokens, strings.Join(chars, ""))
 				char = lexer.read()
 			} else {
 				// This is a division symbol
@@ -160,7 +237,7 @@ func (lexer *Lexer) Tokenize(src string) []types.Token {
 
 			for {
 				if char == "\n" || char == "\000" {
-					lexer.emitError(startCol, startLine, "Unterminated string literal.")
+					lexer.emitError(UNTERMINATED_STRING, startCol, startLine)
 				}
 
 				if char == `"` {
@@ -212,5 +289,5 @@ func (lexer *Lexer) Tokenize(src string) []types.Token {
 			char = lexer.read()
 		}
 	}
-	return tokens
+	return tokens, nil
 }
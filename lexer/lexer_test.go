@@ -2,79 +2,95 @@ package lexer_test
 
 import (
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
-	. "github.com/MlkMahmud/jack-compiler/types"
 	. "github.com/MlkMahmud/jack-compiler/lexer"
 )
 
-const TEST_DATA_PATH = "../testdata"
-
-func writeSymbol(lexeme string) string {
-	switch lexeme {
-	case "<":
-		return "&lt;"
-	case ">":
-		return "&gt;"
-	case "&":
-		return "&amp;"
-	default:
-		return lexeme
+func TestLexer(t *testing.T) {
+	tests := map[string]struct {
+		source   string
+		options  []Option
+		expected []string
+	}{
+		"Statements": {
+			source: "let s = \"a b\";\n  let i = 42;",
+			expected: []string{
+				"keyword let 1:1", "identifier s 1:5", "symbol = 1:7", "stringConstant a b 1:9", "symbol ; 1:14",
+				"keyword let 2:3", "identifier i 2:7", "symbol = 2:9", "integerConstant 42 2:11", "symbol ; 2:13",
+			},
+		},
+		"Symbols": {
+			source: "if (~(a < b) & c) { return x[1]; }",
+			expected: []string{
+				"keyword if 1:1", "symbol ( 1:4", "symbol ~ 1:5", "symbol ( 1:6", "identifier a 1:7", "symbol < 1:9",
+				"identifier b 1:11", "symbol ) 1:12", "symbol & 1:14", "identifier c 1:16", "symbol ) 1:17", "symbol { 1:19",
+				"keyword return 1:21", "identifier x 1:28", "symbol [ 1:29", "integerConstant 1 1:30", "symbol ] 1:31",
+				"symbol ; 1:32", "symbol } 1:34",
+			},
+		},
+		"Comments": {
+			source:   "// line\n/* block\n */ /** doc */ x",
+			expected: []string{"identifier x 3:16"},
+		},
+		"KeptComments": {
+			source:   "// line\n/* block\n */ /** doc */ x",
+			options:  []Option{WithComments()},
+			expected: []string{"comment // line 1:1", "comment /* block\n */ 2:1", "comment /** doc */ 3:5", "identifier x 3:16"},
+		},
 	}
-}
 
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tokens, err := NewLexer(test.options...).TokenizeReader("Main.jack", strings.NewReader(test.source))
 
-func readFileContent(filename string) string {
-	file, _ := os.Open(filename)
-	bytes, err := io.ReadAll(file)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	fileContent := string(bytes)
-	return fileContent
-}
+			if err != nil {
+				t.Fatal(err)
+			}
 
-func writeTokensToXML(tokens []Token, dest string) {
-	file, err := os.Create(dest)
-	if err != nil {
-		log.Fatal(err)
-	}
+			actual := []string{}
+			for _, token := range tokens {
+				actual = append(actual, fmt.Sprintf("%s %s %d:%d", token.TokenType, token.Lexeme, token.LineNum, token.ColNum))
+			}
 
-	file.WriteString("<tokens>\n")
-	for _, token := range tokens {
-		file.WriteString(
-			fmt.Sprintf(
-				"  <%s> %s </%s>\n",
-				token.TokenType.String(),
-				writeSymbol(token.Lexeme),
-				token.TokenType.String(),
-			),
-		)
+			if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("Expected tokens %q, got %q", test.expected, actual)
+			}
+		})
 	}
-	file.WriteString("</tokens>")
 }
 
-func TestLexer(t *testing.T) {
-	files := []string{"Array", "Square", "SquareGame"}
+func TestLexerErrors(t *testing.T) {
+	tests := map[string]struct {
+		source string
+		kind   LexerErrorType
+		line   int
+		column int
+	}{
+		"UnterminatedString":  {"class Main {\n  let s = \"oops;\n}", UNTERMINATED_STRING, 2, 12},
+		"UnterminatedComment": {"class Main {\n /* oops\n}", UNTERMINATED_COMMENT, 2, 2},
+	}
 	lexer := NewLexer()
 
-	for _, file := range files {
-		t.Run(file, func(t *testing.T) {
-			filePath := path.Join(TEST_DATA_PATH, fmt.Sprintf("%s%s", file, ".jack"))
-			cmpFilePath := path.Join(TEST_DATA_PATH, "expected", fmt.Sprintf("%s%s", file, "T.xml"))
-			outFilePath := path.Join(TEST_DATA_PATH, fmt.Sprintf("%s%s", file, "T.xml"))
-			tokens := lexer.Tokenize(filePath)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			filePath := path.Join(t.TempDir(), "Main.jack")
+			if err := os.WriteFile(filePath, []byte(test.source), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := lexer.Tokenize(filePath)
+			lexerError, ok := err.(*LexerError)
 
-			writeTokensToXML(tokens, outFilePath)
+			if !ok {
+				t.Fatalf("Expected a *LexerError, got %v", err)
+			}
 
-			if readFileContent(cmpFilePath) != readFileContent(outFilePath) {
-				t.Errorf("Expected content of %s to match content of %s", outFilePath, cmpFilePath)
+			if lexerError.Kind != test.kind || lexerError.Line != test.line || lexerError.Column != test.column || lexerError.Filename != filePath {
+				t.Errorf("Expected %s at %d:%d, got %s at %d:%d", test.kind, test.line, test.column, lexerError.Kind, lexerError.Line, lexerError.Column)
 			}
 		})
 	}
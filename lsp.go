@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/MlkMahmud/jack-compiler/lsp"
+)
+
+func printLSPHelpMessage() {
+	log.SetFlags(0)
+	log.Fatalln(("usage:\n go run . lsp\t\t\t\tServes the Language Server Protocol over stdin and stdout\n go run . lsp --check=<mode>\t\tAlso reports the type errors of 'lenient' or 'strict' mode"))
+}
+
+// serveLanguageServer runs a language server for editors, which start it
+// and talk to it over stdin and stdout. Logs go to stderr.
+func serveLanguageServer(args []string) {
+	var check string
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	flags.StringVar(&check, "check", "", "Report the type errors of 'lenient' or 'strict' mode along with the other errors.")
+	flags.Parse(args)
+
+	server := lsp.NewServer(os.Stdin, os.Stdout)
+
+	if mode, ok := checkModes[check]; ok {
+		server.Mode = &mode
+	} else if check != "" {
+		printLSPHelpMessage()
+	}
+
+	if err := server.Serve(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,422 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/MlkMahmud/jack-compiler/checker"
+	"github.com/MlkMahmud/jack-compiler/completion"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+LANGUAGE SERVER
+
+The server speaks the Language Server Protocol over a pair of streams,
+usually stdin and stdout. Every message is a JSON-RPC 2.0 object preceded by
+a header:
+
+	Content-Length: <number of bytes of the content>\r\n
+	\r\n
+	<content>
+
+A Jack program is every class of a directory, so each directory is analyzed
+as a whole: the open documents are read from the editor and the other
+'.jack' files from disk. The server supports:
+
+	textDocument/didOpen, didChange, didClose   publishes the syntax, reference
+	                                            and call errors of every open
+	                                            document of the directory,
+	                                            and type errors with a mode
+	textDocument/definition                     where a class, subroutine or
+	                                            variable is declared
+	textDocument/references                     where it is used
+	textDocument/hover                          its kind, declared type and
+	                                            doc comment
+	textDocument/documentSymbol                 the variables and subroutines
+	                                            of the class of a document
+	textDocument/completion                     the names that can complete
+	                                            the name being typed
+
+Documents are synchronized in full on every change.
+*/
+
+type Server struct {
+	// Mode type checks the program when it is not nil.
+	Mode      *checker.Mode
+	documents map[string]string
+	input     *bufio.Reader
+	output    io.Writer
+	shutdown  bool
+	// workspaces caches the analysis of each directory until one of its documents changes.
+	workspaces map[string]*workspace
+}
+
+func NewServer(input io.Reader, output io.Writer) *Server {
+	return &Server{
+		documents:  map[string]string{},
+		input:      bufio.NewReader(input),
+		output:     output,
+		workspaces: map[string]*workspace{},
+	}
+}
+
+// Serve handles messages until the client sends 'exit' or closes the input.
+func (server *Server) Serve() error {
+	for {
+		content, err := ReadMessage(server.input)
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var request Message
+
+		if err := json.Unmarshal(content, &request); err != nil {
+			null := json.RawMessage("null")
+			server.respond(&null, nil, &ResponseError{Code: PARSE_ERROR, Message: err.Error()})
+			continue
+		}
+
+		if request.Method == "exit" {
+			return nil
+		}
+
+		result, err := server.handle(request.Method, request.Params)
+
+		// Notifications have no response, even when they fail.
+		if request.ID != nil {
+			if err := server.respond(request.ID, result, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (server *Server) respond(id *json.RawMessage, result any, err error) error {
+	response := Message{JSONRPC: "2.0", ID: id}
+
+	if err != nil {
+		responseError, ok := err.(*ResponseError)
+
+		if !ok {
+			responseError = &ResponseError{Code: INTERNAL_ERROR, Message: err.Error()}
+		}
+
+		response.Error = responseError
+		return WriteMessage(server.output, response)
+	}
+
+	content, err := json.Marshal(result)
+
+	if err != nil {
+		return err
+	}
+
+	response.Result = content
+	return WriteMessage(server.output, response)
+}
+
+func (server *Server) notify(method string, params any) error {
+	content, err := json.Marshal(params)
+
+	if err != nil {
+		return err
+	}
+
+	return WriteMessage(server.output, Message{JSONRPC: "2.0", Method: method, Params: content})
+}
+
+func decode[T any](params json.RawMessage) (T, error) {
+	var value T
+
+	if err := json.Unmarshal(params, &value); err != nil {
+		return value, &ResponseError{Code: INVALID_PARAMS, Message: err.Error()}
+	}
+
+	return value, nil
+}
+
+func (server *Server) handle(method string, params json.RawMessage) (any, error) {
+	if server.shutdown {
+		return nil, &ResponseError{Code: INVALID_REQUEST, Message: "the server is shutting down"}
+	}
+
+	switch method {
+	case "initialize":
+		var result InitializeResult
+		result.Capabilities = ServerCapabilities{
+			TextDocumentSync:       1,
+			DefinitionProvider:     true,
+			ReferencesProvider:     true,
+			HoverProvider:          true,
+			DocumentSymbolProvider: true,
+			CompletionProvider:     &CompletionOptions{TriggerCharacters: []string{"."}},
+		}
+		result.ServerInfo.Name = "jack-compiler"
+		return result, nil
+
+	case "initialized", "textDocument/didSave":
+		return nil, nil
+
+	case "shutdown":
+		server.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		params, err := decode[DidOpenTextDocumentParams](params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, server.update(params.TextDocument.URI, &params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		params, err := decode[DidChangeTextDocumentParams](params)
+
+		if err != nil || len(params.ContentChanges) == 0 {
+			return nil, err
+		}
+
+		return nil, server.update(params.TextDocument.URI, &params.ContentChanges[len(params.ContentChanges)-1].Text)
+
+	case "textDocument/didClose":
+		params, err := decode[DidCloseTextDocumentParams](params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, server.update(params.TextDocument.URI, nil)
+
+	case "textDocument/definition":
+		params, err := decode[TextDocumentPositionParams](params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return server.definition(params)
+
+	case "textDocument/references":
+		params, err := decode[ReferenceParams](params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return server.references(params)
+
+	case "textDocument/hover":
+		params, err := decode[TextDocumentPositionParams](params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return server.hover(params)
+
+	case "textDocument/documentSymbol":
+		params, err := decode[DocumentSymbolParams](params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return server.documentSymbol(params)
+
+	case "textDocument/completion":
+		params, err := decode[TextDocumentPositionParams](params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return server.completion(params)
+	}
+
+	return nil, &ResponseError{Code: METHOD_NOT_FOUND, Message: fmt.Sprintf("method '%s' is not supported", method)}
+}
+
+func (server *Server) workspace(dir string) *workspace {
+	space, ok := server.workspaces[dir]
+
+	if !ok {
+		space = analyze(dir, server.documents, server.Mode)
+		server.workspaces[dir] = space
+	}
+
+	return space
+}
+
+// update sets the text of a document, or closes it when text is nil, and
+// publishes the diagnostics of every open document of its directory.
+func (server *Server) update(uri string, text *string) error {
+	path, err := uriToPath(uri)
+
+	if err != nil {
+		return &ResponseError{Code: INVALID_PARAMS, Message: err.Error()}
+	}
+
+	dir := filepath.Dir(path)
+	delete(server.workspaces, dir)
+
+	if text == nil {
+		delete(server.documents, path)
+
+		// Clear the diagnostics of the closed document.
+		if err := server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}}); err != nil {
+			return err
+		}
+	} else {
+		server.documents[path] = *text
+	}
+
+	space := server.workspace(dir)
+	paths := []string{}
+
+	for path := range server.documents {
+		if filepath.Dir(path) == dir {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		diagnostics := []Diagnostic{}
+
+		if f, ok := space.files[path]; ok && f.diagnostics != nil {
+			diagnostics = f.diagnostics
+		}
+
+		if err := server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: pathToURI(path), Diagnostics: diagnostics}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the workspace of a document and its occurrence under the cursor.
+func (server *Server) lookup(params TextDocumentPositionParams) (*workspace, *file, *occurrence, error) {
+	path, err := uriToPath(params.TextDocument.URI)
+
+	if err != nil {
+		return nil, nil, nil, &ResponseError{Code: INVALID_PARAMS, Message: err.Error()}
+	}
+
+	space := server.workspace(filepath.Dir(path))
+	f, ok := space.files[path]
+
+	if !ok {
+		return space, nil, nil, nil
+	}
+
+	if occurrence, ok := f.at(params.Position); ok {
+		return space, f, &occurrence, nil
+	}
+
+	return space, f, nil, nil
+}
+
+func (server *Server) definition(params TextDocumentPositionParams) (*Location, error) {
+	space, _, occurrence, err := server.lookup(params)
+
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+
+	declaration, ok := space.declarations[occurrence.key]
+
+	if !ok || !declaration.span.StartPos.IsValid() {
+		return nil, nil
+	}
+
+	return &Location{URI: pathToURI(declaration.span.StartPos.Filename), Range: toRange(declaration.span)}, nil
+}
+
+func (server *Server) references(params ReferenceParams) ([]Location, error) {
+	space, _, occurrence, err := server.lookup(params.TextDocumentPositionParams)
+
+	if err != nil || occurrence == nil {
+		return []Location{}, err
+	}
+
+	return space.references(occurrence.key, params.Context.IncludeDeclaration), nil
+}
+
+func (server *Server) hover(params TextDocumentPositionParams) (*Hover, error) {
+	space, _, occurrence, err := server.lookup(params)
+
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+
+	declaration, ok := space.declarations[occurrence.key]
+
+	if !ok {
+		return nil, nil
+	}
+
+	contents := "```jack\n" + declaration.detail + "\n```"
+
+	if declaration.doc != "" {
+		contents += "\n\n" + declaration.doc
+	}
+
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: contents},
+		Range:    toRange(occurrence.span),
+	}, nil
+}
+
+func (server *Server) documentSymbol(params DocumentSymbolParams) ([]DocumentSymbol, error) {
+	path, err := uriToPath(params.TextDocument.URI)
+
+	if err != nil {
+		return nil, &ResponseError{Code: INVALID_PARAMS, Message: err.Error()}
+	}
+
+	if f, ok := server.workspace(filepath.Dir(path)).files[path]; ok {
+		return f.symbols(), nil
+	}
+
+	return []DocumentSymbol{}, nil
+}
+
+var completionKinds = map[completion.Kind]int{
+	completion.CLASS:                   CLASS_COMPLETION,
+	completion.KEYWORD:                 KEYWORD_COMPLETION,
+	completion.Kind(types.Argument):    VARIABLE_COMPLETION,
+	completion.Kind(types.Constructor): CONSTRUCTOR_COMPLETION,
+	completion.Kind(types.Field):       FIELD_COMPLETION,
+	completion.Kind(types.Function):    FUNCTION_COMPLETION,
+	completion.Kind(types.Method):      METHOD_COMPLETION,
+	completion.Kind(types.Static):      VARIABLE_COMPLETION,
+	completion.Kind(types.Var):         VARIABLE_COMPLETION,
+}
+
+func (server *Server) completion(params TextDocumentPositionParams) ([]CompletionItem, error) {
+	space, f, _, err := server.lookup(params)
+	items := []CompletionItem{}
+
+	if err != nil || f == nil {
+		return items, err
+	}
+
+	for _, item := range completion.Complete(f.class, f.tokens, space.classes, fromPosition(params.Position)) {
+		items = append(items, CompletionItem{Label: item.Label, Kind: completionKinds[item.Kind], Detail: item.Detail})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,306 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/lsp"
+)
+
+const COUNTER = `/** Counts. */
+class Counter {
+  field int count;
+
+  constructor Counter new(int start) {
+    let count = start;
+    return this;
+  }
+
+  method void increment() {
+    let count = count + 1;
+    return;
+  }
+
+  method int value() {
+    return count;
+  }
+}
+`
+
+const MAIN = `class Main {
+  function void main() {
+    var Counter c;
+    let c = Counter.new(3);
+    do c.increment();
+    do Output.printInt(c.value());
+    return;
+  }
+}
+`
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// session sends requests to a server and returns the responses by ID and
+// the last diagnostics published for each document.
+func session(t *testing.T, requests ...request) (map[int]Message, map[string][]Diagnostic) {
+	t.Helper()
+	var input, output bytes.Buffer
+
+	for _, request := range requests {
+		request.JSONRPC = "2.0"
+
+		if err := WriteMessage(&input, request); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewServer(&input, &output).Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := map[int]Message{}
+	diagnostics := map[string][]Diagnostic{}
+	reader := bufio.NewReader(&output)
+
+	for {
+		content, err := ReadMessage(reader)
+
+		if err != nil {
+			break
+		}
+
+		var message Message
+
+		if err := json.Unmarshal(content, &message); err != nil {
+			t.Fatal(err)
+		}
+
+		if message.Method == "textDocument/publishDiagnostics" {
+			var params PublishDiagnosticsParams
+			json.Unmarshal(message.Params, &params)
+			diagnostics[params.URI] = params.Diagnostics
+		} else if message.ID != nil {
+			var id int
+			json.Unmarshal(*message.ID, &id)
+			responses[id] = message
+		}
+	}
+
+	return responses, diagnostics
+}
+
+func open(uri, text string) request {
+	return request{Method: "textDocument/didOpen", Params: map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "jack", "version": 1, "text": text},
+	}}
+}
+
+func at(id int, method, uri string, line, character int) request {
+	return request{ID: id, Method: method, Params: map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+		"context":      map[string]any{"includeDeclaration": true},
+	}}
+}
+
+func result[T any](t *testing.T, message Message) T {
+	t.Helper()
+	var value T
+
+	if message.Error != nil {
+		t.Fatal(message.Error)
+	}
+
+	if err := json.Unmarshal(message.Result, &value); err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	counter := "file://" + filepath.ToSlash(filepath.Join(dir, "Counter.jack"))
+	main := "file://" + filepath.ToSlash(filepath.Join(dir, "Main.jack"))
+
+	responses, diagnostics := session(t,
+		request{ID: 1, Method: "initialize", Params: map[string]any{}},
+		request{Method: "initialized", Params: map[string]any{}},
+		open(counter, COUNTER),
+		open(main, MAIN),
+		// 'new' in 'Counter.new(3)'
+		at(2, "textDocument/definition", main, 3, 21),
+		// 'count' in 'field int count'
+		at(3, "textDocument/references", counter, 2, 12),
+		// 'Counter' in 'class Counter'
+		at(4, "textDocument/references", counter, 1, 7),
+		// 'c' in 'do c.increment()'
+		at(5, "textDocument/hover", main, 4, 7),
+		// 'printInt' of the OS
+		at(6, "textDocument/hover", main, 5, 14),
+		at(7, "textDocument/definition", main, 5, 14),
+		request{ID: 8, Method: "textDocument/documentSymbol", Params: map[string]any{"textDocument": map[string]any{"uri": counter}}},
+		request{ID: 9, Method: "textDocument/formatting", Params: map[string]any{}},
+		// 'Counter' in 'var Counter c'
+		at(11, "textDocument/hover", main, 2, 9),
+		request{ID: 10, Method: "shutdown"},
+		request{Method: "exit"},
+	)
+
+	if capabilities := result[InitializeResult](t, responses[1]).Capabilities; !capabilities.DefinitionProvider || capabilities.TextDocumentSync != 1 {
+		t.Errorf("Expected the server to provide definitions, got %+v", capabilities)
+	}
+
+	for uri, list := range diagnostics {
+		if len(list) != 0 {
+			t.Errorf("Expected no diagnostics for %s, got %+v", uri, list)
+		}
+	}
+
+	if location := result[Location](t, responses[2]); location.URI != counter || location.Range != (Range{Start: Position{4, 22}, End: Position{4, 25}}) {
+		t.Errorf("Expected 'new' to be declared on line 4 of Counter.jack, got %+v", location)
+	}
+
+	if locations := result[[]Location](t, responses[3]); len(locations) != 5 || locations[4].Range.Start != (Position{15, 11}) {
+		t.Errorf("Expected 5 references to 'count', got %+v", locations)
+	}
+
+	// The declaration, the type of 'new', the type of 'c' and 'Counter.new'.
+	if locations := result[[]Location](t, responses[4]); len(locations) != 4 || locations[2].URI != main || locations[2].Range.Start != (Position{2, 8}) {
+		t.Errorf("Expected 4 references to 'Counter', got %+v", locations)
+	}
+
+	if hover := result[Hover](t, responses[5]); !strings.Contains(hover.Contents.Value, "var Counter c") {
+		t.Errorf("Expected the hover of 'c' to show its declaration, got %q", hover.Contents.Value)
+	}
+
+	if hover := result[Hover](t, responses[6]); !strings.Contains(hover.Contents.Value, "function void Output.printInt(int") {
+		t.Errorf("Expected the hover of 'printInt' to show its signature, got %q", hover.Contents.Value)
+	}
+
+	if hover := result[Hover](t, responses[11]); !strings.HasSuffix(hover.Contents.Value, "```\n\nCounts.") {
+		t.Errorf("Expected the hover of 'Counter' to show its doc comment, got %q", hover.Contents.Value)
+	}
+
+	if string(responses[7].Result) != "null" {
+		t.Errorf("Expected the OS to have no definition, got %s", responses[7].Result)
+	}
+
+	symbols := result[[]DocumentSymbol](t, responses[8])
+
+	if len(symbols) != 1 || symbols[0].Kind != CLASS_SYMBOL || len(symbols[0].Children) != 4 {
+		t.Fatalf("Expected the outline of class Counter, got %+v", symbols)
+	}
+
+	for index, kind := range []int{FIELD_SYMBOL, CONSTRUCTOR_SYMBOL, METHOD_SYMBOL, METHOD_SYMBOL} {
+		if symbols[0].Children[index].Kind != kind {
+			t.Errorf("Expected symbol %d to be of kind %d, got %+v", index, kind, symbols[0].Children[index])
+		}
+	}
+
+	if responses[9].Error == nil || responses[9].Error.Code != METHOD_NOT_FOUND {
+		t.Errorf("Expected formatting not to be supported, got %+v", responses[9])
+	}
+
+	if responses[10].Error != nil {
+		t.Errorf("Expected the server to shut down, got %v", responses[10].Error)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	counter := "file://" + filepath.ToSlash(filepath.Join(dir, "Counter.jack"))
+	main := "file://" + filepath.ToSlash(filepath.Join(dir, "Main.jack"))
+
+	tests := []struct {
+		text    string
+		message string
+		start   Position
+		end     Position
+	}{
+		{strings.Replace(MAIN, "let c", "let d", 1), "Reference error: 'd' is not defined", Position{3, 8}, Position{3, 9}},
+		{strings.Replace(MAIN, "c.increment()", "c.increment(1)", 1), "Call error: 'Counter.increment' expects 0 argument(s), got 1", Position{4, 7}, Position{4, 8}},
+		{strings.Replace(MAIN, "let c =", "let c", 1), "Syntax error: unexpected token 'Counter'", Position{3, 10}, Position{3, 17}},
+		{strings.Replace(MAIN, "return;", `do Output.printString("oops);`, 1), "Unterminated string literal.", Position{6, 27}, Position{6, 27}},
+	}
+
+	for _, test := range tests {
+		_, diagnostics := session(t, open(counter, COUNTER), open(main, test.text))
+		list := diagnostics[main]
+
+		if len(list) != 1 || list[0].Message != test.message || list[0].Range.Start != test.start || list[0].Range.End != test.end {
+			t.Errorf("Expected %q from %+v to %+v, got %+v", test.message, test.start, test.end, list)
+		}
+	}
+
+	// Fixing a class clears the errors of the classes that call it.
+	responses, diagnostics := session(t,
+		open(counter, strings.Replace(COUNTER, "method void increment()", "method void increment(int by)", 1)),
+		open(main, MAIN),
+		request{Method: "textDocument/didChange", Params: map[string]any{
+			"textDocument":   map[string]any{"uri": counter, "version": 2},
+			"contentChanges": []map[string]any{{"text": COUNTER}},
+		}},
+	)
+
+	if len(responses) != 0 || len(diagnostics[main]) != 0 {
+		t.Errorf("Expected the call error of Main.jack to be cleared, got %+v", diagnostics[main])
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	dir := t.TempDir()
+	counter := "file://" + filepath.ToSlash(filepath.Join(dir, "Counter.jack"))
+	main := "file://" + filepath.ToSlash(filepath.Join(dir, "Main.jack"))
+
+	responses, _ := session(t,
+		open(counter, COUNTER),
+		open(main, "class Main {\n  function void main() {\n    var Counter c;\n    do c."),
+		at(1, "textDocument/completion", main, 3, 9),
+	)
+
+	items := result[[]CompletionItem](t, responses[1])
+
+	if len(items) != 2 || items[0].Label != "increment" || items[0].Kind != METHOD_COMPLETION || items[1].Detail != "method int Counter.value()" {
+		t.Errorf("Expected the methods of Counter, got %+v", items)
+	}
+}
+
+func TestMidFileSyntaxError(t *testing.T) {
+	dir := t.TempDir()
+	counter := "file://" + filepath.ToSlash(filepath.Join(dir, "Counter.jack"))
+
+	// 'increment' is being edited, its statement has no ';' yet.
+	responses, diagnostics := session(t,
+		open(counter, strings.Replace(COUNTER, "let count = count + 1;", "let count = count + 1", 1)),
+		// 'count' in 'let count = count + 1'
+		at(1, "textDocument/hover", counter, 10, 9),
+		request{ID: 2, Method: "textDocument/documentSymbol", Params: map[string]any{"textDocument": map[string]any{"uri": counter}}},
+		at(3, "textDocument/definition", counter, 10, 9),
+	)
+
+	if list := diagnostics[counter]; len(list) != 1 || list[0].Message != "Syntax error: expected ';'" || list[0].Range.Start != (Position{10, 25}) {
+		t.Errorf("Expected a missing ';' at the end of line 10, got %+v", list)
+	}
+
+	if hover := result[Hover](t, responses[1]); !strings.Contains(hover.Contents.Value, "field int count") {
+		t.Errorf("Expected the hover of 'count' to show its declaration, got %q", hover.Contents.Value)
+	}
+
+	if symbols := result[[]DocumentSymbol](t, responses[2]); len(symbols) != 1 || len(symbols[0].Children) != 4 {
+		t.Errorf("Expected the outline to keep the subroutine being edited, got %+v", symbols)
+	}
+
+	if location := result[Location](t, responses[3]); location.Range.Start != (Position{2, 12}) {
+		t.Errorf("Expected 'count' to be declared on line 2, got %+v", location)
+	}
+}
@@ -0,0 +1,278 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+// JSON-RPC error codes.
+const (
+	PARSE_ERROR      = -32700
+	INVALID_REQUEST  = -32600
+	METHOD_NOT_FOUND = -32601
+	INVALID_PARAMS   = -32602
+	INTERNAL_ERROR   = -32603
+)
+
+// Symbol kinds of the document symbols.
+const (
+	CLASS_SYMBOL       = 5
+	METHOD_SYMBOL      = 6
+	FIELD_SYMBOL       = 8
+	CONSTRUCTOR_SYMBOL = 9
+	FUNCTION_SYMBOL    = 12
+	VARIABLE_SYMBOL    = 13
+)
+
+// Kinds of the completion items.
+const (
+	METHOD_COMPLETION      = 2
+	FUNCTION_COMPLETION    = 3
+	CONSTRUCTOR_COMPLETION = 4
+	FIELD_COMPLETION       = 5
+	VARIABLE_COMPLETION    = 6
+	CLASS_COMPLETION       = 7
+	KEYWORD_COMPLETION     = 14
+)
+
+const ERROR_SEVERITY = 1
+
+// Message is a JSON-RPC request, notification or response. Notifications
+// have no ID.
+type Message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("[%d]: %s", e.Code, e.Message)
+}
+
+// Position is a zero-based line and character offset in a document.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// TextDocumentContentChangeEvent holds the whole text of the document, as
+// the server only supports full document synchronization.
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+type DidChangeTextDocumentParams struct {
+	TextDocument   TextDocumentIdentifier           `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type ServerCapabilities struct {
+	TextDocumentSync       int                `json:"textDocumentSync"`
+	CompletionProvider     *CompletionOptions `json:"completionProvider,omitempty"`
+	DefinitionProvider     bool               `json:"definitionProvider"`
+	ReferencesProvider     bool               `json:"referencesProvider"`
+	HoverProvider          bool               `json:"hoverProvider"`
+	DocumentSymbolProvider bool               `json:"documentSymbolProvider"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   struct {
+		Name string `json:"name"`
+	} `json:"serverInfo"`
+}
+
+// ReadMessage reads a message framed by a 'Content-Length' header.
+func ReadMessage(reader *bufio.Reader) ([]byte, error) {
+	length := -1
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			break
+		}
+
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid header '%s'", line)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("message has no 'Content-Length' header")
+	}
+
+	content := make([]byte, length)
+
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// WriteMessage writes v as a JSON message framed by a 'Content-Length' header.
+func WriteMessage(writer io.Writer, v any) error {
+	content, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
+
+func uriToPath(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Scheme != "file" {
+		return "", fmt.Errorf("'%s' is not a file URI", uri)
+	}
+
+	return filepath.FromSlash(parsed.Path), nil
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// toPosition converts a position of the lexer, whose lines and columns start
+// at 1, to a position of the protocol. Jack source is expected to be ASCII,
+// so columns are used as UTF-16 offsets.
+func toPosition(pos types.Position) Position {
+	return Position{Line: pos.Line - 1, Character: pos.Column - 1}
+}
+
+// fromPosition converts a position of the protocol to a position of the lexer.
+func fromPosition(pos Position) types.Position {
+	return types.Position{Line: pos.Line + 1, Column: pos.Character + 1}
+}
+
+func toRange(span types.Span) Range {
+	return Range{Start: toPosition(span.StartPos), End: toPosition(span.EndPos)}
+}
+
+func (r Range) contains(pos Position) bool {
+	before := func(a, b Position) bool {
+		return a.Line < b.Line || (a.Line == b.Line && a.Character <= b.Character)
+	}
+	return before(r.Start, pos) && before(pos, r.End)
+}
@@ -0,0 +1,455 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/checker"
+	"github.com/MlkMahmud/jack-compiler/completion"
+	"github.com/MlkMahmud/jack-compiler/helpers"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/program"
+	"github.com/MlkMahmud/jack-compiler/resolver"
+	"github.com/MlkMahmud/jack-compiler/stdlib"
+	"github.com/MlkMahmud/jack-compiler/symboltable"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+// key identifies a declaration: a class, a subroutine (Subroutine set), a
+// class variable (Name set) or an argument or local variable of a
+// subroutine (both set).
+type key struct {
+	Class      string
+	Subroutine string
+	Name       string
+}
+
+type declaration struct {
+	// detail describes the declaration, such as 'field int x'.
+	detail string
+	doc    string
+	// span is the span of the declared name, invalid for the OS classes
+	// the program does not implement.
+	span types.Span
+}
+
+// occurrence is a name in the source that declares or refers to a declaration.
+type occurrence struct {
+	key  key
+	span types.Span
+	decl bool
+}
+
+type file struct {
+	class       types.Class
+	diagnostics []Diagnostic
+	occurrences []occurrence
+	// seen holds the offsets of the names that have an occurrence.
+	seen   map[int]bool
+	tokens []types.Token
+}
+
+// workspace is the analysis of every class of a directory, which is the
+// program the classes are compiled into.
+type workspace struct {
+	// classes holds every class the program can call, including the OS classes.
+	classes      []types.Class
+	declarations map[key]declaration
+	files        map[string]*file
+}
+
+// analyze parses and links the '.jack' files of dir. documents holds the
+// text of the files open in the editor, which is used instead of the text
+// on disk and may not have been saved yet.
+func analyze(dir string, documents map[string]string, mode *checker.Mode) *workspace {
+	space := &workspace{declarations: map[key]declaration{}, files: map[string]*file{}}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.jack"))
+
+	for path := range documents {
+		if filepath.Dir(path) == dir && strings.HasSuffix(path, ".jack") {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+	prog := program.New()
+	// parsed holds the files that are free of syntax errors.
+	parsed := map[string]bool{}
+
+	for _, path := range paths {
+		if _, ok := space.files[path]; ok {
+			continue
+		}
+
+		f := &file{seen: map[int]bool{}}
+		space.files[path] = f
+		text, ok := documents[path]
+
+		if !ok {
+			source, err := os.ReadFile(path)
+
+			if err != nil {
+				continue
+			}
+
+			text = string(source)
+		}
+
+		tokens, err := lexer.NewLexer(lexer.WithComments()).TokenizeReader(path, strings.NewReader(text))
+
+		if err != nil {
+			f.addError(err)
+			continue
+		}
+
+		f.tokens = helpers.WithoutComments(tokens)
+		class, err := parser.NewParser().Parse(tokens)
+
+		if err != nil {
+			f.addError(err)
+		}
+
+		if class.Name.Name != "" {
+			f.class = class
+			prog.Add(path, class)
+			parsed[path] = err == nil
+		}
+	}
+
+	// The errors of a file with syntax errors come from its partial class.
+	addErrors := func(err error) {
+		var list program.ErrorList
+
+		if !errors.As(err, &list) {
+			return
+		}
+
+		for _, err := range list {
+			if f := space.files[errorPos(err).Filename]; f != nil && parsed[errorPos(err).Filename] {
+				f.addError(err)
+			}
+		}
+	}
+
+	err := prog.Link()
+	addErrors(err)
+
+	if err == nil && mode != nil {
+		addErrors(prog.Check(*mode))
+	}
+
+	space.index()
+	return space
+}
+
+func errorPos(err error) types.Position {
+	var callError *program.CallError
+	var diagnostic *checker.Diagnostic
+	var resolverError *resolver.ResolverError
+
+	switch {
+	case errors.As(err, &callError):
+		return callError.Pos
+	case errors.As(err, &diagnostic):
+		return diagnostic.Pos
+	case errors.As(err, &resolverError):
+		return resolverError.Pos
+	}
+
+	return types.Position{}
+}
+
+// tokenEnd returns the end of the token at pos, or pos when there is none.
+func (f *file) tokenEnd(pos types.Position) types.Position {
+	for _, token := range f.tokens {
+		if token.Offset == pos.Offset && token.LineNum == pos.Line {
+			return token.End()
+		}
+	}
+	return pos
+}
+
+// location matches the location that starts the message of an error, which
+// the range of a diagnostic makes redundant.
+var location = regexp.MustCompile(`^\([^)]*\)(:\[[^\]]*\])?: `)
+
+func (f *file) addError(err error) {
+	add := func(span types.Span, message string) {
+		f.diagnostics = append(f.diagnostics, Diagnostic{
+			Range:    toRange(span),
+			Severity: ERROR_SEVERITY,
+			Source:   "jack",
+			Message:  location.ReplaceAllString(message, ""),
+		})
+	}
+
+	var lexerError *lexer.LexerError
+	var parserErrors parser.ErrorList
+	var diagnostic *checker.Diagnostic
+
+	switch {
+	case errors.As(err, &lexerError):
+		add(types.Span{StartPos: lexerError.Pos(), EndPos: lexerError.End}, lexerError.Message)
+
+	case errors.As(err, &parserErrors):
+		for _, parserError := range parserErrors {
+			add(types.Span{StartPos: parserError.Pos(), EndPos: parserError.End}, parserError.Error())
+		}
+
+	case errors.As(err, &diagnostic):
+		add(types.Span{StartPos: diagnostic.Pos, EndPos: diagnostic.End}, diagnostic.Error())
+
+	default:
+		pos := errorPos(err)
+		add(types.Span{StartPos: pos, EndPos: f.tokenEnd(pos)}, err.Error())
+	}
+}
+
+func (f *file) add(k key, span types.Span, decl bool) {
+	f.occurrences = append(f.occurrences, occurrence{key: k, span: span, decl: decl})
+	f.seen[span.StartPos.Offset] = true
+}
+
+// at returns the occurrence under the cursor.
+func (f *file) at(pos Position) (occurrence, bool) {
+	for _, occurrence := range f.occurrences {
+		if toRange(occurrence.span).contains(pos) {
+			return occurrence, true
+		}
+	}
+	return occurrence{}, false
+}
+
+// paramSpan returns the span of the name of a parameter, which ends the
+// span of the parameter.
+func paramSpan(param types.Parameter) types.Span {
+	start := param.End()
+	start.Column -= len(param.Name)
+	start.Offset -= len(param.Name)
+	return types.Span{StartPos: start, EndPos: param.End()}
+}
+
+func (space *workspace) declare(k key, detail, doc string, span types.Span) {
+	if _, ok := space.declarations[k]; !ok {
+		space.declarations[k] = declaration{detail: detail, doc: doc, span: span}
+	}
+}
+
+// index records the declarations of every class and the occurrences of
+// their names. The OS classes the program does not implement are declared
+// from their headers.
+func (space *workspace) index() {
+	classes := map[string]bool{}
+	paths := make([]string, 0, len(space.files))
+
+	for path := range space.files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if class := space.files[path].class; class.Name.Name != "" {
+			classes[class.Name.Name] = true
+			space.classes = append(space.classes, class)
+		}
+	}
+
+	headers, _ := stdlib.Classes()
+
+	for _, header := range headers {
+		if name := header.Name.Name; !classes[name] {
+			classes[name] = true
+			space.classes = append(space.classes, header)
+			space.declare(key{Class: name}, "class "+name, header.Doc, types.Span{})
+
+			for _, subroutine := range header.Subroutines {
+				space.declare(key{Class: name, Subroutine: subroutine.Name.Name}, completion.Signature(name, subroutine), subroutine.Doc, types.Span{})
+			}
+		}
+	}
+
+	for _, f := range space.files {
+		if f.class.Name.Name != "" {
+			space.indexClass(f)
+		}
+
+		// The names left are the class names of types and calls.
+		for _, token := range f.tokens {
+			if token.TokenType == types.IDENTIFIER && !f.seen[token.Offset] && classes[token.Lexeme] {
+				f.add(key{Class: token.Lexeme}, types.Span{StartPos: token.Pos(), EndPos: token.End()}, false)
+			}
+		}
+
+		sort.SliceStable(f.occurrences, func(i, j int) bool {
+			return f.occurrences[i].span.StartPos.Offset < f.occurrences[j].span.StartPos.Offset
+		})
+	}
+}
+
+func (space *workspace) indexClass(f *file) {
+	class := f.class
+	className := class.Name.Name
+	classKey := key{Class: className}
+	space.declare(classKey, "class "+className, class.Doc, class.Name.Span)
+	f.add(classKey, class.Name.Span, true)
+
+	classTable := symboltable.New(nil)
+
+	for _, decl := range class.Vars {
+		k := key{Class: className, Name: decl.Name}
+		space.declare(k, fmt.Sprintf("%s %s %s", decl.Kind, decl.Type, decl.Name), decl.Doc, decl.Span)
+		f.add(k, decl.Span, true)
+
+		if !classTable.Has(decl.Name) {
+			classTable.Define(decl.Name, decl.Kind, decl.Type)
+		}
+	}
+
+	for _, subroutine := range class.Subroutines {
+		name := subroutine.Name.Name
+		k := key{Class: className, Subroutine: name}
+		space.declare(k, completion.Signature(className, subroutine), subroutine.Doc, subroutine.Name.Span)
+		f.add(k, subroutine.Name.Span, true)
+
+		table := symboltable.New(classTable)
+		define := func(name string, kind types.SymbolKind, symbolType, doc string, span types.Span) {
+			k := key{Class: className, Subroutine: subroutine.Name.Name, Name: name}
+			space.declare(k, fmt.Sprintf("%s %s %s", kind, symbolType, name), doc, span)
+			f.add(k, span, true)
+
+			if !table.Has(name) {
+				table.Define(name, kind, symbolType)
+			}
+		}
+
+		for _, param := range subroutine.Params {
+			define(param.Name, types.Argument, param.Type, "", paramSpan(param))
+		}
+
+		for _, decl := range subroutine.Body.Vars {
+			define(decl.Name, types.Var, decl.Type, decl.Doc, decl.Span)
+		}
+
+		// variable records a reference to a variable and returns its symbol.
+		variable := func(ident types.Ident) (types.Symbol, bool) {
+			symbol, ok := table.Lookup(ident.Name)
+
+			if ok && (symbol.Kind == types.Field || symbol.Kind == types.Static) {
+				f.add(key{Class: className, Name: ident.Name}, ident.Span, false)
+			} else if ok {
+				f.add(key{Class: className, Subroutine: name, Name: ident.Name}, ident.Span, false)
+			}
+
+			return symbol, ok
+		}
+
+		types.Inspect(subroutine.Body, func(node types.Node) bool {
+			switch node := node.(type) {
+			case types.CallExpr:
+				switch callee := node.Callee.(type) {
+				case types.Ident:
+					f.add(key{Class: className, Subroutine: callee.Name}, callee.Span, false)
+
+				case types.MemberExpr:
+					// 'foo.bar()' calls a method of the class of 'foo', 'Foo.bar()' a function of 'Foo'.
+					calleeClass := callee.Object.Name
+
+					if symbol, ok := variable(callee.Object); ok {
+						calleeClass = symbol.Type
+					} else {
+						f.add(key{Class: calleeClass}, callee.Object.Span, false)
+					}
+
+					f.add(key{Class: calleeClass, Subroutine: callee.Property.Name}, callee.Property.Span, false)
+				}
+
+			case types.Ident:
+				if !f.seen[node.Pos().Offset] {
+					variable(node)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// references returns every occurrence of a declaration, ordered by file.
+func (space *workspace) references(k key, includeDeclaration bool) []Location {
+	paths := make([]string, 0, len(space.files))
+
+	for path := range space.files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+	locations := []Location{}
+
+	for _, path := range paths {
+		for _, occurrence := range space.files[path].occurrences {
+			if occurrence.key == k && (includeDeclaration || !occurrence.decl) {
+				locations = append(locations, Location{URI: pathToURI(path), Range: toRange(occurrence.span)})
+			}
+		}
+	}
+
+	return locations
+}
+
+// symbols outlines the class of a file.
+func (f *file) symbols() []DocumentSymbol {
+	class := f.class
+
+	if class.Name.Name == "" {
+		return []DocumentSymbol{}
+	}
+
+	children := []DocumentSymbol{}
+
+	for _, decl := range class.Vars {
+		kind := VARIABLE_SYMBOL
+
+		if decl.Kind == types.Field {
+			kind = FIELD_SYMBOL
+		}
+
+		children = append(children, DocumentSymbol{
+			Name:           decl.Name,
+			Detail:         fmt.Sprintf("%s %s", decl.Kind, decl.Type),
+			Kind:           kind,
+			Range:          toRange(decl.Span),
+			SelectionRange: toRange(decl.Span),
+		})
+	}
+
+	kinds := map[types.SymbolKind]int{
+		types.Constructor: CONSTRUCTOR_SYMBOL,
+		types.Function:    FUNCTION_SYMBOL,
+		types.Method:      METHOD_SYMBOL,
+	}
+
+	for _, subroutine := range class.Subroutines {
+		children = append(children, DocumentSymbol{
+			Name:           subroutine.Name.Name,
+			Detail:         completion.Signature(class.Name.Name, subroutine),
+			Kind:           kinds[subroutine.Kind],
+			Range:          toRange(subroutine.Span),
+			SelectionRange: toRange(subroutine.Name.Span),
+		})
+	}
+
+	return []DocumentSymbol{{
+		Name:           class.Name.Name,
+		Detail:         "class",
+		Kind:           CLASS_SYMBOL,
+		Range:          toRange(class.Span),
+		SelectionRange: toRange(class.Name.Span),
+		Children:       children,
+	}}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"log"
@@ -8,28 +9,379 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/MlkMahmud/jack-compiler/assembler"
+	"github.com/MlkMahmud/jack-compiler/astjson"
+	"github.com/MlkMahmud/jack-compiler/checker"
+	"github.com/MlkMahmud/jack-compiler/codegen"
 	"github.com/MlkMahmud/jack-compiler/lexer"
 	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/program"
+	"github.com/MlkMahmud/jack-compiler/vmtranslator"
+	"github.com/MlkMahmud/jack-compiler/xmlwriter"
+)
+
+const (
+	EMIT_ASM        = "asm"
+	EMIT_AST_JSON   = "ast-json"
+	EMIT_HACK       = "hack"
+	EMIT_PARSE_XML  = "parse-xml"
+	EMIT_TOKENS_XML = "tokens-xml"
+	EMIT_VM         = "vm"
 )
 
 func printHelpMessage() {
 	log.SetFlags(0)
-	log.Fatalln(("usage:\n go run main.go --src .\t\t\tCompiles all the .jack files in the current directory\n go run main.go --src <fileName.jack>\tCompiles the specified .jack file\n go run main.go --src <dirName>\t\tCompiles all the .jack files in the specified directory"))
+	log.Fatalln(("usage:\n go run main.go --src .\t\t\tCompiles all the .jack files in the current directory\n go run main.go --src <fileName.jack>\tCompiles the specified .jack file\n go run main.go --src <dirName>\t\tCompiles all the .jack files in the specified directory\n go run main.go --src <src> --emit=<mode>\tWrites 'vm' (default), 'tokens-xml' (FooT.xml), 'parse-xml' (Foo.xml) or 'ast-json' (Foo.ast.json) output\n go run main.go --src <dirName> --emit=asm\tTranslates the program, and any other .vm file in <dirName>, to a single <dirName>.asm\n go run main.go --src <src> --emit=hack\tAssembles the program, or the specified .asm file, to a single .hack file\n go run main.go --src <src> --from-ast\tCompiles the .ast.json AST files in <src> instead of .jack files\n go run main.go --src <src> --check=<mode>\tType checks the program in 'lenient' or 'strict' mode before compiling it\n go run main.go run --src <src>\t\tRuns the program on the VM interpreter, see 'run --help'\n go run main.go test --src <src>\t\tRuns .tst test scripts, see 'test --help'\n go run main.go lsp\t\t\t\tServes the Language Server Protocol over stdio, see 'lsp --help'\n go run main.go fmt --src <src>\t\tFormats .jack files in the canonical style, see 'fmt --help'\n go run main.go doc --src <src>\t\tWrites HTML and Markdown API documentation from doc comments, see 'doc --help'"))
+}
+
+var checkModes = map[string]checker.Mode{
+	"lenient": checker.Lenient,
+	"strict":  checker.Strict,
+}
+
+type compiler struct {
+	// asmFile is the file the whole program is written to with '--emit=asm',
+	// or with a '.hack' extension with '--emit=hack'.
+	asmFile       string
+	check         string
+	emit          string
+	fromAST       bool
+	generator     *codegen.CodeGenerator
+	lexer         *lexer.Lexer
+	parser        *parser.Parser
+	parserOptions []parser.Option
+	// siblings are the other source files of the directory of a single
+	// source file, linked with it. Their VM code is only written as part of
+	// the assembly of the program.
+	siblings []string
+	// vmFiles are the '.vm' files of the source directory, translated along with the program.
+	vmFiles []string
+}
+
+func (c *compiler) compileFile(src string) error {
+	var output bytes.Buffer
+	var dest string
+	base := strings.TrimSuffix(src, ".jack")
+
+	tokens, err := c.lexer.Tokenize(src)
+
+	if err != nil {
+		return err
+	}
+
+	if c.emit == EMIT_TOKENS_XML {
+		dest = base + "T.xml"
+		if err := xmlwriter.WriteTokens(&output, tokens); err != nil {
+			return err
+		}
+		return writeOutput(src, dest, output.Bytes())
+	}
+
+	class, err := c.parser.Parse(tokens)
+
+	if err != nil {
+		return err
+	}
+
+	switch c.emit {
+	case EMIT_AST_JSON:
+		data, err := astjson.Marshal(class)
+		if err != nil {
+			return err
+		}
+		return writeOutput(src, base+".ast.json", data)
+
+	default:
+		dest = base + ".xml"
+		if err := xmlwriter.WriteClass(&output, tokens, class); err != nil {
+			return err
+		}
+		return writeOutput(src, dest, output.Bytes())
+	}
+}
+
+// addASTFile adds a class decoded from the JSON form written by '--emit=ast-json' to the program.
+func (c *compiler) addASTFile(prog *program.Program, src string) error {
+	data, err := os.ReadFile(src)
+
+	if err != nil {
+		return err
+	}
+
+	class, err := astjson.Unmarshal(data)
+
+	if err != nil {
+		return fmt.Errorf("(%s): %w", src, err)
+	}
+
+	prog.Add(src, class)
+	return nil
+}
+
+// siblingFiles returns the files with suffix in the directory of src, other than src.
+func siblingFiles(src, suffix string) []string {
+	siblings := []string{}
+	entries, err := os.ReadDir(filepath.Dir(src))
+
+	if err != nil {
+		return siblings
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(filepath.Dir(src), entry.Name())
+
+		if strings.HasSuffix(entry.Name(), suffix) && !entry.IsDir() && filepath.Clean(path) != filepath.Clean(src) {
+			siblings = append(siblings, path)
+		}
+	}
+
+	return siblings
+}
+
+// generateProgram compiles every file, followed by the siblings of a single
+// file, to VM code once the whole program has been loaded and its calls
+// checked, so that nothing is written for a program that does not link.
+func (c *compiler) generateProgram(files []string) ([]vmtranslator.File, bool) {
+	prog := program.New(c.parserOptions...)
+	failed := false
+
+	for _, src := range append(append([]string{}, files...), c.siblings...) {
+		var err error
+
+		if c.fromAST {
+			err = c.addASTFile(prog, src)
+		} else {
+			err = prog.AddFile(src)
+		}
+
+		if err != nil {
+			log.Println(err)
+			failed = true
+		}
+	}
+
+	if failed {
+		return nil, false
+	}
+
+	if err := prog.Link(); err != nil {
+		log.Println(err)
+		return nil, false
+	}
+
+	if mode, ok := checkModes[c.check]; ok {
+		if err := prog.Check(mode); err != nil {
+			log.Println(err)
+			return nil, false
+		}
+	}
+
+	vmFiles := []vmtranslator.File{}
+
+	for index, class := range prog.Classes {
+		src := prog.Paths[index]
+		base := strings.TrimSuffix(strings.TrimSuffix(src, ".jack"), ".ast.json")
+		code, err := c.generator.Generate(class)
+
+		if err != nil {
+			log.Println(err)
+			failed = true
+		}
+
+		vmFiles = append(vmFiles, vmtranslator.File{Name: base + ".vm", Code: code})
+	}
+
+	return vmFiles, !failed
+}
+
+// withVMFiles adds the VM files of the source directory that were not
+// compiled from the program, such as those of an OS implementation.
+func (c *compiler) withVMFiles(vmFiles []vmtranslator.File) ([]vmtranslator.File, error) {
+	compiled := map[string]bool{}
+
+	for _, file := range vmFiles {
+		compiled[file.Name] = true
+	}
+
+	for _, src := range c.vmFiles {
+		if compiled[src] {
+			continue
+		}
+
+		code, err := os.ReadFile(src)
+
+		if err != nil {
+			return nil, err
+		}
+
+		vmFiles = append(vmFiles, vmtranslator.File{Name: src, Code: string(code)})
+	}
+
+	return vmFiles, nil
+}
+
+func (c *compiler) compileProgram(files []string) bool {
+	vmFiles, ok := c.generateProgram(files)
+
+	if !ok {
+		return false
+	}
+
+	if c.emit == EMIT_VM {
+		failed := false
+
+		// Only the requested files are written, not the siblings they are linked with.
+		for index, file := range vmFiles[:len(files)] {
+			if err := writeOutput(files[index], file.Name, []byte(file.Code)); err != nil {
+				log.Println(err)
+				failed = true
+			}
+		}
+
+		return !failed
+	}
+
+	vmFiles, err := c.withVMFiles(vmFiles)
+	asm := ""
+
+	if err == nil {
+		asm, err = vmtranslator.NewTranslator().Translate(vmFiles, true)
+	}
+
+	if err == nil {
+		if c.emit == EMIT_HACK {
+			// Translated code declares every label it jumps to.
+			err = c.assembleFile(filepath.Dir(c.asmFile), c.asmFile, asm, assembler.WithStrictSymbols())
+		} else {
+			err = writeOutput(filepath.Dir(c.asmFile), c.asmFile, []byte(asm))
+		}
+	}
+
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	return true
+}
+
+// assembleFile writes the binary form of the assembly compiled from src
+// next to asmFile, the file the assembly is or would have been written to.
+func (c *compiler) assembleFile(src, asmFile, asm string, options ...assembler.Option) error {
+	hack, err := assembler.NewAssembler(options...).Assemble(asmFile, asm)
+
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(src, strings.TrimSuffix(asmFile, ".asm")+".hack", []byte(hack))
+}
+
+func writeOutput(src, dest string, content []byte) error {
+	if err := os.WriteFile(dest, content, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Compiled %s -> %s\n", src, dest)
+	return nil
 }
 
 func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		runProgram(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		testScripts(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		serveLanguageServer(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		formatSources(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "doc" {
+		generateDocs(os.Args[2:])
+		return
+	}
+
 	var source string
+	var emit string
+	var precedence bool
+	var fromAST bool
+	var check string
 	flag.StringVar(&source, "src", "", "Path to a '.jack' file or a directory containing one or more '.jack' files.")
+	flag.StringVar(&emit, "emit", EMIT_VM, "Output to write next to each '.jack' file: 'vm', 'tokens-xml', 'parse-xml' or 'ast-json', or 'asm' and 'hack' for a single assembly or binary file.")
+	flag.BoolVar(&fromAST, "from-ast", false, "Read classes from '.ast.json' AST files written by '--emit=ast-json' instead of '.jack' files.")
+	flag.StringVar(&check, "check", "", "Type check the program in 'lenient' or 'strict' mode before compiling it.")
+	flag.BoolVar(&precedence, "precedence", false, "Group operators by conventional precedence instead of Jack's strict left-to-right order.")
 	flag.Parse()
 
+	switch emit {
+	case EMIT_ASM, EMIT_HACK, EMIT_VM:
+	case EMIT_AST_JSON, EMIT_PARSE_XML, EMIT_TOKENS_XML:
+		// AST files can only be compiled, the XML forms need the tokens they do not have.
+		if fromAST {
+			printHelpMessage()
+		}
+	default:
+		printHelpMessage()
+	}
+
+	if _, ok := checkModes[check]; check != "" && !ok {
+		printHelpMessage()
+	}
+
+	sourceSuffix := ".jack"
+
+	if fromAST {
+		sourceSuffix = ".ast.json"
+	}
+
 	info, err := os.Stat(source)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	lexer := lexer.NewLexer()
-	parser := parser.NewParser()
+	// Hand-written assembly only needs to be assembled.
+	if emit == EMIT_HACK && strings.HasSuffix(source, ".asm") {
+		asm, err := os.ReadFile(source)
+
+		if err == nil {
+			err = new(compiler).assembleFile(source, source, string(asm))
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	parserOptions := []parser.Option{}
+
+	if precedence {
+		parserOptions = append(parserOptions, parser.WithOperatorPrecedence())
+	}
+
+	c := &compiler{
+		check:         check,
+		emit:          emit,
+		fromAST:       fromAST,
+		generator:     codegen.NewCodeGenerator(),
+		lexer:         lexer.NewLexer(lexer.WithComments()), // for the doc comments of '--emit=ast-json'
+		parser:        parser.NewParser(parserOptions...),
+		parserOptions: parserOptions,
+	}
 
 	jackFiles := []string{}
 
@@ -41,20 +393,48 @@ func main() {
 		}
 
 		for _, entry := range entries {
-			if fileName := entry.Name(); strings.HasSuffix(fileName, ".jack") {
+			if fileName := entry.Name(); strings.HasSuffix(fileName, sourceSuffix) {
 				jackFiles = append(jackFiles, filepath.Join(source, fileName))
+			} else if strings.HasSuffix(fileName, ".vm") {
+				c.vmFiles = append(c.vmFiles, filepath.Join(source, fileName))
 			}
 		}
+
+		// Like the VM translator, name the program after its directory.
+		absolute, err := filepath.Abs(source)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.asmFile = filepath.Join(source, filepath.Base(absolute)+".asm")
 	} else {
-		if !strings.HasSuffix(source, ".jack") {
+		if !strings.HasSuffix(source, sourceSuffix) {
 			printHelpMessage()
 		}
 		jackFiles = append(jackFiles, source)
+		c.siblings = siblingFiles(source, sourceSuffix)
+		c.asmFile = strings.TrimSuffix(strings.TrimSuffix(source, ".jack"), ".ast.json") + ".asm"
+	}
+
+	if c.emit == EMIT_ASM || c.emit == EMIT_HACK || c.emit == EMIT_VM {
+		if !c.compileProgram(jackFiles) {
+			os.Exit(1)
+		}
+		return
 	}
 
+	failed := false
+
 	for _, src := range jackFiles {
-		tokens := lexer.Tokenize(src)
-		class := parser.Parse(tokens)
-		fmt.Printf("ClassName: %s\nVar Count: %d\nSubroutine Count: %d\n", class.Name, len(class.Vars), len(class.Subroutines))
+		// Keep going so that every file's errors are reported in a single run.
+		if err := c.compileFile(src); err != nil {
+			log.Println(err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const TEST_DATA_PATH = "testdata"
+
+// TestMain runs main instead of the tests when the test binary is started
+// by jc, so that the command line is tested as a whole.
+func TestMain(m *testing.M) {
+	if os.Getenv("JC_RUN_MAIN") == "1" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// jc runs the command line with args and returns its output.
+func jc(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"--"}, args...)...)
+	cmd.Env = append(os.Environ(), "JC_RUN_MAIN=1")
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// copyTestData copies files of the test data to a new directory.
+func copyTestData(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(TEST_DATA_PATH, file))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, file), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestCompileSingleFile(t *testing.T) {
+	dir := copyTestData(t, "Square.jack", "SquareGame.jack")
+
+	if output, err := jc(t, "--src", filepath.Join(dir, "SquareGame.jack")); err != nil {
+		t.Fatalf("%s: %s", err, output)
+	}
+
+	actual, err := os.ReadFile(filepath.Join(dir, "SquareGame.vm"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile(filepath.Join(TEST_DATA_PATH, "expected", "SquareGame.vm"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(actual) != string(expected) {
+		t.Errorf("Expected SquareGame.vm to match the expected output")
+	}
+
+	// The class it is linked with is not compiled.
+	if _, err := os.Stat(filepath.Join(dir, "Square.vm")); !os.IsNotExist(err) {
+		t.Errorf("Expected Square.vm not to be written, got %v", err)
+	}
+}
+
+func TestCompileProgramWithoutOS(t *testing.T) {
+	dir := copyTestData(t, "Square.jack", "SquareGame.jack")
+	output, err := jc(t, "--src", dir, "--emit=asm")
+
+	if err == nil {
+		t.Fatalf("Expected the translation to fail without an OS, got: %s", output)
+	}
+
+	if !strings.Contains(output, "function 'Sys.init' is not declared") || strings.Count(output, "function 'Memory.alloc' is not declared") != 1 {
+		t.Errorf("Expected the undeclared functions to be reported, got: %s", output)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, filepath.Base(dir)+".asm")); !os.IsNotExist(err) {
+		t.Errorf("Expected no assembly to be written, got %v", err)
+	}
+}
+
+func TestCompileFromAST(t *testing.T) {
+	dir := copyTestData(t, "Square.jack", "SquareGame.jack")
+
+	if output, err := jc(t, "--src", dir, "--emit=ast-json"); err != nil {
+		t.Fatalf("%s: %s", err, output)
+	}
+
+	// Other JSON files of the directory are not AST files.
+	if err := os.WriteFile(filepath.Join(dir, "settings.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := jc(t, "--src", dir, "--from-ast"); err != nil {
+		t.Fatalf("%s: %s", err, output)
+	}
+
+	for _, file := range []string{"Square.vm", "SquareGame.vm"} {
+		actual, err := os.ReadFile(filepath.Join(dir, file))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := os.ReadFile(filepath.Join(TEST_DATA_PATH, "expected", file))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(actual) != string(expected) {
+			t.Errorf("Expected %s to match the expected output", file)
+		}
+	}
+}
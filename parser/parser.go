@@ -3,9 +3,9 @@ package parser
 import (
 	"errors"
 	"fmt"
-	"os"
-	"runtime/debug"
+	"strings"
 
+	"github.com/MlkMahmud/jack-compiler/diag"
 	"github.com/MlkMahmud/jack-compiler/helpers"
 	"github.com/MlkMahmud/jack-compiler/types"
 )
@@ -91,48 +91,264 @@ type ParserErrorType int
 const (
 	UNEXPECTED_TOKEN ParserErrorType = iota
 	UNEXPECTED_END_OF_INPUT
+	MISSING_TOKEN
 )
 
+func (errorType ParserErrorType) String() string {
+	return []string{
+		"UNEXPECTED_TOKEN", "UNEXPECTED_END_OF_INPUT", "MISSING_TOKEN",
+	}[errorType]
+}
+
 type ParserError struct {
-	errorMessage string
+	diag.Diagnostic
+	Kind  ParserErrorType
+	Token types.Token
 }
 
 func (e *ParserError) Error() string {
-	return e.errorMessage
+	return fmt.Sprintf(
+		"(%s):[%d:%d]: Syntax error: %s",
+		e.Filename,
+		e.Line,
+		e.Column,
+		e.Message,
+	)
+}
+
+// ErrorList is the list of every syntax error found in a single file, in source order.
+type ErrorList = diag.List[*ParserError]
+
+var precedences = map[string]int{
+	"&": 1, "|": 1,
+	"<": 2, ">": 2, "=": 2,
+	"+": 3, "-": 3,
+	"*": 4, "/": 4,
 }
 
+var statementKeywords = []string{"do", "if", "let", "return", "var", "while"}
+var declarationKeywords = []string{"constructor", "field", "function", "method", "static"}
+
 type Parser struct {
-	filename string
-	tokens   []types.Token
+	// comments are the comment tokens of the class, searched for doc comments.
+	comments           []types.Token
+	errors             ErrorList
+	filename           string
+	lastToken          types.Token
+	operatorPrecedence bool
+	tokens             []types.Token
+}
+
+// Option configures optional parser behaviour.
+type Option func(*Parser)
+
+// WithOperatorPrecedence makes the parser group binary operators by
+// conventional precedence (unary > '*' '/' > '+' '-' > '<' '>' '=' > '&' '|')
+// instead of the strict left-to-right order of the Jack specification.
+func WithOperatorPrecedence() Option {
+	return func(parser *Parser) {
+		parser.operatorPrecedence = true
+	}
 }
 
-func NewParser() *Parser {
-	return new(Parser)
+func NewParser(options ...Option) *Parser {
+	parser := new(Parser)
+
+	for _, option := range options {
+		option(parser)
+	}
+
+	return parser
 }
 
 func (parser *Parser) emitError(errorType ParserErrorType, token any) {
-	var errorMessage string
+	panic(parser.newError(errorType, token))
+}
+
+func (parser *Parser) newError(errorType ParserErrorType, token any) *ParserError {
+	parserError := &ParserError{Diagnostic: diag.Diagnostic{Filename: parser.filename}, Kind: errorType}
 
 	switch errorType {
 	case UNEXPECTED_TOKEN:
-		errorMessage = fmt.Sprintf(
-			"(%s):[%d:%d]: Syntax error: unexpected token '%s'\n",
-			parser.filename,
-			token.(types.Token).LineNum,
-			token.(types.Token).ColNum,
-			token.(types.Token).Lexeme,
-		)
+		parserError.Token = token.(types.Token)
+		parserError.Line = parserError.Token.LineNum
+		parserError.Column = parserError.Token.ColNum
+		parserError.End = parserError.Token.End()
+		parserError.Message = fmt.Sprintf("unexpected token '%s'", parserError.Token.Lexeme)
 
 	case UNEXPECTED_END_OF_INPUT:
-		errorMessage = fmt.Sprintf(
-			"(%s): Syntax error: unexpected end of input\n",
-			parser.filename,
-		)
+		// Point just past the last token that was consumed.
+		parserError.End = parser.lastToken.End()
+		parserError.Line = parserError.End.Line
+		parserError.Column = parserError.End.Column
+		parserError.Message = "unexpected end of input"
+
+	case MISSING_TOKEN:
+		// Point just past the last token, where the missing one belongs.
+		parserError.End = parser.lastToken.End()
+		parserError.Line = parserError.End.Line
+		parserError.Column = parserError.End.Column
+		parserError.Message = fmt.Sprintf("expected %s", token)
 
 	default:
 		panic(fmt.Sprintf("Error Type: [%d] is not a valid parser error", errorType))
 	}
-	panic(&ParserError{errorMessage})
+	return parserError
+}
+
+// recoverError records the syntax error being panicked with, if any, and
+// re-panics with anything else. It reports whether an error was recorded.
+func (parser *Parser) recoverError(r any) bool {
+	var parserError *ParserError
+	if e, ok := r.(error); !ok || !errors.As(e, &parserError) {
+		panic(r)
+	}
+
+	if count := len(parser.errors); count > 0 {
+		// The same error surfaces again when it unwinds through several recovery
+		// points, and a node cut short misses every token that should follow.
+		if parser.errors[count-1].Pos() == parserError.Pos() {
+			return true
+		}
+	}
+
+	parser.errors = append(parser.errors, parserError)
+	return true
+}
+
+// synchronizeStatement discards tokens up to and including the next ';', or
+// up to the next '}' or statement/declaration keyword.
+func (parser *Parser) synchronizeStatement(remaining int) {
+	// Always make progress, otherwise the offending token is parsed again.
+	if len(parser.tokens) == remaining && len(parser.tokens) > 0 {
+		parser.getNextToken()
+	}
+
+	for len(parser.tokens) > 0 {
+		token := parser.tokens[0]
+
+		if helpers.IsOneOfSymbols(token, []string{";"}) {
+			parser.getNextToken()
+			return
+		}
+
+		if helpers.IsOneOfSymbols(token, []string{"}"}) ||
+			helpers.IsOneOfKeywords(token, statementKeywords) ||
+			helpers.IsOneOfKeywords(token, declarationKeywords) {
+			return
+		}
+
+		parser.getNextToken()
+	}
+}
+
+// synchronizeDeclaration discards tokens up to the next class-level
+// declaration keyword or the '}' that closes the class.
+func (parser *Parser) synchronizeDeclaration(remaining int) {
+	if len(parser.tokens) == remaining && len(parser.tokens) > 0 {
+		parser.getNextToken()
+	}
+
+	for len(parser.tokens) > 0 {
+		token := parser.tokens[0]
+
+		if helpers.IsOneOfKeywords(token, declarationKeywords) {
+			return
+		}
+
+		if len(parser.tokens) == 1 && helpers.IsOneOfSymbols(token, []string{"}"}) {
+			return
+		}
+
+		parser.getNextToken()
+	}
+}
+
+// atEnd reports whether every token has been consumed.
+func (parser *Parser) atEnd() bool {
+	return len(parser.tokens) == 0
+}
+
+// atBlockEnd reports whether the statements of the current block have ended,
+// either at its '}' or, when that brace is missing, at the next class-level
+// declaration or the end of the input.
+func (parser *Parser) atBlockEnd() bool {
+	if parser.atEnd() {
+		return true
+	}
+
+	nextToken := parser.peekNextToken()
+	return helpers.IsOneOfSymbols(nextToken, []string{"}"}) || helpers.IsOneOfKeywords(nextToken, declarationKeywords)
+}
+
+// expectClosingBrace consumes a '}' without consuming anything else on failure,
+// so that the token can start the next declaration during recovery.
+func (parser *Parser) expectClosingBrace() {
+	if !parser.endIncomplete() {
+		parser.assertToken(parser.peekNextToken(), []string{"}"})
+		parser.getNextToken()
+	}
+}
+
+// expectSymbol consumes the symbol that ends a node. The token that comes
+// instead is left for the recovery, which may need it to close a block.
+func (parser *Parser) expectSymbol(symbol string) {
+	if parser.endIncomplete() {
+		return
+	}
+
+	if helpers.IsOneOfSymbols(parser.peekNextToken(), []string{symbol}) {
+		parser.getNextToken()
+		return
+	}
+
+	if !parser.cutShort("'" + symbol + "'") {
+		parser.emitError(MISSING_TOKEN, "'"+symbol+"'")
+	}
+}
+
+// cutShort reports whether the next token cannot continue the node being
+// parsed, as it ends a statement or a block or starts another one, and
+// records that expected is missing when it does. Like an incomplete trailing
+// node, the node is then kept as it is: a statement being typed in the
+// middle of a file does not cost the subroutine around it.
+func (parser *Parser) cutShort(expected string) bool {
+	next := parser.peekNextToken()
+
+	if !helpers.IsOneOfSymbols(next, []string{";", "}"}) &&
+		!helpers.IsOneOfKeywords(next, statementKeywords) &&
+		!helpers.IsOneOfKeywords(next, declarationKeywords) {
+		return false
+	}
+
+	parser.recoverError(parser.newError(MISSING_TOKEN, expected))
+	return true
+}
+
+// endIncomplete reports whether the input has ended, and records the error
+// when it has. The node being parsed is then kept as it is, which is usually
+// the case of a file being edited: the subroutine around an incomplete
+// trailing statement is still known to an editor.
+func (parser *Parser) endIncomplete() bool {
+	if !parser.atEnd() {
+		return false
+	}
+
+	parser.recoverError(parser.newError(UNEXPECTED_END_OF_INPUT, nil))
+	return true
+}
+
+// span returns the span from start to the end of the last consumed token.
+func (parser *Parser) span(start types.Position) types.Span {
+	return types.Span{StartPos: start, EndPos: parser.lastToken.End()}
+}
+
+func tokenSpan(token types.Token) types.Span {
+	return types.Span{StartPos: token.Pos(), EndPos: token.End()}
+}
+
+func newIdent(token types.Token) types.Ident {
+	return types.Ident{Span: tokenSpan(token), Name: token.Lexeme}
 }
 
 func (parser *Parser) getNextToken() types.Token {
@@ -142,6 +358,7 @@ func (parser *Parser) getNextToken() types.Token {
 
 	token := parser.tokens[0]
 	parser.tokens = parser.tokens[1:]
+	parser.lastToken = token
 	return token
 }
 
@@ -193,6 +410,41 @@ func (parser *Parser) assertToken(token types.Token, terminals []string) {
 	parser.emitError(UNEXPECTED_TOKEN, token)
 }
 
+// docComment returns the text of the doc comment of the declaration starting
+// at next: the last comment between the previous token and next, when it is
+// a '/** */' comment.
+func (parser *Parser) docComment(next types.Token) string {
+	doc := ""
+
+	for _, comment := range parser.comments {
+		if comment.Offset >= next.Offset {
+			break
+		}
+
+		if comment.Offset >= parser.lastToken.End().Offset {
+			doc = comment.Lexeme
+		}
+	}
+
+	if !strings.HasPrefix(doc, "/**") || doc == "/**/" {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSuffix(strings.TrimPrefix(doc, "/**"), "*/"), "\n")
+
+	for index, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimPrefix(line[1:], " ")
+		}
+
+		lines[index] = line
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
 func (parser *Parser) parseParameterList() (params []types.Parameter) {
 	// GRAMMAR: ((type varName), (',' type varName)*)?
 	for nextToken := parser.peekNextToken(); !helpers.IsOneOfSymbols(nextToken, []string{")"}); nextToken = parser.peekNextToken() {
@@ -200,7 +452,11 @@ func (parser *Parser) parseParameterList() (params []types.Parameter) {
 		paramNameToken := parser.getNextToken()
 		parser.assertToken(paramTypeToken, []string{"boolean", "char", "className", "int"})
 		parser.assertToken(paramNameToken, []string{"varName"})
-		params = append(params, types.Parameter{Name: paramNameToken.Lexeme, Type: paramTypeToken.Lexeme})
+		params = append(params, types.Parameter{
+			Span: types.Span{StartPos: paramTypeToken.Pos(), EndPos: paramNameToken.End()},
+			Name: paramNameToken.Lexeme,
+			Type: paramTypeToken.Lexeme,
+		})
 
 		if helpers.IsOneOfSymbols(parser.peekNextToken(), []string{","}) {
 			parser.getNextToken()
@@ -212,6 +468,7 @@ func (parser *Parser) parseParameterList() (params []types.Parameter) {
 
 func (parser *Parser) parseVarDec() (vars []types.VarDecl) {
 	// GRAMMAR: 'var' type varName (',' varName)* ';'
+	doc := parser.docComment(parser.peekNextToken())
 	parser.assertToken(parser.getNextToken(), []string{"var"})
 
 	varTypeToken := parser.getNextToken()
@@ -219,14 +476,14 @@ func (parser *Parser) parseVarDec() (vars []types.VarDecl) {
 	parser.assertToken(varTypeToken, []string{"boolean", "char", "className", "int"})
 	parser.assertToken(varNameToken, []string{"varName"})
 
-	vars = append(vars, types.VarDecl{Name: varNameToken.Lexeme, Type: varTypeToken.Lexeme, Kind: types.Var})
+	vars = append(vars, types.VarDecl{Span: tokenSpan(varNameToken), Doc: doc, Name: varNameToken.Lexeme, Type: varTypeToken.Lexeme, Kind: types.Var})
 
 	for nextToken := parser.peekNextToken(); !helpers.IsOneOfSymbols(nextToken, []string{";"}); nextToken = parser.peekNextToken() {
 		parser.assertToken(parser.getNextToken(), []string{","})
 		nextVarNameToken := parser.getNextToken()
 		parser.assertToken(nextVarNameToken, []string{"varName"})
 
-		vars = append(vars, types.VarDecl{Name: nextVarNameToken.Lexeme, Type: varTypeToken.Lexeme, Kind: types.Var})
+		vars = append(vars, types.VarDecl{Span: tokenSpan(nextVarNameToken), Doc: doc, Name: nextVarNameToken.Lexeme, Type: varTypeToken.Lexeme, Kind: types.Var})
 	}
 
 	parser.assertToken(parser.getNextToken(), []string{";"})
@@ -242,6 +499,11 @@ func (parser *Parser) parseTerm() types.Expr {
 
 	if token.TokenType == types.IDENTIFIER {
 		var expr types.Expr
+
+		if len(parser.tokens) < 2 {
+			return newIdent(parser.getNextToken())
+		}
+
 		nextToken := parser.peekNthToken(1)
 
 		if helpers.IsOneOfSymbols(nextToken, []string{"(", "."}) {
@@ -249,7 +511,7 @@ func (parser *Parser) parseTerm() types.Expr {
 		} else if helpers.IsOneOfSymbols(nextToken, []string{"["}) {
 			expr = parser.parseIndexExpression()
 		} else {
-			expr = types.Ident{Name: parser.getNextToken().Lexeme}
+			expr = newIdent(parser.getNextToken())
 		}
 		return expr
 	}
@@ -264,25 +526,26 @@ func (parser *Parser) parseTerm() types.Expr {
 func (parse *Parser) parseLiteralExpression() types.Literal {
 	// GRAMMAR: 'true' | 'false' | 'null' | 'this' | integerConstant | stringConstant
 	token := parse.getNextToken()
+	span := tokenSpan(token)
 
 	if helpers.IsOneOfKeywords(token, []string{"true", "false"}) {
-		return types.Literal{Type: types.BooleanLiteral, Value: token.Lexeme}
+		return types.Literal{Span: span, Type: types.BooleanLiteral, Value: token.Lexeme}
 	}
 
 	if helpers.IsOneOfKeywords(token, []string{"null"}) {
-		return types.Literal{Type: types.ThisLiteral, Value: token.Lexeme}
+		return types.Literal{Span: span, Type: types.NullLiteral, Value: token.Lexeme}
 	}
 
 	if helpers.IsOneOfKeywords(token, []string{"this"}) {
-		return types.Literal{Type: types.ThisLiteral, Value: token.Lexeme}
+		return types.Literal{Span: span, Type: types.ThisLiteral, Value: token.Lexeme}
 	}
 
 	if token.TokenType == types.INTEGER_CONSTANT {
-		return types.Literal{Type: types.IntegerLiteral, Value: token.Lexeme}
+		return types.Literal{Span: span, Type: types.IntegerLiteral, Value: token.Lexeme}
 	}
 
 	parse.assertToken(token, []string{"stringConstant"})
-	return types.Literal{Type: types.StringLiteral, Value: token.Lexeme}
+	return types.Literal{Span: span, Type: types.StringLiteral, Value: token.Lexeme}
 }
 
 func (parser *Parser) parseIndexExpression() types.IndexExpr {
@@ -292,19 +555,22 @@ func (parser *Parser) parseIndexExpression() types.IndexExpr {
 	parser.assertToken(identToken, []string{"varName"})
 	parser.assertToken(parser.getNextToken(), []string{"["})
 	expr = types.IndexExpr{
-		Object:  types.Ident{Name: identToken.Lexeme},
+		Object:  newIdent(identToken),
 		Indexer: parser.parseExpression(),
 	}
 	parser.assertToken(parser.getNextToken(), []string{"]"})
+	expr.Span = parser.span(identToken.Pos())
 	return expr
 }
 
 func (parser *Parser) parseParenExpression() types.ParenExpr {
 	// GRAMMAR: '(' expression ')'
 	var expr types.ParenExpr
+	start := parser.peekNextToken().Pos()
 	parser.assertToken(parser.getNextToken(), []string{"("})
 	expr.Expression = parser.parseExpression()
 	parser.assertToken(parser.getNextToken(), []string{")"})
+	expr.Span = parser.span(start)
 	return expr
 }
 
@@ -315,89 +581,138 @@ func (parser *Parser) parseSubroutineCall() types.CallExpr {
 	token := parser.getNextToken()
 	parser.assertToken(token, []string{"className", "subroutineName", "varName"})
 
-	if helpers.IsOneOfSymbols(parser.peekNextToken(), []string{"("}) {
-		expr.Callee = types.Ident{Name: token.Lexeme}
+	if parser.atEnd() || helpers.IsOneOfSymbols(parser.peekNextToken(), []string{"("}) {
+		expr.Callee = newIdent(token)
 	} else {
 		parser.assertToken(parser.getNextToken(), []string{"."})
+
+		// 'Foo.' ends an incomplete call, whose name is empty.
+		if parser.endIncomplete() || parser.cutShort("a subroutine name") {
+			dot := parser.lastToken.End()
+			expr.Callee = types.MemberExpr{
+				Span:     parser.span(token.Pos()),
+				Object:   newIdent(token),
+				Property: types.Ident{Span: types.Span{StartPos: dot, EndPos: dot}},
+			}
+			expr.Span = parser.span(token.Pos())
+			return expr
+		}
+
 		subroutineNameToken := parser.getNextToken()
 		parser.assertToken(subroutineNameToken, []string{"subroutineName"})
 
 		expr.Callee = types.MemberExpr{
-			Object:   types.Ident{Name: token.Lexeme},
-			Property: types.Ident{Name: subroutineNameToken.Lexeme},
+			Span:     types.Span{StartPos: token.Pos(), EndPos: subroutineNameToken.End()},
+			Object:   newIdent(token),
+			Property: newIdent(subroutineNameToken),
 		}
 	}
-	expr.Arguments = parser.parseExpressionList()
+	if !parser.endIncomplete() && !parser.cutShort("'('") {
+		expr.Arguments = parser.parseExpressionList()
+	}
+
+	expr.Span = parser.span(token.Pos())
 	return expr
 }
 
 func (parser *Parser) parseUnaryExpression() types.UnaryExpr {
 	// GRAMMAR: ('-' | '~') term
+	parser.assertToken(parser.peekNextToken(), []string{"-", "~"})
 	opToken := parser.getNextToken()
-	parser.assertToken(opToken, []string{"-", "~"})
 	operator := types.UnaryOperator(opToken.Lexeme)
 
+	operand := parser.parseTerm()
+
 	return types.UnaryExpr{
+		Span:     parser.span(opToken.Pos()),
 		Operator: operator,
-		Operand:  parser.parseExpression(),
+		Operand:  operand,
+	}
+}
+
+func (parser *Parser) precedence(token types.Token) int {
+	if !parser.operatorPrecedence {
+		// Jack evaluates every operator strictly from left to right.
+		return 1
 	}
+	return precedences[token.Lexeme]
 }
 
 func (parser *Parser) parseExpression() types.Expr {
 	// GRAMMAR: term (op term)*
-	term := parser.parseTerm()
-	nexToken := parser.peekNextToken()
+	return parser.parseBinaryExpression(1)
+}
+
+func (parser *Parser) parseBinaryExpression(minPrecedence int) types.Expr {
+	// Operators of equal precedence associate to the left, higher ones bind their operands first.
+	left := parser.parseTerm()
 
-	if helpers.IsBinaryOperator(nexToken) {
-		parser.assertToken(parser.getNextToken(), []string{"+", "-", "*", "/", "<", ">", "="})
-		operator := types.BinaryOperator(nexToken.Lexeme)
+	for !parser.atEnd() {
+		nextToken := parser.peekNextToken()
 
-		return types.BinaryExpr{
-			Left:     term,
-			Operator: operator,
-			Right:    parser.parseExpression(),
+		if !helpers.IsBinaryOperator(nextToken) && !helpers.IsLogicalOperator(nextToken) {
+			return left
 		}
-	}
 
-	if helpers.IsLogicalOperator(nexToken) {
-		parser.assertToken(parser.getNextToken(), []string{"&", "|"})
-		operator := types.LogicalOperator(nexToken.Lexeme)
-		return types.LogicalExpr{
-			Left:     term,
-			Operator: operator,
-			Right:    parser.parseExpression(),
+		precedence := parser.precedence(nextToken)
+
+		if precedence < minPrecedence {
+			return left
+		}
+
+		opToken := parser.getNextToken()
+		right := parser.parseBinaryExpression(precedence + 1)
+		span := types.Span{StartPos: left.Pos(), EndPos: right.End()}
+
+		if helpers.IsLogicalOperator(opToken) {
+			left = types.LogicalExpr{
+				Span:     span,
+				Left:     left,
+				Operator: types.LogicalOperator(opToken.Lexeme),
+				Right:    right,
+			}
+		} else {
+			left = types.BinaryExpr{
+				Span:     span,
+				Left:     left,
+				Operator: types.BinaryOperator(opToken.Lexeme),
+				Right:    right,
+			}
 		}
 	}
 
-	return term
+	return left
 }
 
 func (parser *Parser) parseExpressionList() (args []types.Expr) {
 	// GRAMMAR: (expression (',' expression)*)?
 	parser.assertToken(parser.getNextToken(), []string{"("})
 
-	for nextToken := parser.peekNextToken(); !helpers.IsOneOfSymbols(nextToken, []string{")"}); nextToken = parser.peekNextToken() {
+	for !parser.atEnd() && !helpers.IsOneOfSymbols(parser.peekNextToken(), []string{")"}) {
 		args = append(args, parser.parseExpression())
-		if helpers.IsOneOfSymbols(parser.peekNextToken(), []string{","}) {
+		if !parser.atEnd() && helpers.IsOneOfSymbols(parser.peekNextToken(), []string{","}) {
 			// discard "," token before next expresssion in list
 			parser.getNextToken()
 		}
 	}
 
-	parser.assertToken(parser.getNextToken(), []string{")"})
+	parser.expectSymbol(")")
 	return args
 }
 
 func (parser *Parser) parseDoStatement() (stmt types.DoStmt) {
 	// GRAMMAR: 'do' subroutineName '(' expressionList ')' ';' | 'do' (className | varName) '.' subroutineName '(' expressionList ') ';'
+	start := parser.peekNextToken().Pos()
 	parser.assertToken(parser.getNextToken(), []string{"do"})
 	stmt.Expression = parser.parseSubroutineCall()
-	parser.assertToken(parser.getNextToken(), []string{";"})
+	parser.expectSymbol(";")
+	stmt.Span = parser.span(start)
 	return stmt
 }
 
 func (parser *Parser) parseIfStatement() (stmt types.IfStmt) {
 	// GRAMMAR: 'if' '(' expression ')' '{' statements '}' ('else' '{' statements '}')?
+	start := parser.peekNextToken().Pos()
 	parser.assertToken(parser.getNextToken(), []string{"if"})
 	parser.assertToken(parser.getNextToken(), []string{"("})
 	stmt.Condition = parser.parseExpression()
@@ -405,16 +720,18 @@ func (parser *Parser) parseIfStatement() (stmt types.IfStmt) {
 
 	stmt.ThenStmt = parser.parseBlockStatement()
 
-	if helpers.IsOneOfKeywords(parser.peekNextToken(), []string{"else"}) {
+	if !parser.atEnd() && helpers.IsOneOfKeywords(parser.peekNextToken(), []string{"else"}) {
 		// discard 'else' keyword and parse else statement block
 		parser.getNextToken()
 		stmt.ElseStmt = parser.parseBlockStatement()
 	}
+	stmt.Span = parser.span(start)
 	return stmt
 }
 
 func (parser *Parser) parseLetStatement() (stmt types.LetStmt) {
 	// GRAMMAR: 'let' varName ('[' expression ']')? '=' expression ';'
+	start := parser.peekNextToken().Pos()
 	parser.assertToken(parser.getNextToken(), []string{"let"})
 
 	// If the token ahead of the next token is a '[' we're dealing with an index expression.
@@ -423,54 +740,71 @@ func (parser *Parser) parseLetStatement() (stmt types.LetStmt) {
 	} else {
 		identToken := parser.getNextToken()
 		parser.assertToken(identToken, []string{"varName"})
-		stmt.Target = types.Ident{Name: identToken.Lexeme}
+		stmt.Target = newIdent(identToken)
 	}
 
 	parser.assertToken(parser.getNextToken(), []string{"="})
 	stmt.Value = parser.parseExpression()
-	parser.assertToken(parser.getNextToken(), []string{";"})
+	parser.expectSymbol(";")
+	stmt.Span = parser.span(start)
 
 	return stmt
 }
 
 func (parser *Parser) parseReturnStatement() (stmt types.ReturnStmt) {
 	// GRAMMAR: 'return' expression? ';'
+	start := parser.peekNextToken().Pos()
 	parser.assertToken(parser.getNextToken(), []string{"return"})
 
-	if !helpers.IsOneOfSymbols(parser.peekNextToken(), []string{";"}) {
+	if !parser.atEnd() && !helpers.IsOneOfSymbols(parser.peekNextToken(), []string{";"}) {
 		stmt.Expression = parser.parseExpression()
 	}
 
-	parser.assertToken(parser.getNextToken(), []string{";"})
+	parser.expectSymbol(";")
+	stmt.Span = parser.span(start)
 	return stmt
 }
 
 func (parser *Parser) parseWhileStatement() (stmt types.WhileStmt) {
 	// GRAMMAR: 'while' '(' expression ')' '{' statements '}'
+	start := parser.peekNextToken().Pos()
 	parser.assertToken(parser.getNextToken(), []string{"while"})
 	parser.assertToken(parser.getNextToken(), []string{"("})
 	stmt.Condition = parser.parseExpression()
 	parser.assertToken(parser.getNextToken(), []string{")"})
 	stmt.Body = parser.parseBlockStatement()
+	stmt.Span = parser.span(start)
 
 	return stmt
 }
 
 func (parser *Parser) parseBlockStatement() (block types.BlockStmt) {
 	// GRAMMAR: '{' statements '}'
+	start := parser.peekNextToken().Pos()
 	parser.assertToken(parser.getNextToken(), []string{"{"})
 
-	for nextToken := parser.peekNextToken(); !helpers.IsOneOfSymbols(nextToken, []string{"}"}); nextToken = parser.peekNextToken() {
-		block.Statements = append(block.Statements, parser.parseStatement())
+	for !parser.atBlockEnd() {
+		if stmt := parser.parseStatement(); stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
 	}
 
-	parser.assertToken(parser.getNextToken(), []string{"}"})
+	parser.expectClosingBrace()
+	block.Span = parser.span(start)
 	return block
 }
 
-func (parser *Parser) parseStatement() types.Stmt {
+func (parser *Parser) parseStatement() (stmt types.Stmt) {
+	remaining := len(parser.tokens)
+
+	defer func() {
+		if r := recover(); r != nil && parser.recoverError(r) {
+			stmt = nil
+			parser.synchronizeStatement(remaining)
+		}
+	}()
+
 	token := parser.peekNextToken()
-	var stmt types.Stmt
 
 	switch token.Lexeme {
 	case "do":
@@ -491,23 +825,35 @@ func (parser *Parser) parseStatement() types.Stmt {
 
 func (parser *Parser) parseSubroutineBody() (body types.SubroutineBody) {
 	// GRAMMAR: '{' varDec* statements '}'
+	start := parser.peekNextToken().Pos()
 	parser.assertToken(parser.getNextToken(), []string{"{"})
 
-	for nextToken := parser.peekNextToken(); !helpers.IsOneOfSymbols(nextToken, []string{"}"}); nextToken = parser.peekNextToken() {
-		if helpers.IsOneOfKeywords(nextToken, []string{"var"}) {
+	for !parser.atBlockEnd() {
+		if helpers.IsOneOfKeywords(parser.peekNextToken(), []string{"var"}) {
 			vars := parser.parseVarDec()
 			body.Vars = append(body.Vars, vars...)
-		} else {
-			body.Statements = append(body.Statements, parser.parseStatement())
+		} else if stmt := parser.parseStatement(); stmt != nil {
+			body.Statements = append(body.Statements, stmt)
 		}
 	}
 
-	parser.assertToken(parser.getNextToken(), []string{"}"})
+	parser.expectClosingBrace()
+	body.Span = parser.span(start)
 	return body
 }
 
-func (parser *Parser) parseSubroutineDec() (subroutine types.SubroutineDecl) {
+func (parser *Parser) parseSubroutineDec() (subroutine types.SubroutineDecl, ok bool) {
 	// GRAMMAR: ('constructor' | 'function' | 'method') ('void' | type) subroutineName '(' parameterList ')' subroutineBody
+	remaining := len(parser.tokens)
+
+	defer func() {
+		if r := recover(); r != nil && parser.recoverError(r) {
+			ok = false
+			parser.synchronizeDeclaration(remaining)
+		}
+	}()
+
+	subroutine.Doc = parser.docComment(parser.peekNextToken())
 	subroutineKindToken := parser.getNextToken()
 	subroutineTypeToken := parser.getNextToken()
 	subroutineNameToken := parser.getNextToken()
@@ -518,7 +864,7 @@ func (parser *Parser) parseSubroutineDec() (subroutine types.SubroutineDecl) {
 
 	subroutineKind := types.SymbolKind(subroutineKindToken.Lexeme)
 
-	subroutine.Name = types.Ident{Name: subroutineNameToken.Lexeme}
+	subroutine.Name = newIdent(subroutineNameToken)
 	subroutine.Kind = subroutineKind
 	subroutine.Type = subroutineTypeToken.Lexeme
 
@@ -527,11 +873,22 @@ func (parser *Parser) parseSubroutineDec() (subroutine types.SubroutineDecl) {
 	parser.assertToken(parser.getNextToken(), []string{")"})
 
 	subroutine.Body = parser.parseSubroutineBody()
-	return subroutine
+	subroutine.Span = parser.span(subroutineKindToken.Pos())
+	return subroutine, true
 }
 
 func (parser *Parser) parseClassVarDec() (vars []types.VarDecl) {
 	// GRAMMAR: ('static' | 'field') type varName (',' varName)* ';'
+	remaining := len(parser.tokens)
+
+	defer func() {
+		if r := recover(); r != nil && parser.recoverError(r) {
+			vars = nil
+			parser.synchronizeStatement(remaining)
+		}
+	}()
+
+	doc := parser.docComment(parser.peekNextToken())
 	varKindToken := parser.getNextToken()
 	varTypeToken := parser.getNextToken()
 	varNameToken := parser.getNextToken()
@@ -541,7 +898,7 @@ func (parser *Parser) parseClassVarDec() (vars []types.VarDecl) {
 	parser.assertToken(varNameToken, []string{"varName"})
 
 	varKind := types.SymbolKind(varKindToken.Lexeme)
-	vars = append(vars, types.VarDecl{Name: varNameToken.Lexeme, Type: varTypeToken.Lexeme, Kind: varKind})
+	vars = append(vars, types.VarDecl{Span: tokenSpan(varNameToken), Doc: doc, Name: varNameToken.Lexeme, Type: varTypeToken.Lexeme, Kind: varKind})
 
 	// Check if it's a multi var declaration.
 	for nextToken := parser.peekNextToken(); !helpers.IsOneOfSymbols(nextToken, []string{";"}); nextToken = parser.peekNextToken() {
@@ -549,50 +906,73 @@ func (parser *Parser) parseClassVarDec() (vars []types.VarDecl) {
 		parser.assertToken(parser.peekNextToken(), []string{"varName"})
 
 		nextVarNameToken := parser.getNextToken()
-		vars = append(vars, types.VarDecl{Name: nextVarNameToken.Lexeme, Type: varTypeToken.Lexeme, Kind: varKind})
+		vars = append(vars, types.VarDecl{Span: tokenSpan(nextVarNameToken), Doc: doc, Name: nextVarNameToken.Lexeme, Type: varTypeToken.Lexeme, Kind: varKind})
 	}
 
 	parser.assertToken(parser.getNextToken(), []string{";"})
 	return vars
 }
 
-func (parser *Parser) Parse(tokens []types.Token) (class types.Class) {
+// Parse builds the class declared by tokens. When the class contains syntax
+// errors, Parse recovers from each of them and returns the partial class
+// together with an ErrorList of every error found.
+func (parser *Parser) Parse(tokens []types.Token) (class types.Class, err error) {
+	parser.errors = nil
+	parser.comments = nil
+
+	for _, token := range tokens {
+		if token.TokenType == types.COMMENT {
+			parser.comments = append(parser.comments, token)
+		}
+	}
+
+	tokens = helpers.WithoutComments(tokens)
+
 	defer func() {
 		if r := recover(); r != nil {
-			var parserError *ParserError
-			if errors.As(r.(error), &parserError) {
-				fmt.Println(r)
-			} else {
-				debug.PrintStack()
-			}
-			os.Exit(1)
+			parser.recoverError(r)
 		}
+		err = parser.errors.Err()
 	}()
 
 	if len(tokens) < 1 {
-		return class
+		return class, nil
 	}
 
 	parser.filename = tokens[0].Filename
+	parser.lastToken = types.Token{}
 	parser.tokens = tokens
+	start := parser.peekNextToken().Pos()
+	class.Doc = parser.docComment(parser.peekNextToken())
 	parser.assertToken(parser.getNextToken(), []string{"class"})
 	classNameToken := parser.getNextToken()
 	parser.assertToken(classNameToken, []string{"className"})
 	parser.assertToken(parser.getNextToken(), []string{"{"})
 
-	class.Name = types.Ident{Name: classNameToken.Lexeme}
+	class.Name = newIdent(classNameToken)
+
+	for len(parser.tokens) > 0 && !helpers.IsOneOfSymbols(parser.peekNextToken(), []string{"}"}) {
+		nextToken := parser.peekNextToken()
 
-	for nextToken := parser.peekNextToken(); !helpers.IsOneOfSymbols(nextToken, []string{"}"}); nextToken = parser.peekNextToken() {
 		if helpers.IsOneOfKeywords(nextToken, []string{"field", "static"}) {
 			vars := parser.parseClassVarDec()
 			class.Vars = append(class.Vars, vars...)
 		} else if helpers.IsOneOfKeywords(nextToken, []string{"constructor", "function", "method"}) {
-			subroutine := parser.parseSubroutineDec()
-			class.Subroutines = append(class.Subroutines, subroutine)
+			if subroutine, ok := parser.parseSubroutineDec(); ok {
+				class.Subroutines = append(class.Subroutines, subroutine)
+			}
 		} else {
-			parser.emitError(UNEXPECTED_TOKEN, nextToken)
+			parser.recoverError(parser.newError(UNEXPECTED_TOKEN, nextToken))
+			parser.synchronizeDeclaration(len(parser.tokens))
 		}
 	}
 
-	return class
+	parser.assertToken(parser.getNextToken(), []string{"}"})
+	class.Span = parser.span(start)
+
+	if len(parser.tokens) > 0 {
+		parser.emitError(UNEXPECTED_TOKEN, parser.peekNextToken())
+	}
+
+	return class, nil
 }
@@ -2,6 +2,7 @@ package parser_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -9,8 +10,9 @@ import (
 	"strings"
 	"testing"
 
-	. "github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/lexer"
 	. "github.com/MlkMahmud/jack-compiler/parser"
+	. "github.com/MlkMahmud/jack-compiler/types"
 	"github.com/nsf/jsondiff"
 )
 
@@ -28,7 +30,7 @@ func readFileContent(filename string) []byte {
 
 func TestParser(t *testing.T) {
 	files := []string{"Array", "Square", "SquareGame"}
-	lexer := NewLexer()
+	lexer := lexer.NewLexer()
 	parser := NewParser()
 
 	for _, file := range files {
@@ -36,9 +38,19 @@ func TestParser(t *testing.T) {
 			filePath := path.Join(TEST_DATA_PATH, strings.Join([]string{file, "jack"}, "."))
 			cmpFilePath := path.Join(TEST_DATA_PATH, "expected", strings.Join([]string{file, "json"}, "."))
 			
-			tokens := lexer.Tokenize(filePath)
-			class := parser.Parse(tokens)
-			
+			tokens, err := lexer.Tokenize(filePath)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			class, err := parser.Parse(tokens)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+
 			expected := readFileContent(cmpFilePath)
 
 			actual, err := json.Marshal(class)
@@ -55,3 +67,406 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+func TestParserErrors(t *testing.T) {
+	tests := map[string]struct {
+		source string
+		kind   ParserErrorType
+		line   int
+		column int
+	}{
+		"UnexpectedToken":      {"class Main {\n  function void main() {\n    let = 1;\n  }\n}", UNEXPECTED_TOKEN, 3, 9},
+		"UnexpectedEndOfInput": {"class Main {\n  function void main() {", UNEXPECTED_END_OF_INPUT, 2, 25},
+	}
+	lexer := lexer.NewLexer()
+	parser := NewParser()
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			filePath := path.Join(t.TempDir(), "Main.jack")
+			if err := os.WriteFile(filePath, []byte(test.source), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			tokens, err := lexer.Tokenize(filePath)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = parser.Parse(tokens)
+			errorList, ok := err.(ErrorList)
+
+			if !ok || len(errorList) != 1 {
+				t.Fatalf("Expected a single syntax error, got %v", err)
+			}
+
+			parserError := errorList[0]
+
+			if parserError.Kind != test.kind || parserError.Line != test.line || parserError.Column != test.column {
+				t.Errorf("Expected %s at %d:%d, got %s at %d:%d", test.kind, test.line, test.column, parserError.Kind, parserError.Line, parserError.Column)
+			}
+		})
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	source := `class Main {
+  field int x y;
+  static boolean flag;
+  function void main() {
+    let x = ;
+    do Output.printInt(1);
+    let = 2;
+    return;
+  }
+  method int foo( {
+    return 1;
+  }
+  function void bar() {
+    return;
+  }
+}`
+	lexer := lexer.NewLexer()
+	parser := NewParser()
+	filePath := path.Join(t.TempDir(), "Main.jack")
+
+	if err := os.WriteFile(filePath, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := lexer.Tokenize(filePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := parser.Parse(tokens)
+	errorList, ok := err.(ErrorList)
+
+	if !ok {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+
+	lines := []int{}
+	for _, parserError := range errorList {
+		lines = append(lines, parserError.Line)
+	}
+
+	if fmt.Sprint(lines) != "[2 5 7 10]" {
+		t.Errorf("Expected errors on lines [2 5 7 10], got %v", lines)
+	}
+
+	if len(class.Vars) != 1 || class.Vars[0].Name != "flag" {
+		t.Errorf("Expected the partial class to declare 'flag', got %v", class.Vars)
+	}
+
+	if len(class.Subroutines) != 2 || class.Subroutines[0].Name.Name != "main" || class.Subroutines[1].Name.Name != "bar" {
+		t.Fatalf("Expected the partial class to declare 'main' and 'bar', got %v", class.Subroutines)
+	}
+
+	if count := len(class.Subroutines[0].Body.Statements); count != 2 {
+		t.Errorf("Expected 'main' to keep its 2 valid statements, got %d", count)
+	}
+}
+
+func TestParserMissingTerminator(t *testing.T) {
+	source := `class Main {
+  method void foo() {
+    let x = 1
+    let y = 2;
+    do Output.printInt(y)
+  }
+  method void bar() {
+    return;
+  }
+}`
+	tokens, err := lexer.NewLexer().TokenizeReader("Main.jack", strings.NewReader(source))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := NewParser().Parse(tokens)
+	errorList, ok := err.(ErrorList)
+
+	if !ok {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+
+	expected := []string{
+		"(Main.jack):[3:14]: Syntax error: expected ';'",
+		"(Main.jack):[5:26]: Syntax error: expected ';'",
+	}
+	actual := []string{}
+
+	for _, parserError := range errorList {
+		actual = append(actual, parserError.Error())
+	}
+
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	// The statements missing their ';' are kept, and the next one is not swallowed.
+	if len(class.Subroutines) != 2 || class.Subroutines[0].Name.Name != "foo" || class.Subroutines[1].Name.Name != "bar" {
+		t.Fatalf("Expected the class to declare 'foo' and 'bar', got %v", class.Subroutines)
+	}
+
+	if count := len(class.Subroutines[0].Body.Statements); count != 3 {
+		t.Errorf("Expected 'foo' to keep its 3 statements, got %d", count)
+	}
+}
+
+func group(expr Expr) string {
+	switch expr := expr.(type) {
+	case BinaryExpr:
+		return fmt.Sprintf("(%s %s %s)", group(expr.Left), expr.Operator, group(expr.Right))
+	case LogicalExpr:
+		return fmt.Sprintf("(%s %s %s)", group(expr.Left), expr.Operator, group(expr.Right))
+	case UnaryExpr:
+		return fmt.Sprintf("(%s%s)", expr.Operator, group(expr.Operand))
+	default:
+		return expr.String()
+	}
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		expression  string
+		leftToRight string
+		precedence  string
+	}{
+		{"a - b - c", "((a - b) - c)", "((a - b) - c)"},
+		{"1 + 2 * 3", "((1 + 2) * 3)", "(1 + (2 * 3))"},
+		{"-x + y", "((-x) + y)", "((-x) + y)"},
+		{"a < b & c > d", "(((a < b) & c) > d)", "((a < b) & (c > d))"},
+		{"a * b - c / d", "(((a * b) - c) / d)", "((a * b) - (c / d))"},
+	}
+	lexer := lexer.NewLexer()
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			source := fmt.Sprintf("class Main { function int main() { return %s; } }", test.expression)
+			filePath := path.Join(t.TempDir(), "Main.jack")
+
+			if err := os.WriteFile(filePath, []byte(source), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			tokens, err := lexer.Tokenize(filePath)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			modes := []struct {
+				expected string
+				parser   *Parser
+			}{
+				{test.leftToRight, NewParser()},
+				{test.precedence, NewParser(WithOperatorPrecedence())},
+			}
+
+			for _, mode := range modes {
+				class, err := mode.parser.Parse(tokens)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				stmt := class.Subroutines[0].Body.Statements[0].(ReturnStmt)
+
+				if actual := group(stmt.Expression); actual != mode.expected {
+					t.Errorf("Expected '%s' to parse as %s, got %s", test.expression, mode.expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestPositions(t *testing.T) {
+	source := `class Main {
+  field int x;
+  method void run(int n, char c) {
+    let x = (n + 1) * 2;
+    if (x > Math.abs("ab")) { do Output.printInt(x); }
+    return;
+  }
+}`
+	lexer := lexer.NewLexer()
+	parser := NewParser()
+	filePath := path.Join(t.TempDir(), "Main.jack")
+
+	if err := os.WriteFile(filePath, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := lexer.Tokenize(filePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := parser.Parse(tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subroutine := class.Subroutines[0]
+	letStmt := subroutine.Body.Statements[0].(LetStmt)
+	ifStmt := subroutine.Body.Statements[1].(IfStmt)
+	condition := ifStmt.Condition.(BinaryExpr)
+
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{class.Name, "Main"},
+		{class.Vars[0], "x"},
+		{subroutine.Params[1], "char c"},
+		{letStmt, "let x = (n + 1) * 2;"},
+		{letStmt.Value, "(n + 1) * 2"},
+		{letStmt.Value.(BinaryExpr).Left, "(n + 1)"},
+		{condition.Right, `Math.abs("ab")`},
+		{condition.Right.(CallExpr).Arguments[0], `"ab"`},
+		{ifStmt.ThenStmt, "{ do Output.printInt(x); }"},
+		{subroutine.Body.Statements[2], "return;"},
+	}
+
+	for _, test := range tests {
+		start, end := test.node.Pos(), test.node.End()
+
+		if start.Filename != filePath || end.Filename != filePath {
+			t.Errorf("Expected '%s' to be positioned in %s, got %s", test.expected, filePath, start)
+		}
+
+		if actual := source[start.Offset:end.Offset]; actual != test.expected {
+			t.Errorf("Expected the span at %s to cover '%s', got '%s'", start, test.expected, actual)
+		}
+	}
+
+	if pos := condition.Pos(); pos.Line != 5 || pos.Column != 9 {
+		t.Errorf("Expected the if condition to start at 5:9, got %d:%d", pos.Line, pos.Column)
+	}
+
+	if !strings.HasPrefix(source[class.Pos().Offset:class.End().Offset], "class Main {") || class.End().Offset != len(source) {
+		t.Errorf("Expected the class to span the whole file, got %s-%s", class.Pos(), class.End())
+	}
+}
+
+func TestIncompleteInput(t *testing.T) {
+	source := "class Main {\n  function void main() {\n    var Foo f;\n    if (true) {\n      let x = f."
+	lexer := lexer.NewLexer()
+	parser := NewParser()
+	filePath := path.Join(t.TempDir(), "Main.jack")
+
+	if err := os.WriteFile(filePath, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := lexer.Tokenize(filePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := parser.Parse(tokens)
+	errorList, ok := err.(ErrorList)
+
+	if !ok || len(errorList) != 1 || errorList[0].Kind != UNEXPECTED_END_OF_INPUT {
+		t.Fatalf("Expected a single unexpected end of input, got %v", err)
+	}
+
+	if len(class.Subroutines) != 1 || len(class.Subroutines[0].Body.Vars) != 1 {
+		t.Fatalf("Expected the incomplete subroutine to be kept, got %v", class.Subroutines)
+	}
+
+	ifStmt := class.Subroutines[0].Body.Statements[0].(IfStmt)
+	call := ifStmt.ThenStmt.Statements[0].(LetStmt).Value.(CallExpr)
+	callee := call.Callee.(MemberExpr)
+
+	if callee.Object.Name != "f" || callee.Property.Name != "" || callee.Property.Pos().Offset != len(source) {
+		t.Errorf("Expected an incomplete call on 'f' ending the input, got '%s'", callee)
+	}
+
+	// In the middle of a file, the statement being typed is cut short by the code that follows it.
+	source = "class Main {\n  function void main() {\n    var Foo f;\n    do f.\n  }\n  function void run() {\n    return;\n  }\n}"
+
+	if tokens, err = lexer.TokenizeReader("Main.jack", strings.NewReader(source)); err != nil {
+		t.Fatal(err)
+	}
+
+	class, err = parser.Parse(tokens)
+
+	if errorList, ok := err.(ErrorList); !ok || len(errorList) != 1 || errorList[0].Message != "expected a subroutine name" {
+		t.Fatalf("Expected a single missing subroutine name, got %v", err)
+	}
+
+	if len(class.Subroutines) != 2 || len(class.Subroutines[0].Body.Statements) != 1 {
+		t.Errorf("Expected the subroutine being edited to be kept with its incomplete call, got %v", class.Subroutines)
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	source := `/** A point.
+ *
+ * Immutable. */
+class Point {
+  /** The coordinates. */
+  field int x, y;
+  // Not a doc comment.
+  static int count;
+
+  /**
+   * Creates a point.
+   * @param ax the abscissa
+   */
+  constructor Point new(int ax, int ay) {
+    /** The sum. */ var int sum;
+    /** Detached. */
+    let x = ax;
+    return this;
+  }
+
+  /** Detached by a comment. */
+  // Gets x.
+  method int getX() { return x; }
+}
+`
+	tokens, err := lexer.NewLexer(lexer.WithComments()).TokenizeReader("Point.jack", strings.NewReader(source))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := NewParser().Parse(tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct{ actual, doc string }{
+		{class.Doc, "A point.\n\nImmutable."},
+		{class.Vars[0].Doc, "The coordinates."},
+		{class.Vars[1].Doc, "The coordinates."},
+		{class.Vars[2].Doc, ""},
+		{class.Subroutines[0].Doc, "Creates a point.\n@param ax the abscissa"},
+		{class.Subroutines[0].Body.Vars[0].Doc, "The sum."},
+		{class.Subroutines[1].Doc, ""},
+	}
+
+	for _, test := range expected {
+		if test.actual != test.doc {
+			t.Errorf("Expected doc %q, got %q", test.doc, test.actual)
+		}
+	}
+
+	// Without comments, there are no docs.
+	tokens, _ = lexer.NewLexer().TokenizeReader("Point.jack", strings.NewReader(source))
+
+	if class, _ := NewParser().Parse(tokens); class.Doc != "" || class.Subroutines[0].Doc != "" {
+		t.Error("Expected no doc comments")
+	}
+}
@@ -0,0 +1,316 @@
+package program
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/checker"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/resolver"
+	"github.com/MlkMahmud/jack-compiler/stdlib"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+A program is every class of a directory, compiled together. Loading parses
+all of them first, so that once they are linked every call can be checked
+against the subroutine it names:
+
+	Foo.bar()  'Foo' is a class of the program and 'bar' one of its functions
+	           or constructors.
+	foo.bar()  'foo' is a variable holding an object and 'bar' a method of
+	           its class.
+	bar()      'bar' is a method of the current class, called on 'this' from
+	           a method or a constructor.
+
+Every call must also pass as many arguments as the subroutine has parameters.
+
+The classes of the Jack OS are declared by the headers of the stdlib package,
+unless the program ships its own implementation of them.
+*/
+
+type CallErrorType int
+
+const (
+	UNDEFINED_CLASS CallErrorType = iota
+	UNDEFINED_SUBROUTINE
+	WRONG_ARGUMENT_COUNT
+	WRONG_CALL_KIND
+)
+
+func (errorType CallErrorType) String() string {
+	return []string{
+		"UNDEFINED_CLASS", "UNDEFINED_SUBROUTINE", "WRONG_ARGUMENT_COUNT", "WRONG_CALL_KIND",
+	}[errorType]
+}
+
+type CallError struct {
+	Kind    CallErrorType
+	Message string
+	Pos     types.Position
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf(
+		"(%s):[%d:%d]: Call error: %s",
+		e.Pos.Filename,
+		e.Pos.Line,
+		e.Pos.Column,
+		e.Message,
+	)
+}
+
+// ErrorList is the list of every error found in a program, in the order of its files.
+type ErrorList []error
+
+func (list ErrorList) Error() string {
+	messages := make([]string, 0, len(list))
+
+	for _, err := range list {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Err returns the list as an error, or nil when it is empty.
+func (list ErrorList) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+type Program struct {
+	// Classes holds the classes of the program, resolved once it is linked.
+	Classes []types.Class
+	// Paths holds the file each class was read from.
+	Paths []string
+	// headers holds the OS classes the program does not implement itself.
+	headers  []types.Class
+	index    map[string]map[string]types.SubroutineDecl
+	lexer    *lexer.Lexer
+	parser   *parser.Parser
+	resolver *resolver.Resolver
+}
+
+func New(options ...parser.Option) *Program {
+	return &Program{
+		lexer:    lexer.NewLexer(),
+		parser:   parser.NewParser(options...),
+		resolver: resolver.NewResolver(),
+	}
+}
+
+// Load parses every file and links them into a program.
+func Load(paths []string, options ...parser.Option) (*Program, error) {
+	program := New(options...)
+	errors := ErrorList{}
+
+	for _, path := range paths {
+		if err := program.AddFile(path); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) > 0 {
+		return program, errors
+	}
+
+	return program, program.Link()
+}
+
+// Add adds a class read from path to the program.
+func (program *Program) Add(path string, class types.Class) {
+	program.Classes = append(program.Classes, class)
+	program.Paths = append(program.Paths, path)
+}
+
+// AddFile parses a '.jack' file and adds its class to the program.
+func (program *Program) AddFile(path string) error {
+	tokens, err := program.lexer.Tokenize(path)
+
+	if err != nil {
+		return err
+	}
+
+	return program.addTokens(path, tokens)
+}
+
+// AddSource parses the source of a '.jack' file that need not exist on disk,
+// and adds its class to the program.
+func (program *Program) AddSource(path, source string) error {
+	tokens, err := program.lexer.TokenizeReader(path, strings.NewReader(source))
+
+	if err != nil {
+		return err
+	}
+
+	return program.addTokens(path, tokens)
+}
+
+func (program *Program) addTokens(path string, tokens []types.Token) error {
+	class, err := program.parser.Parse(tokens)
+
+	if err != nil {
+		return err
+	}
+
+	program.Add(path, class)
+	return nil
+}
+
+// Lookup returns the subroutine of a class of the program.
+func (program *Program) Lookup(className, name string) (types.SubroutineDecl, bool) {
+	subroutine, ok := program.index[className][name]
+	return subroutine, ok
+}
+
+func (program *Program) indexClass(class types.Class) {
+	name := class.Name.Name
+	program.index[name] = map[string]types.SubroutineDecl{}
+
+	for _, subroutine := range class.Subroutines {
+		program.index[name][subroutine.Name.Name] = subroutine
+	}
+}
+
+// Link resolves every class of the program, builds the index of their
+// subroutines and checks every call.
+func (program *Program) Link() error {
+	errors := ErrorList{}
+	headers, err := stdlib.Classes()
+
+	if err != nil {
+		return err
+	}
+
+	program.headers = nil
+	program.index = map[string]map[string]types.SubroutineDecl{}
+
+	for index, class := range program.Classes {
+		if _, ok := program.index[class.Name.Name]; ok {
+			errors = append(errors, fmt.Errorf("(%s): class '%s' has already been declared", program.Paths[index], class.Name))
+			continue
+		}
+		program.indexClass(class)
+	}
+
+	for _, header := range headers {
+		if _, ok := program.index[header.Name.Name]; !ok {
+			program.headers = append(program.headers, header)
+			program.indexClass(header)
+		}
+	}
+
+	classNames := make([]string, 0, len(program.index))
+
+	for name := range program.index {
+		classNames = append(classNames, name)
+	}
+
+	program.resolver.DeclareClasses(classNames...)
+
+	for index, class := range program.Classes {
+		resolved, err := program.resolver.Resolve(class)
+
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			program.Classes[index] = resolved
+		}
+	}
+
+	// Unresolved classes would report every call on a variable as a call on a class.
+	if len(errors) > 0 {
+		return errors
+	}
+
+	for _, class := range program.Classes {
+		for _, subroutine := range class.Subroutines {
+			types.Inspect(subroutine.Body, func(node types.Node) bool {
+				if call, ok := node.(types.CallExpr); ok {
+					if err := program.checkCall(class, subroutine, call); err != nil {
+						errors = append(errors, err)
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	return errors.Err()
+}
+
+// Check type checks every class of a linked program.
+func (program *Program) Check(mode checker.Mode) error {
+	errors := ErrorList{}
+	typeChecker := checker.NewChecker(mode)
+
+	classes := append(append([]types.Class{}, program.Classes...), program.headers...)
+
+	for _, class := range program.Classes {
+		for _, diagnostic := range typeChecker.Check(class, classes) {
+			errors = append(errors, diagnostic)
+		}
+	}
+
+	return errors.Err()
+}
+
+func (program *Program) checkCall(class types.Class, caller types.SubroutineDecl, call types.CallExpr) error {
+	newError := func(kind CallErrorType, format string, args ...any) error {
+		return &CallError{Kind: kind, Message: fmt.Sprintf(format, args...), Pos: call.Pos()}
+	}
+
+	var className, name string
+	// onObject is true when the call has an object to operate on.
+	var onObject bool
+
+	switch callee := call.Callee.(type) {
+	case types.Ident:
+		className, name, onObject = class.Name.Name, callee.Name, true
+
+		if caller.Kind == types.Function {
+			return newError(WRONG_CALL_KIND, "'%s' cannot be called without an object from function '%s'", name, caller.Name)
+		}
+
+	case types.MemberExpr:
+		className, name = callee.Object.Name, callee.Property.Name
+
+		if symbol := callee.Object.Symbol; symbol != nil {
+			className, onObject = symbol.Type, true
+
+			if symbol.Type == "int" || symbol.Type == "char" || symbol.Type == "boolean" {
+				return newError(WRONG_CALL_KIND, "cannot call '%s' on '%s' of type '%s'", name, callee.Object, symbol.Type)
+			}
+		}
+
+	default:
+		return newError(WRONG_CALL_KIND, "'%s' cannot be called", call.Callee)
+	}
+
+	if _, ok := program.index[className]; !ok {
+		return newError(UNDEFINED_CLASS, "class '%s' is not defined", className)
+	}
+
+	subroutine, ok := program.Lookup(className, name)
+
+	if !ok {
+		return newError(UNDEFINED_SUBROUTINE, "class '%s' has no subroutine '%s'", className, name)
+	}
+
+	if isMethod := subroutine.Kind == types.Method; isMethod != onObject {
+		if isMethod {
+			return newError(WRONG_CALL_KIND, "'%s.%s' is a method and must be called on an object", className, name)
+		}
+		return newError(WRONG_CALL_KIND, "'%s.%s' is a %s and must be called as '%s.%s()'", className, name, subroutine.Kind, className, name)
+	}
+
+	if expected, actual := len(subroutine.Params), len(call.Arguments); expected != actual {
+		return newError(WRONG_ARGUMENT_COUNT, "'%s.%s' expects %d argument(s), got %d", className, name, expected, actual)
+	}
+
+	return nil
+}
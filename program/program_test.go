@@ -0,0 +1,159 @@
+package program_test
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/checker"
+	. "github.com/MlkMahmud/jack-compiler/program"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func TestLoad(t *testing.T) {
+	paths := []string{}
+
+	for _, file := range []string{"Array", "Square", "SquareGame"} {
+		paths = append(paths, path.Join(TEST_DATA_PATH, file+".jack"))
+	}
+
+	program, err := Load(paths)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(program.Classes) != 3 {
+		t.Fatalf("Expected 3 classes, got %d", len(program.Classes))
+	}
+
+	if subroutine, ok := program.Lookup("Square", "moveUp"); !ok || subroutine.Kind != "method" {
+		t.Errorf("Expected 'Square.moveUp' to be indexed as a method, got %+v", subroutine)
+	}
+
+	if subroutine, ok := program.Lookup("Keyboard", "readInt"); !ok || len(subroutine.Params) != 1 {
+		t.Errorf("Expected the OS class 'Keyboard' to be indexed, got %+v", subroutine)
+	}
+
+	for _, mode := range []checker.Mode{checker.Lenient, checker.Strict} {
+		if err := program.Check(mode); err != nil {
+			t.Errorf("Expected the program to type check in %s mode, got:\n%s", mode, err)
+		}
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	source := `class Main {
+  field int count;
+  function void main() {
+    var Square s;
+    var int x;
+    do Square.moveUp();
+    let s = Square.new(1, 2);
+    do s.new(1, 2, 3);
+    do Nope.run();
+    do Square.fly();
+    do draw();
+    do x.draw();
+    do Output.printInt(Square.new(1, 2, 3));
+    do Output.printInt(1, 2);
+    do Output.print(1);
+    return;
+  }
+  method void draw() {
+    do draw();
+    do Main.main(1);
+    return;
+  }
+}`
+	program := New()
+
+	if err := program.AddSource("Main.jack", source); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := program.AddFile(path.Join(TEST_DATA_PATH, "Square.jack")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := program.Link()
+	errorList, ok := err.(ErrorList)
+
+	if !ok {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+
+	expected := []string{
+		"6:8 WRONG_CALL_KIND",
+		"7:13 WRONG_ARGUMENT_COUNT",
+		"8:8 WRONG_CALL_KIND",
+		"9:8 UNDEFINED_CLASS",
+		"10:8 UNDEFINED_SUBROUTINE",
+		"11:8 WRONG_CALL_KIND",
+		"12:8 WRONG_CALL_KIND",
+		"14:8 WRONG_ARGUMENT_COUNT",
+		"15:8 UNDEFINED_SUBROUTINE",
+		"20:8 WRONG_ARGUMENT_COUNT",
+	}
+	actual := []string{}
+
+	for _, err := range errorList {
+		callError, ok := err.(*CallError)
+		if !ok {
+			t.Fatalf("Expected a CallError, got %v", err)
+		}
+		actual = append(actual, fmt.Sprintf("%d:%d %s", callError.Pos.Line, callError.Pos.Column, callError.Kind))
+	}
+
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestOverrideOSClass(t *testing.T) {
+	program := New()
+
+	if err := program.AddSource("Main.jack", `class Main {
+  function void main() {
+    do Output.printInt(Math.abs(1, 2));
+    return;
+  }
+}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := program.AddSource("Math.jack", `class Math {
+  function int abs(int x, int y) { return x; }
+}`); err != nil {
+		t.Fatal(err)
+	}
+
+	err := program.Link()
+
+	// The call is checked against the project's own 'Math', not the OS header.
+	if err != nil {
+		t.Errorf("Expected the project's 'Math' to replace the OS class, got %v", err)
+	}
+}
+
+func TestUndefinedType(t *testing.T) {
+	program := New()
+
+	if err := program.AddSource("Main.jack", "class Main { field Foo foo; }"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := program.Link(); err == nil || !strings.Contains(err.Error(), "type 'Foo' is not defined") {
+		t.Errorf("Expected 'Foo' to be reported as an undefined type, got %v", err)
+	}
+}
+
+func TestDuplicateClass(t *testing.T) {
+	squarePath := path.Join(TEST_DATA_PATH, "Square.jack")
+
+	if _, err := Load([]string{squarePath, squarePath}); err == nil {
+		t.Errorf("Expected a class declared twice to fail to link")
+	}
+}
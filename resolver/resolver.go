@@ -0,0 +1,275 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MlkMahmud/jack-compiler/symboltable"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+type ResolverError struct {
+	// Pos locates the declaration or reference in error.
+	Pos     types.Position
+	message string
+}
+
+func (e *ResolverError) Error() string {
+	return e.message
+}
+
+// Resolver builds the class and subroutine symbol tables of a class and
+// annotates every variable reference in its AST with the symbol it names.
+type Resolver struct {
+	class      types.Class
+	classTable *symboltable.SymbolTable
+	// classes is the set of classes a type can name, nil when types are not checked.
+	classes    map[string]bool
+	scopeTable *symboltable.SymbolTable
+	subroutine types.SubroutineDecl
+}
+
+func NewResolver() *Resolver {
+	return new(Resolver)
+}
+
+func (resolver *Resolver) emitError(pos types.Position, format string, args ...any) {
+	location := resolver.class.Name.Name
+
+	if resolver.subroutine.Name.Name != "" {
+		location = fmt.Sprintf("%s.%s", location, resolver.subroutine.Name)
+	}
+
+	panic(&ResolverError{Pos: pos, message: fmt.Sprintf(
+		"(%s): Reference error: %s",
+		location,
+		fmt.Sprintf(format, args...),
+	)})
+}
+
+// DeclareClasses makes the resolver check that every type it meets is
+// either a primitive type or one of the given classes.
+func (resolver *Resolver) DeclareClasses(names ...string) {
+	resolver.classes = map[string]bool{}
+
+	for _, name := range names {
+		resolver.classes[name] = true
+	}
+}
+
+func (resolver *Resolver) checkType(pos types.Position, typeName string) {
+	if resolver.classes == nil || resolver.classes[typeName] {
+		return
+	}
+
+	if typeName == "boolean" || typeName == "char" || typeName == "int" {
+		return
+	}
+
+	resolver.emitError(pos, "type '%s' is not defined", typeName)
+}
+
+func (resolver *Resolver) define(table *symboltable.SymbolTable, pos types.Position, name string, kind types.SymbolKind, symbolType string) {
+	resolver.checkType(pos, symbolType)
+
+	if table.Has(name) {
+		resolver.emitError(pos, "identifier '%s' has already been declared", name)
+	}
+	table.Define(name, kind, symbolType)
+}
+
+// ClassTable returns the table built from the class variables of the last resolved class.
+func (resolver *Resolver) ClassTable() *symboltable.SymbolTable {
+	return resolver.classTable
+}
+
+// SubroutineTable builds the table of arguments and local variables of a
+// subroutine, enclosed by the class table of the last resolved class.
+func (resolver *Resolver) SubroutineTable(subroutine types.SubroutineDecl) *symboltable.SymbolTable {
+	table := symboltable.New(resolver.classTable)
+
+	// Methods receive the object they operate on as a hidden first argument.
+	if subroutine.Kind == types.Method {
+		table.Reserve(types.Argument)
+	}
+
+	for _, param := range subroutine.Params {
+		resolver.define(table, param.Pos(), param.Name, types.Argument, param.Type)
+	}
+
+	for _, decl := range subroutine.Body.Vars {
+		resolver.define(table, decl.Pos(), decl.Name, types.Var, decl.Type)
+	}
+
+	return table
+}
+
+func (resolver *Resolver) resolveVariable(ident types.Ident) types.Ident {
+	symbol, ok := resolver.scopeTable.Lookup(ident.Name)
+
+	if !ok {
+		resolver.emitError(ident.Pos(), "'%s' is not defined", ident.Name)
+	}
+
+	if symbol.Kind == types.Field && resolver.subroutine.Kind == types.Function {
+		resolver.emitError(ident.Pos(), "field '%s' cannot be referenced from a function", ident.Name)
+	}
+
+	ident.Symbol = &symbol
+	return ident
+}
+
+func (resolver *Resolver) resolveIndexExpression(expr types.IndexExpr) types.IndexExpr {
+	expr.Object = resolver.resolveVariable(expr.Object)
+	expr.Indexer = resolver.resolveExpression(expr.Indexer)
+	return expr
+}
+
+func (resolver *Resolver) resolveCallExpression(expr types.CallExpr) types.CallExpr {
+	if callee, ok := expr.Callee.(types.MemberExpr); ok {
+		// 'Foo.bar()' is either a call on the variable 'Foo' or on the class 'Foo'.
+		if _, ok := resolver.scopeTable.Lookup(callee.Object.Name); ok {
+			callee.Object = resolver.resolveVariable(callee.Object)
+			expr.Callee = callee
+		}
+	}
+
+	args := make([]types.Expr, 0, len(expr.Arguments))
+
+	for _, arg := range expr.Arguments {
+		args = append(args, resolver.resolveExpression(arg))
+	}
+
+	expr.Arguments = args
+	return expr
+}
+
+func (resolver *Resolver) resolveExpression(expr types.Expr) types.Expr {
+	switch expr := expr.(type) {
+	case types.BinaryExpr:
+		expr.Left = resolver.resolveExpression(expr.Left)
+		expr.Right = resolver.resolveExpression(expr.Right)
+		return expr
+
+	case types.CallExpr:
+		return resolver.resolveCallExpression(expr)
+
+	case types.Ident:
+		return resolver.resolveVariable(expr)
+
+	case types.IndexExpr:
+		return resolver.resolveIndexExpression(expr)
+
+	case types.LogicalExpr:
+		expr.Left = resolver.resolveExpression(expr.Left)
+		expr.Right = resolver.resolveExpression(expr.Right)
+		return expr
+
+	case types.ParenExpr:
+		expr.Expression = resolver.resolveExpression(expr.Expression)
+		return expr
+
+	case types.UnaryExpr:
+		expr.Operand = resolver.resolveExpression(expr.Operand)
+		return expr
+	}
+
+	return expr
+}
+
+func (resolver *Resolver) resolveBlockStatement(block types.BlockStmt) types.BlockStmt {
+	block.Statements = resolver.resolveStatements(block.Statements)
+	return block
+}
+
+func (resolver *Resolver) resolveStatement(stmt types.Stmt) types.Stmt {
+	switch stmt := stmt.(type) {
+	case types.BlockStmt:
+		return resolver.resolveBlockStatement(stmt)
+
+	case types.DoStmt:
+		stmt.Expression = resolver.resolveCallExpression(stmt.Expression)
+		return stmt
+
+	case types.IfStmt:
+		stmt.Condition = resolver.resolveExpression(stmt.Condition)
+		stmt.ThenStmt = resolver.resolveBlockStatement(stmt.ThenStmt)
+		stmt.ElseStmt = resolver.resolveBlockStatement(stmt.ElseStmt)
+		return stmt
+
+	case types.LetStmt:
+		stmt.Target = resolver.resolveExpression(stmt.Target)
+		stmt.Value = resolver.resolveExpression(stmt.Value)
+		return stmt
+
+	case types.ReturnStmt:
+		if stmt.Expression != nil {
+			stmt.Expression = resolver.resolveExpression(stmt.Expression)
+		}
+		return stmt
+
+	case types.WhileStmt:
+		stmt.Condition = resolver.resolveExpression(stmt.Condition)
+		stmt.Body = resolver.resolveBlockStatement(stmt.Body)
+		return stmt
+	}
+
+	return stmt
+}
+
+func (resolver *Resolver) resolveStatements(stmts []types.Stmt) []types.Stmt {
+	if stmts == nil {
+		return nil
+	}
+
+	resolved := make([]types.Stmt, 0, len(stmts))
+
+	for _, stmt := range stmts {
+		resolved = append(resolved, resolver.resolveStatement(stmt))
+	}
+
+	return resolved
+}
+
+func (resolver *Resolver) resolveSubroutine(subroutine types.SubroutineDecl) types.SubroutineDecl {
+	resolver.subroutine = subroutine
+	if subroutine.Type != "void" {
+		resolver.checkType(subroutine.Pos(), subroutine.Type)
+	}
+	resolver.scopeTable = resolver.SubroutineTable(subroutine)
+	subroutine.Body.Statements = resolver.resolveStatements(subroutine.Body.Statements)
+	resolver.subroutine = types.SubroutineDecl{}
+	return subroutine
+}
+
+// Resolve returns a copy of the class in which every identifier that refers
+// to a variable carries its resolved symbol.
+func (resolver *Resolver) Resolve(class types.Class) (resolved types.Class, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var resolverError *ResolverError
+			if e, ok := r.(error); ok && errors.As(e, &resolverError) {
+				err = resolverError
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	resolver.class = class
+	resolver.subroutine = types.SubroutineDecl{}
+	resolver.classTable = symboltable.New(nil)
+
+	for _, decl := range class.Vars {
+		resolver.define(resolver.classTable, decl.Pos(), decl.Name, decl.Kind, decl.Type)
+	}
+
+	resolved = class
+	resolved.Subroutines = make([]types.SubroutineDecl, 0, len(class.Subroutines))
+
+	for _, subroutine := range class.Subroutines {
+		resolved.Subroutines = append(resolved.Subroutines, resolver.resolveSubroutine(subroutine))
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,103 @@
+package resolver_test
+
+import (
+	"path"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	. "github.com/MlkMahmud/jack-compiler/parser"
+	. "github.com/MlkMahmud/jack-compiler/resolver"
+	. "github.com/MlkMahmud/jack-compiler/types"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func TestResolver(t *testing.T) {
+	lexer := lexer.NewLexer()
+	parser := NewParser()
+	resolver := NewResolver()
+
+	tokens, err := lexer.Tokenize(path.Join(TEST_DATA_PATH, "Square.jack"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := parser.Parse(tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err = resolver.Resolve(class)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	classTable := resolver.ClassTable()
+
+	for name, position := range map[string]int{"x": 0, "y": 1, "size": 2} {
+		symbol := classTable.Get(name)
+		if symbol.Kind != Field || symbol.Position != position {
+			t.Errorf("Expected '%s' to be field %d, got %s %d", name, position, symbol.Kind, symbol.Position)
+		}
+	}
+
+	if count := classTable.Count(Field); count != 3 {
+		t.Errorf("Expected 3 fields, got %d", count)
+	}
+
+	// constructor Square new(int Ax, int Ay, int Asize) { let x = Ax; ... }
+	let := class.Subroutines[0].Body.Statements[0].(LetStmt)
+	target := let.Target.(Ident)
+	value := let.Value.(Ident)
+
+	if target.Symbol == nil || target.Symbol.Kind != Field || target.Symbol.Position != 0 {
+		t.Errorf("Expected 'x' to resolve to field 0, got %+v", target.Symbol)
+	}
+
+	if value.Symbol == nil || value.Symbol.Kind != Argument || value.Symbol.Position != 0 {
+		t.Errorf("Expected 'Ax' to resolve to argument 0, got %+v", value.Symbol)
+	}
+}
+
+func TestResolverMethodArguments(t *testing.T) {
+	resolver := NewResolver()
+
+	if _, err := resolver.Resolve(Class{Name: Ident{Name: "Foo"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	table := resolver.SubroutineTable(SubroutineDecl{
+		Name:   Ident{Name: "bar"},
+		Kind:   Method,
+		Params: []Parameter{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+		Body:   SubroutineBody{Vars: []VarDecl{{Name: "c", Kind: Var, Type: "int"}}},
+	})
+
+	if symbol := table.Get("a"); symbol.Position != 1 {
+		t.Errorf("Expected 'a' to be argument 1 of a method, got %d", symbol.Position)
+	}
+
+	if symbol := table.Get("c"); symbol.Kind != Var || symbol.Position != 0 {
+		t.Errorf("Expected 'c' to be var 0, got %s %d", symbol.Kind, symbol.Position)
+	}
+}
+
+func TestResolverUndefinedVariable(t *testing.T) {
+	resolver := NewResolver()
+	class := Class{
+		Name: Ident{Name: "Foo"},
+		Subroutines: []SubroutineDecl{{
+			Name: Ident{Name: "bar"},
+			Kind: Function,
+			Type: "void",
+			Body: SubroutineBody{Statements: []Stmt{LetStmt{Target: Ident{Name: "x"}, Value: Literal{Type: IntegerLiteral, Value: "1"}}}},
+		}},
+	}
+
+	if _, err := resolver.Resolve(class); err == nil {
+		t.Error("Expected an error for the undefined variable 'x'")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/codegen"
+	"github.com/MlkMahmud/jack-compiler/jackos"
+	"github.com/MlkMahmud/jack-compiler/keyscript"
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/screen"
+	"github.com/MlkMahmud/jack-compiler/vm"
+)
+
+func printRunHelpMessage() {
+	log.SetFlags(0)
+	log.Fatalln(("usage:\n go run . run --src <dirName>\t\tCompiles the .jack files in <dirName> and runs them along with its other .vm files\n go run . run --src <fileName.jack>\tCompiles and runs the specified .jack file\n go run . run --src <fileName.vm>\tRuns the specified .vm file\n go run . run --src <src> --steps=<n>\tStops the program after <n> commands (default 10000000, 0 for no limit)\n go run . run --src <src> --check=<mode>\tType checks the program in 'lenient' or 'strict' mode before running it\n go run . run --src <src> --png=<file>\tWrites the screen to a PNG image once the program stops\n go run . run --src <src> --gif=<file>\tWrites the screen every '--frame-every' commands to an animated GIF\n go run . run --src <src> --keys=<file>\tDrives the keyboard with the key events of <file> instead of stdin"))
+}
+
+// runProgram compiles a program in memory and executes it on the VM
+// interpreter, with the native implementation of the OS classes it does not
+// implement itself. The value the program returns is printed once it halts.
+func runProgram(args []string) {
+	var source string
+	var steps int
+	var precedence bool
+	var check string
+	var pngFile string
+	var gifFile string
+	var frameEvery int
+	var keysFile string
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	flags.StringVar(&source, "src", "", "Path to a '.jack' or '.vm' file or a directory containing '.jack' and '.vm' files.")
+	flags.IntVar(&steps, "steps", 10000000, "Maximum number of VM commands to execute, 0 for no limit.")
+	flags.StringVar(&check, "check", "", "Type check the program in 'lenient' or 'strict' mode before running it.")
+	flags.BoolVar(&precedence, "precedence", false, "Group operators by conventional precedence instead of Jack's strict left-to-right order.")
+	flags.StringVar(&pngFile, "png", "", "Write the screen to a PNG image once the program stops.")
+	flags.StringVar(&gifFile, "gif", "", "Write the screen every '--frame-every' commands to an animated GIF.")
+	flags.IntVar(&frameEvery, "frame-every", 100000, "Number of VM commands between two frames of '--gif'.")
+	flags.StringVar(&keysFile, "keys", "", "Drive the keyboard with a key script instead of reading it from stdin.")
+	flags.Parse(args)
+
+	if frameEvery <= 0 {
+		printRunHelpMessage()
+	}
+
+	if _, ok := checkModes[check]; check != "" && !ok {
+		printRunHelpMessage()
+	}
+
+	info, err := os.Stat(source)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	parserOptions := []parser.Option{}
+
+	if precedence {
+		parserOptions = append(parserOptions, parser.WithOperatorPrecedence())
+	}
+
+	c := &compiler{
+		check:         check,
+		emit:          EMIT_VM,
+		generator:     codegen.NewCodeGenerator(),
+		lexer:         lexer.NewLexer(),
+		parser:        parser.NewParser(parserOptions...),
+		parserOptions: parserOptions,
+	}
+
+	jackFiles := []string{}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(source)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, entry := range entries {
+			if fileName := entry.Name(); strings.HasSuffix(fileName, ".jack") {
+				jackFiles = append(jackFiles, filepath.Join(source, fileName))
+			} else if strings.HasSuffix(fileName, ".vm") {
+				c.vmFiles = append(c.vmFiles, filepath.Join(source, fileName))
+			}
+		}
+	} else if strings.HasSuffix(source, ".jack") {
+		// The other classes of its directory are part of the program.
+		jackFiles = append(jackFiles, source)
+		c.siblings = siblingFiles(source, ".jack")
+	} else if strings.HasSuffix(source, ".vm") {
+		c.vmFiles = append(c.vmFiles, source)
+	} else {
+		printRunHelpMessage()
+	}
+
+	compiled, ok := c.generateProgram(jackFiles)
+
+	if !ok {
+		os.Exit(1)
+	}
+
+	vmFiles, err := c.withVMFiles(compiled)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var script *keyscript.Script
+
+	if keysFile != "" {
+		if script, err = keyscript.Load(keysFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	machine := vm.NewMachine()
+	system := jackos.New(os.Stdout, os.Stdin)
+
+	if script != nil {
+		// The keyboard is only driven by the script.
+		system = jackos.New(os.Stdout, nil)
+	} else if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		// A terminal already displays what is typed.
+		system.Echo = false
+	}
+
+	// The OS classes the program does not implement itself run natively.
+	system.Register(machine)
+	files := make([]vm.File, 0, len(vmFiles))
+
+	for _, file := range vmFiles {
+		files = append(files, vm.File{Name: file.Name, Code: file.Code})
+	}
+
+	if err := machine.Load(files); err != nil {
+		log.Fatal(err)
+	}
+
+	var recorder *screen.Recorder
+
+	if gifFile != "" {
+		recorder = screen.NewRecorder(10)
+	}
+
+	// The script is applied after every step, frames are captured every frameEvery steps.
+	every := frameEvery
+	hook := func(machine *vm.Machine) error {
+		if script != nil {
+			script.Apply(machine)
+		}
+
+		if recorder != nil && (machine.Steps%frameEvery == 0 || machine.Halted()) {
+			recorder.Capture(machine.Screen())
+		}
+
+		return nil
+	}
+
+	if script != nil {
+		every = 1
+		script.Apply(machine)
+	}
+
+	err = machine.RunEvery(steps, every, hook)
+
+	// The screen is written even if the program failed, it may show why.
+	if recorder != nil {
+		if err != nil {
+			recorder.Capture(machine.Screen())
+		}
+
+		if err := writeImage(gifFile, recorder.WriteGIF); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if pngFile != "" {
+		write := func(w io.Writer) error {
+			return screen.WritePNG(w, machine.Screen())
+		}
+
+		if err := writeImage(pngFile, write); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The program's own output goes to stdout.
+	if !machine.Returned() {
+		log.Printf("\nProgram halted after %d steps\n", machine.Steps)
+		return
+	}
+
+	top, err := machine.Top()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("\nProgram halted after %d steps, returning %d\n", machine.Steps, top)
+}
+
+func writeImage(dest string, write func(w io.Writer) error) error {
+	file, err := os.Create(dest)
+
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Wrote the screen to %s\n", dest)
+	return nil
+}
@@ -0,0 +1,96 @@
+package screen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+)
+
+/*
+HACK SCREEN
+
+The screen is 512 by 256 black and white pixels, mapped to the 8192 words of
+memory from RAM[16384]. Each row of pixels takes 32 words and pixel (x, y) is
+bit x%16 of word 32*y + x/16, 1 for black and 0 for white. The VM, the CPU
+and the OS share these constants.
+*/
+
+const (
+	WIDTH  = 512
+	HEIGHT = 256
+	// BASE is the address of the first word of the screen memory map.
+	BASE = 16384
+	// ROW_WORDS is the number of words of a row of pixels.
+	ROW_WORDS = WIDTH / 16
+	// SIZE is the number of words of the screen memory map.
+	SIZE = ROW_WORDS * HEIGHT
+	// KBD is the address of the keyboard register, which follows the screen
+	// memory map.
+	KBD = BASE + SIZE
+)
+
+var palette = color.Palette{color.White, color.Black}
+
+// Image renders a screen memory map. Missing words are rendered white.
+func Image(words []int16) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, WIDTH, HEIGHT), palette)
+
+	for index, word := range words {
+		if index >= SIZE {
+			break
+		}
+
+		for bit := 0; bit < 16; bit++ {
+			if uint16(word)&(1<<bit) != 0 {
+				img.Pix[index*16+bit] = 1
+			}
+		}
+	}
+
+	return img
+}
+
+// WritePNG writes a screen memory map as a PNG image.
+func WritePNG(w io.Writer, words []int16) error {
+	return png.Encode(w, Image(words))
+}
+
+// Recorder captures the frames of an animated GIF.
+type Recorder struct {
+	// Delay is the time each capture is displayed for, in 100ths of a second.
+	Delay  int
+	frames *gif.GIF
+	last   []byte
+}
+
+func NewRecorder(delay int) *Recorder {
+	return &Recorder{Delay: delay, frames: &gif.GIF{}}
+}
+
+// Capture adds a frame of a screen memory map. A frame identical to the
+// previous one extends it instead, so that idle screens do not grow the GIF.
+func (recorder *Recorder) Capture(words []int16) {
+	img := Image(words)
+
+	if count := len(recorder.frames.Image); count > 0 && bytes.Equal(img.Pix, recorder.last) {
+		recorder.frames.Delay[count-1] += recorder.Delay
+		return
+	}
+
+	recorder.frames.Image = append(recorder.frames.Image, img)
+	recorder.frames.Delay = append(recorder.frames.Delay, recorder.Delay)
+	recorder.last = img.Pix
+}
+
+// Frames returns the number of distinct frames captured so far.
+func (recorder *Recorder) Frames() int {
+	return len(recorder.frames.Image)
+}
+
+// WriteGIF writes the captured frames as an animated GIF.
+func (recorder *Recorder) WriteGIF(w io.Writer) error {
+	return gif.EncodeAll(w, recorder.frames)
+}
@@ -0,0 +1,146 @@
+package screen_test
+
+import (
+	"bytes"
+	"errors"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/codegen"
+	"github.com/MlkMahmud/jack-compiler/jackos"
+	"github.com/MlkMahmud/jack-compiler/program"
+	. "github.com/MlkMahmud/jack-compiler/screen"
+	"github.com/MlkMahmud/jack-compiler/vm"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func TestImage(t *testing.T) {
+	words := make([]int16, SIZE)
+	words[0] = 1               // (0, 0)
+	words[1] = -32768          // (31, 0)
+	words[32*255+31] = 1 << 14 // (510, 255)
+
+	img := Image(words)
+
+	for _, pixel := range []struct {
+		x, y  int
+		black bool
+	}{
+		{0, 0, true}, {1, 0, false}, {31, 0, true}, {16, 0, false}, {0, 1, false}, {510, 255, true}, {511, 255, false},
+	} {
+		if black := img.ColorIndexAt(pixel.x, pixel.y) == 1; black != pixel.black {
+			t.Errorf("Expected pixel (%d, %d) to be black: %t", pixel.x, pixel.y, pixel.black)
+		}
+	}
+
+	if bounds := Image(nil).Bounds(); bounds.Dx() != WIDTH || bounds.Dy() != HEIGHT {
+		t.Errorf("Expected a %dx%d image, got %v", WIDTH, HEIGHT, bounds)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	words := make([]int16, SIZE)
+	recorder := NewRecorder(5)
+
+	recorder.Capture(words)
+	recorder.Capture(words)
+	words[100] = 7
+	recorder.Capture(words)
+
+	if expected, actual := 2, recorder.Frames(); expected != actual {
+		t.Fatalf("Expected %d frames, got %d", expected, actual)
+	}
+
+	var output bytes.Buffer
+
+	if err := recorder.WriteGIF(&output); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := gif.DecodeAll(&output)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded.Image) != 2 || decoded.Delay[0] != 10 || decoded.Delay[1] != 5 {
+		t.Errorf("Expected 2 frames displayed for 10 and 5, got %d frames displayed for %v", len(decoded.Image), decoded.Delay)
+	}
+}
+
+// TestGolden runs SquareGame until it waits for a key and compares the
+// screen with the expected image.
+func TestGolden(t *testing.T) {
+	prog := program.New()
+
+	if err := prog.AddSource("Main.jack", "class Main {\n  function void main() {\n    var SquareGame game;\n    let game = SquareGame.new();\n    do game.run();\n    return;\n  }\n}\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"Square.jack", "SquareGame.jack"} {
+		if err := prog.AddFile(path.Join(TEST_DATA_PATH, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := prog.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []vm.File{}
+
+	for index, class := range prog.Classes {
+		vmCode, err := codegen.NewCodeGenerator().Generate(class)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files = append(files, vm.File{Name: prog.Paths[index], Code: vmCode})
+	}
+
+	machine := vm.NewMachine()
+	jackos.New(io.Discard, bytes.NewReader(nil)).Register(machine)
+
+	if err := machine.Load(files); err != nil {
+		t.Fatal(err)
+	}
+
+	// The game never halts by itself.
+	var vmError *vm.VMError
+
+	if err := machine.Run(200000); !errors.As(err, &vmError) || vmError.Kind != vm.STEP_LIMIT_EXCEEDED {
+		t.Fatalf("Expected the game to wait for a key, got %v", err)
+	}
+
+	file, err := os.Open(path.Join(TEST_DATA_PATH, "expected", "SquareGame.png"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+	expected, err := png.Decode(file)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := Image(machine.Screen())
+
+	for y := 0; y < HEIGHT; y++ {
+		for x := 0; x < WIDTH; x++ {
+			expectedR, _, _, _ := expected.At(x, y).RGBA()
+			actualR, _, _, _ := actual.At(x, y).RGBA()
+
+			if expectedR != actualR {
+				t.Fatalf("Expected the screen to match SquareGame.png, pixel (%d, %d) differs", x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package stdlib
+
+import (
+	"embed"
+	"path"
+	"sort"
+
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	"github.com/MlkMahmud/jack-compiler/parser"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+The headers declare the classes of the Jack OS the way a program would: each
+subroutine has its kind, return type and parameters, but an empty body. They
+are only used to check programs that call into the OS and are never compiled.
+*/
+
+//go:embed headers/*.jack
+var headers embed.FS
+
+// Classes returns the declarations of the OS classes, sorted by name.
+func Classes() ([]types.Class, error) {
+	entries, err := headers.ReadDir("headers")
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	classes := make([]types.Class, 0, len(entries))
+	lexer := lexer.NewLexer(lexer.WithComments())
+	parser := parser.NewParser()
+
+	for _, entry := range entries {
+		class, err := parseHeader(lexer, parser, path.Join("headers", entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		classes = append(classes, class)
+	}
+
+	return classes, nil
+}
+
+func parseHeader(lexer *lexer.Lexer, parser *parser.Parser, name string) (types.Class, error) {
+	file, err := headers.Open(name)
+
+	if err != nil {
+		return types.Class{}, err
+	}
+
+	defer file.Close()
+
+	tokens, err := lexer.TokenizeReader(path.Join("stdlib", name), file)
+
+	if err != nil {
+		return types.Class{}, err
+	}
+
+	return parser.Parse(tokens)
+}
@@ -0,0 +1,57 @@
+package stdlib_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/stdlib"
+	. "github.com/MlkMahmud/jack-compiler/types"
+)
+
+func TestClasses(t *testing.T) {
+	classes, err := Classes()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	signatures := map[string]string{}
+
+	for _, class := range classes {
+		names = append(names, class.Name.Name)
+
+		for _, subroutine := range class.Subroutines {
+			if len(subroutine.Body.Statements) > 0 || len(subroutine.Body.Vars) > 0 {
+				t.Errorf("Expected '%s.%s' to be a declaration only", class.Name, subroutine.Name)
+			}
+
+			params := []string{}
+			for _, param := range subroutine.Params {
+				params = append(params, param.Type)
+			}
+			signatures[fmt.Sprintf("%s.%s", class.Name, subroutine.Name)] = fmt.Sprintf("%s %s %v", subroutine.Kind, subroutine.Type, params)
+		}
+	}
+
+	if expected := "[Array Keyboard Math Memory Output Screen String Sys]"; fmt.Sprint(names) != expected {
+		t.Errorf("Expected the OS classes %s, got %v", expected, names)
+	}
+
+	for name, expected := range map[string]string{
+		"Array.new":         "function Array [int]",
+		"Keyboard.readInt":  "function int [String]",
+		"Math.multiply":     "function int [int int]",
+		"Memory.alloc":      "function int [int]",
+		"Memory.deAlloc":    "function void [Array]",
+		"Output.printInt":   "function void [int]",
+		"Screen.drawLine":   "function void [int int int int]",
+		"String.appendChar": "method String [char]",
+		"String.new":        fmt.Sprintf("%s String [int]", Constructor),
+		"Sys.wait":          "function void [int]",
+	} {
+		if signatures[name] != expected {
+			t.Errorf("Expected '%s' to be declared as '%s', got '%s'", name, expected, signatures[name])
+		}
+	}
+}
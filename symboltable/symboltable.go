@@ -6,19 +6,20 @@ import (
 	"github.com/MlkMahmud/jack-compiler/types"
 )
 
-type Symbol struct {
-	Kind     types.SymbolKind
-	Position int
-	Type     string
-}
+type Symbol = types.Symbol
 
 type SymbolTable struct {
 	Enclosing *SymbolTable
 	Values    map[string]Symbol
+	counts    map[types.SymbolKind]int
 }
 
 func New(enclosing *SymbolTable) *SymbolTable {
-	return &SymbolTable{Enclosing: enclosing}
+	return &SymbolTable{
+		Enclosing: enclosing,
+		Values:    map[string]Symbol{},
+		counts:    map[types.SymbolKind]int{},
+	}
 }
 
 func (table *SymbolTable) Add(id string, symbol Symbol) {
@@ -28,18 +29,52 @@ func (table *SymbolTable) Add(id string, symbol Symbol) {
 		panic(fmt.Sprintf("SyntaxError: Identifier '%s' has already been declared", id))
 	}
 
+	symbol.Name = id
 	table.Values[id] = symbol
 }
 
-func (table *SymbolTable) Get(id string) Symbol {
+// Define adds a symbol of the given kind at the next free index for that kind.
+func (table *SymbolTable) Define(id string, kind types.SymbolKind, symbolType string) Symbol {
+	symbol := Symbol{Kind: kind, Position: table.counts[kind], Type: symbolType}
+	table.Add(id, symbol)
+	table.counts[kind]++
+	return table.Values[id]
+}
+
+// Reserve skips the next index for the given kind without declaring a symbol.
+func (table *SymbolTable) Reserve(kind types.SymbolKind) {
+	table.counts[kind]++
+}
+
+// Count returns the number of indexes assigned to the given kind in this table.
+func (table *SymbolTable) Count(kind types.SymbolKind) int {
+	return table.counts[kind]
+}
+
+func (table *SymbolTable) Has(id string) bool {
+	_, ok := table.Values[id]
+	return ok
+}
+
+func (table *SymbolTable) Lookup(id string) (Symbol, bool) {
 	symbol, ok := table.Values[id]
 
 	if ok {
-		return symbol
+		return symbol, true
 	}
 
 	if table.Enclosing != nil {
-		return table.Enclosing.Get(id)
+		return table.Enclosing.Lookup(id)
+	}
+
+	return Symbol{}, false
+}
+
+func (table *SymbolTable) Get(id string) Symbol {
+	symbol, ok := table.Lookup(id)
+
+	if ok {
+		return symbol
 	}
 
 	panic(fmt.Sprintf("ReferenceError: '%s' is not defined.", id))
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/tst"
+)
+
+func printTestHelpMessage() {
+	log.SetFlags(0)
+	log.Fatalln(("usage:\n go run . test --src <fileName.tst>\tRuns the specified test script and compares its output with its compare file\n go run . test --src <dirName>\t\tRuns every .tst file in the specified directory"))
+}
+
+// testScripts runs test scripts the way the tools of the course do, writing
+// their '.out' files next to them.
+func testScripts(args []string) {
+	var source string
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	flags.StringVar(&source, "src", "", "Path to a '.tst' file or a directory containing '.tst' files.")
+	flags.Parse(args)
+
+	info, err := os.Stat(source)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	scripts := []string{source}
+
+	if info.IsDir() {
+		if scripts, err = filepath.Glob(filepath.Join(source, "*.tst")); err != nil {
+			log.Fatal(err)
+		}
+		sort.Strings(scripts)
+	} else if !strings.HasSuffix(source, ".tst") {
+		printTestHelpMessage()
+	}
+
+	failed := false
+	runner := tst.NewRunner(tst.NewTarget)
+	runner.Echo = os.Stdout
+
+	for _, script := range scripts {
+		if err := runner.Run(script); err != nil {
+			log.Printf("%s: %s\n", script, err)
+			failed = true
+		} else {
+			log.Printf("%s: End of script - Comparison ended successfully\n", script)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
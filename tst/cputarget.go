@@ -0,0 +1,68 @@
+package tst
+
+import (
+	"fmt"
+
+	"github.com/MlkMahmud/jack-compiler/cpu"
+)
+
+// CPUTarget runs test scripts on the CPU emulator: every 'ticktock', or
+// 'tock', executes a single instruction.
+type CPUTarget struct {
+	CPU *cpu.CPU
+}
+
+func NewCPUTarget() *CPUTarget {
+	return &CPUTarget{CPU: cpu.NewCPU()}
+}
+
+func (target *CPUTarget) Load(path string) error {
+	return target.CPU.LoadFile(path)
+}
+
+func (target *CPUTarget) register(variable Variable) (*int16, error) {
+	switch {
+	case variable.Name == "RAM" && variable.Indexed && variable.Index < cpu.RAM_SIZE:
+		return &target.CPU.RAM[variable.Index], nil
+	case variable.Name == "A" && !variable.Indexed:
+		return &target.CPU.A, nil
+	case variable.Name == "D" && !variable.Indexed:
+		return &target.CPU.D, nil
+	case variable.Name == "PC" && !variable.Indexed:
+		return &target.CPU.PC, nil
+	}
+
+	return nil, fmt.Errorf("'%s' is not a variable of the CPU emulator", variable)
+}
+
+func (target *CPUTarget) Get(variable Variable) (int16, error) {
+	register, err := target.register(variable)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return *register, nil
+}
+
+func (target *CPUTarget) Set(variable Variable, value int16) error {
+	register, err := target.register(variable)
+
+	if err != nil {
+		return err
+	}
+
+	*register = value
+	return nil
+}
+
+func (target *CPUTarget) Run(command string) (bool, error) {
+	switch command {
+	case "tick":
+		return true, nil
+	case "tock", "ticktock":
+		return true, target.CPU.Step()
+	}
+
+	return false, nil
+}
@@ -0,0 +1,736 @@
+package tst
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+TEST SCRIPTS
+
+Test scripts drive an emulator the way the tools of the course do:
+
+	load Program.vm,                     loads a program, or the script's directory without an argument
+	output-file Program.out,             the file 'output' writes lines to
+	compare-to Program.cmp,              the file the lines are compared with
+	output-list RAM[0]%D2.6.2 sp;        the columns of 'output', written as a header line
+	set RAM[0] 256,                      sets a variable
+	repeat 25 { vmstep; }                runs a block a fixed number of times
+	while RAM[0] <> 0 { ticktock; }      runs a block while a condition holds
+	output;                              writes and compares a line
+	echo "text";                         displays a message
+
+Commands end with ',', ';' or '!'. Values are decimal or prefixed with %B
+(binary), %X (hexadecimal) or %D (decimal). Columns are formatted as
+'name%Fl.n.r': the value in format F (B, D, S or X) aligned in n characters,
+to the left for S and to the right otherwise, then padded with l spaces on
+its left and r on its right. The 'time' column is the clock of the script,
+advanced by 'tick', 'tock' and 'ticktock'. Every other command, such as
+'vmstep' or 'ticktock', is run by the emulator the script loads.
+*/
+
+type ScriptError struct {
+	Filename string
+	Line     int
+	Message  string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf(
+		"(%s):[%d]: Script error: %s",
+		e.Filename,
+		e.Line,
+		e.Message,
+	)
+}
+
+// ComparisonError reports the first output line that differs from the compare file.
+type ComparisonError struct {
+	Actual   string
+	Expected string
+	Filename string
+	Line     int
+}
+
+func (e *ComparisonError) Error() string {
+	return fmt.Sprintf(
+		"(%s):[%d]: Comparison failure: expected '%s', got '%s'",
+		e.Filename,
+		e.Line,
+		e.Expected,
+		e.Actual,
+	)
+}
+
+// Variable names a value of an emulator, such as 'sp' or 'RAM[256]'.
+type Variable struct {
+	Name    string
+	Index   int
+	Indexed bool
+}
+
+func (variable Variable) String() string {
+	if variable.Indexed {
+		return fmt.Sprintf("%s[%d]", variable.Name, variable.Index)
+	}
+	return variable.Name
+}
+
+// Target is an emulator a test script drives.
+type Target interface {
+	// Load loads the program of a file or a directory.
+	Load(path string) error
+	Get(variable Variable) (int16, error)
+	Set(variable Variable, value int16) error
+	// Run runs an emulator specific command, such as 'vmstep'. It returns
+	// false for a command the emulator does not know.
+	Run(command string) (bool, error)
+}
+
+// TargetFactory returns the target a script loading path runs on.
+type TargetFactory func(path string) (Target, error)
+
+type command struct {
+	line  int
+	words []string
+	// body is the block of 'repeat' and 'while'.
+	body []command
+}
+
+type column struct {
+	format   byte
+	left     int
+	length   int
+	right    int
+	variable Variable
+}
+
+type Runner struct {
+	// Echo is where 'echo' displays its messages.
+	Echo      io.Writer
+	columns   []column
+	compare   []string
+	compareTo string
+	dir       string
+	factory   TargetFactory
+	filename  string
+	// halfCycles is the number of 'tick' and 'tock' of the clock.
+	halfCycles int
+	line       int
+	output     *os.File
+	// outputLine is the number of lines written to the output file.
+	outputLine int
+	target     Target
+}
+
+func NewRunner(factory TargetFactory) *Runner {
+	return &Runner{Echo: io.Discard, factory: factory}
+}
+
+func (runner *Runner) emitError(format string, args ...any) error {
+	return &ScriptError{Filename: runner.filename, Line: runner.line, Message: fmt.Sprintf(format, args...)}
+}
+
+// tokenize splits a script into words, strings and separators, dropping comments.
+func tokenize(filename, source string) ([]string, []int, error) {
+	tokens, lines := []string{}, []int{}
+	line := 1
+
+	for index := 0; index < len(source); {
+		char := source[index]
+
+		switch {
+		case char == '\n':
+			line++
+			index++
+
+		case char == ' ' || char == '\t' || char == '\r':
+			index++
+
+		case strings.HasPrefix(source[index:], "//"):
+			for index < len(source) && source[index] != '\n' {
+				index++
+			}
+
+		case strings.HasPrefix(source[index:], "/*"):
+			end := strings.Index(source[index+2:], "*/")
+
+			if end < 0 {
+				return nil, nil, &ScriptError{Filename: filename, Line: line, Message: "unterminated comment"}
+			}
+
+			line += strings.Count(source[index:index+2+end], "\n")
+			index += end + 4
+
+		case char == '"':
+			end := strings.IndexAny(source[index+1:], "\"\n")
+
+			if end < 0 || source[index+1+end] != '"' {
+				return nil, nil, &ScriptError{Filename: filename, Line: line, Message: "unterminated string"}
+			}
+
+			tokens, lines = append(tokens, source[index:index+end+2]), append(lines, line)
+			index += end + 2
+
+		case strings.ContainsRune(",;!{}", rune(char)):
+			tokens, lines = append(tokens, string(char)), append(lines, line)
+			index++
+
+		default:
+			start := index
+
+			for index < len(source) && !strings.ContainsRune(" \t\r\n,;!{}\"", rune(source[index])) && !strings.HasPrefix(source[index:], "//") {
+				index++
+			}
+
+			tokens, lines = append(tokens, source[start:index]), append(lines, line)
+		}
+	}
+
+	return tokens, lines, nil
+}
+
+// parse groups the tokens of a script into commands and blocks.
+func parse(filename string, tokens []string, lines []int, position *int, nested bool) ([]command, error) {
+	commands := []command{}
+	current := command{}
+
+	for *position < len(tokens) {
+		token, line := tokens[*position], lines[*position]
+		*position++
+
+		switch token {
+		case ",", ";", "!":
+			if len(current.words) > 0 {
+				commands = append(commands, current)
+			}
+			current = command{}
+
+		case "{":
+			if len(current.words) == 0 || (current.words[0] != "repeat" && current.words[0] != "while") {
+				return nil, &ScriptError{Filename: filename, Line: line, Message: "only 'repeat' and 'while' can have a block"}
+			}
+
+			body, err := parse(filename, tokens, lines, position, true)
+
+			if err != nil {
+				return nil, err
+			}
+
+			current.body = body
+			commands = append(commands, current)
+			current = command{}
+
+		case "}":
+			if !nested {
+				return nil, &ScriptError{Filename: filename, Line: line, Message: "unexpected '}'"}
+			}
+
+			if len(current.words) > 0 {
+				commands = append(commands, current)
+			}
+
+			return commands, nil
+
+		default:
+			if len(current.words) == 0 {
+				current.line = line
+			}
+			current.words = append(current.words, token)
+		}
+	}
+
+	if nested {
+		return nil, &ScriptError{Filename: filename, Line: lines[len(lines)-1], Message: "expected '}'"}
+	}
+
+	if len(current.words) > 0 {
+		commands = append(commands, current)
+	}
+
+	return commands, nil
+}
+
+// ParseVariable parses a variable name such as 'sp' or 'RAM[256]'.
+func ParseVariable(name string) (Variable, error) {
+	open := strings.Index(name, "[")
+
+	if open < 0 {
+		return Variable{Name: name}, nil
+	}
+
+	index, err := strconv.Atoi(name[open+1 : len(name)-1])
+
+	if !strings.HasSuffix(name, "]") || err != nil || index < 0 {
+		return Variable{}, fmt.Errorf("'%s' is not a valid variable", name)
+	}
+
+	return Variable{Name: name[:open], Index: index, Indexed: true}, nil
+}
+
+// ParseValue parses a decimal value, or one prefixed with %B, %D or %X.
+func ParseValue(value string) (int16, error) {
+	base, digits := 10, value
+
+	if len(value) > 2 && value[0] == '%' {
+		switch value[1] {
+		case 'B':
+			base = 2
+		case 'D':
+			base = 10
+		case 'X':
+			base = 16
+		default:
+			return 0, fmt.Errorf("'%s' is not a valid value", value)
+		}
+		digits = value[2:]
+	}
+
+	parsed, err := strconv.ParseInt(digits, base, 32)
+
+	// Binary and hexadecimal values are 16 bit patterns.
+	if err != nil || parsed < -32768 || parsed > 65535 || (base == 10 && parsed > 32767) {
+		return 0, fmt.Errorf("'%s' is not a valid value", value)
+	}
+
+	return int16(parsed), nil
+}
+
+func parseColumn(spec string) (column, error) {
+	name, format, found := strings.Cut(spec, "%")
+	col := column{format: 'D', left: 1, length: 6, right: 1}
+
+	if found {
+		parts := strings.Split(format[min(1, len(format)):], ".")
+		numbers := [3]int{}
+		valid := len(format) > 1 && strings.ContainsRune("BDSX", rune(format[0])) && len(parts) == 3
+
+		for index := 0; valid && index < 3; index++ {
+			number, err := strconv.Atoi(parts[index])
+			valid = err == nil && number >= 0
+			numbers[index] = number
+		}
+
+		if !valid {
+			return column{}, fmt.Errorf("'%s' is not a valid output format", spec)
+		}
+
+		col.format, col.left, col.length, col.right = format[0], numbers[0], numbers[1], numbers[2]
+	}
+
+	variable, err := ParseVariable(name)
+	col.variable = variable
+	return col, err
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (col column) width() int {
+	return col.left + col.length + col.right
+}
+
+func (col column) header() string {
+	name := col.variable.String()
+
+	if len(name) > col.width() {
+		name = name[:col.width()]
+	}
+
+	padding := col.width() - len(name)
+	return strings.Repeat(" ", padding/2) + name + strings.Repeat(" ", padding-padding/2)
+}
+
+func (col column) format16(value int16) string {
+	return col.formatText(strconv.Itoa(int(value)), value)
+}
+
+// formatText formats a value, or its text for the S format.
+func (col column) formatText(text string, value int16) string {
+	switch col.format {
+	case 'B':
+		text = fmt.Sprintf("%016b", uint16(value))
+	case 'X':
+		text = fmt.Sprintf("%04X", uint16(value))
+	}
+
+	// Binary and hexadecimal values keep their lowest digits.
+	if col.format != 'D' && col.format != 'S' && len(text) > col.length {
+		text = text[len(text)-col.length:]
+	}
+
+	// Strings are left-aligned, numbers right-aligned.
+	if col.format == 'S' {
+		text = fmt.Sprintf("%-*s", col.length, text)
+	} else {
+		text = fmt.Sprintf("%*s", col.length, text)
+	}
+
+	return strings.Repeat(" ", col.left) + text + strings.Repeat(" ", col.right)
+}
+
+// write writes a line to the output file and compares it with the compare file.
+func (runner *Runner) write(line string) error {
+	if runner.output == nil {
+		return runner.emitError("no output file has been set")
+	}
+
+	if _, err := fmt.Fprintln(runner.output, line); err != nil {
+		return err
+	}
+
+	runner.outputLine++
+
+	if runner.compare == nil {
+		return nil
+	}
+
+	expected := ""
+
+	if runner.outputLine <= len(runner.compare) {
+		expected = runner.compare[runner.outputLine-1]
+	}
+
+	if !matches(expected, line) {
+		return &ComparisonError{Actual: line, Expected: expected, Filename: runner.compareTo, Line: runner.outputLine}
+	}
+
+	return nil
+}
+
+// matches compares a line with a line of a compare file, where '*' matches any character.
+func matches(expected, actual string) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+
+	for index := range expected {
+		if expected[index] != '*' && expected[index] != actual[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (runner *Runner) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(runner.dir, name)
+}
+
+func (runner *Runner) requireTarget() error {
+	if runner.target == nil {
+		return runner.emitError("no program has been loaded")
+	}
+	return nil
+}
+
+func (runner *Runner) condition(words []string) (bool, error) {
+	if len(words) != 3 {
+		return false, runner.emitError("expected a condition such as 'RAM[0] <> 0'")
+	}
+
+	if err := runner.requireTarget(); err != nil {
+		return false, err
+	}
+
+	variable, err := ParseVariable(words[0])
+
+	if err != nil {
+		return false, runner.emitError("%s", err)
+	}
+
+	left, err := runner.target.Get(variable)
+
+	if err != nil {
+		return false, runner.emitError("%s", err)
+	}
+
+	right, err := ParseValue(words[2])
+
+	if err != nil {
+		return false, runner.emitError("%s", err)
+	}
+
+	switch words[1] {
+	case "=":
+		return left == right, nil
+	case "<>":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "<=":
+		return left <= right, nil
+	case ">=":
+		return left >= right, nil
+	}
+
+	return false, runner.emitError("'%s' is not a valid comparison", words[1])
+}
+
+func (runner *Runner) execute(commands []command) error {
+	for _, cmd := range commands {
+		runner.line = cmd.line
+
+		if err := runner.executeCommand(cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (runner *Runner) executeCommand(cmd command) error {
+	name, args := cmd.words[0], cmd.words[1:]
+
+	switch name {
+	case "load":
+		path := runner.dir
+
+		if len(args) > 0 {
+			path = runner.path(args[0])
+		}
+
+		if runner.target == nil {
+			target, err := runner.factory(path)
+
+			if err != nil {
+				return runner.emitError("%s", err)
+			}
+
+			runner.target = target
+		}
+
+		if err := runner.target.Load(path); err != nil {
+			return runner.emitError("%s", err)
+		}
+
+	case "output-file":
+		if len(args) != 1 {
+			return runner.emitError("'output-file' expects a file name")
+		}
+
+		file, err := os.Create(runner.path(args[0]))
+
+		if err != nil {
+			return runner.emitError("%s", err)
+		}
+
+		if runner.output != nil {
+			runner.output.Close()
+		}
+
+		runner.output = file
+
+	case "compare-to":
+		if len(args) != 1 {
+			return runner.emitError("'compare-to' expects a file name")
+		}
+
+		content, err := os.ReadFile(runner.path(args[0]))
+
+		if err != nil {
+			return runner.emitError("%s", err)
+		}
+
+		runner.compareTo = runner.path(args[0])
+		runner.compare = strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+
+	case "output-list":
+		runner.columns = nil
+		headers := []string{}
+
+		for _, spec := range args {
+			col, err := parseColumn(spec)
+
+			if err != nil {
+				return runner.emitError("%s", err)
+			}
+
+			runner.columns = append(runner.columns, col)
+			headers = append(headers, col.header())
+		}
+
+		return runner.write("|" + strings.Join(headers, "|") + "|")
+
+	case "output":
+		if err := runner.requireTarget(); err != nil {
+			return err
+		}
+
+		values := []string{}
+
+		for _, col := range runner.columns {
+			// The clock belongs to the script, not to the emulator.
+			if col.variable.Name == "time" && !col.variable.Indexed {
+				values = append(values, col.formatText(runner.time(), int16(runner.halfCycles/2)))
+				continue
+			}
+
+			value, err := runner.target.Get(col.variable)
+
+			if err != nil {
+				return runner.emitError("%s", err)
+			}
+
+			values = append(values, col.format16(value))
+		}
+
+		return runner.write("|" + strings.Join(values, "|") + "|")
+
+	case "set":
+		if len(args) != 2 {
+			return runner.emitError("'set' expects a variable and a value")
+		}
+
+		if err := runner.requireTarget(); err != nil {
+			return err
+		}
+
+		variable, err := ParseVariable(args[0])
+
+		if err != nil {
+			return runner.emitError("%s", err)
+		}
+
+		value, err := ParseValue(args[1])
+
+		if err != nil {
+			return runner.emitError("%s", err)
+		}
+
+		if err := runner.target.Set(variable, value); err != nil {
+			return runner.emitError("%s", err)
+		}
+
+	case "echo":
+		message := strings.Join(args, " ")
+		fmt.Fprintln(runner.Echo, strings.Trim(message, "\""))
+
+	case "clear-echo":
+
+	case "repeat":
+		count, err := strconv.Atoi(strings.Join(args, ""))
+
+		if err != nil || count < 0 || cmd.body == nil {
+			return runner.emitError("expected 'repeat <count> { ... }'")
+		}
+
+		for index := 0; index < count; index++ {
+			if err := runner.execute(cmd.body); err != nil {
+				return err
+			}
+		}
+
+	case "while":
+		if cmd.body == nil {
+			return runner.emitError("expected 'while <condition> { ... }'")
+		}
+
+		for {
+			runner.line = cmd.line
+			holds, err := runner.condition(args)
+
+			if err != nil {
+				return err
+			}
+
+			if !holds {
+				break
+			}
+
+			if err := runner.execute(cmd.body); err != nil {
+				return err
+			}
+		}
+
+	default:
+		if err := runner.requireTarget(); err != nil {
+			return err
+		}
+
+		if len(args) > 0 {
+			return runner.emitError("'%s' does not expect arguments", name)
+		}
+
+		known, err := runner.target.Run(name)
+
+		if !known {
+			return runner.emitError("unknown command '%s'", name)
+		}
+
+		if err != nil {
+			return runner.emitError("%s", err)
+		}
+
+		switch name {
+		case "tick", "tock":
+			runner.halfCycles++
+		case "ticktock":
+			runner.halfCycles += 2
+		}
+	}
+
+	return nil
+}
+
+// time returns the clock as the CPU emulator displays it: the number of
+// cycles, followed by '+' in the middle of a cycle.
+func (runner *Runner) time() string {
+	if runner.halfCycles%2 == 1 {
+		return fmt.Sprintf("%d+", runner.halfCycles/2)
+	}
+	return strconv.Itoa(runner.halfCycles / 2)
+}
+
+// Run runs a test script. Files are relative to the directory of the script.
+func (runner *Runner) Run(path string) error {
+	source, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	runner.columns = nil
+	runner.compare = nil
+	runner.dir = filepath.Dir(path)
+	runner.filename = path
+	runner.halfCycles = 0
+	runner.line = 0
+	runner.outputLine = 0
+	runner.target = nil
+
+	defer func() {
+		if runner.output != nil {
+			runner.output.Close()
+			runner.output = nil
+		}
+	}()
+
+	tokens, lines, err := tokenize(path, string(source))
+
+	if err != nil {
+		return err
+	}
+
+	position := 0
+	commands, err := parse(path, tokens, lines, &position, false)
+
+	if err != nil {
+		return err
+	}
+
+	return runner.execute(commands)
+}
@@ -0,0 +1,199 @@
+package tst_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/tst"
+)
+
+// writeFiles writes files to a temporary directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"SimpleAdd.vm": "// Pushes and adds two constants.\npush constant 7\npush constant 8\nadd\n",
+		"SimpleAdd.tst": `// Runs SimpleAdd.vm.
+load SimpleAdd.vm,
+output-file SimpleAdd.out,
+compare-to SimpleAdd.cmp,
+output-list RAM[0]%D2.6.2 RAM[256]%D2.6.2 RAM[256]%X1.4.1 RAM[256]%B1.16.1;
+
+set RAM[0] 256;  /* the stack */
+repeat 3 {
+  vmstep;
+}
+output;
+`,
+		"SimpleAdd.cmp": "|  RAM[0]  | RAM[256] |RAM[25|     RAM[256]     |\n|     257  |      15  | 000F | 0000000000001111 |\n",
+	})
+
+	if err := NewRunner(NewTarget).Run(filepath.Join(dir, "SimpleAdd.tst")); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := os.ReadFile(filepath.Join(dir, "SimpleAdd.out"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "|  RAM[0]  | RAM[256] |RAM[25|     RAM[256]     |\n|     257  |      15  | 000F | 0000000000001111 |\n"; string(output) != expected {
+		t.Errorf("Expected the output:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func TestFunction(t *testing.T) {
+	// The script sets up the frame of the call, as the VM emulator does not
+	// call the first function.
+	dir := writeFiles(t, map[string]string{
+		"Function.vm": "function Function.test 2\npush argument 0\npush argument 1\nadd\npop local 1\npush local 1\nreturn\n",
+		"Function.tst": `load,
+output-file Function.out,
+compare-to Function.cmp,
+output-list sp%D1.6.1 local%D1.6.1 argument%D1.6.1 this%D1.6.1 that%D1.6.1 RAM[310]%D1.6.1;
+set sp 317, set local 317, set argument 310, set this 3000, set that 4000,
+set argument[0] 1234, set argument[1] 37, set argument[2] 9,
+set argument[3] 305, set argument[4] 300, set argument[5] 3010, set argument[6] 4010;
+repeat 7 { vmstep; }
+output;
+`,
+		"Function.cmp": "|   sp   | local  |argument|  this  |  that  |RAM[310]|\n|    311 |    305 |    300 |   3010 |   4010 |   1271 |\n",
+	})
+
+	if err := NewRunner(NewTarget).Run(filepath.Join(dir, "Function.tst")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWhile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Loop.vm": "label LOOP\npush constant 1\npop temp 0\ngoto LOOP\n",
+		"Loop.tst": `load Loop.vm, output-file Loop.out, compare-to Loop.cmp, output-list temp[0]%D1.1.1;
+set sp 256;
+while temp[0] = 0 { vmstep; }
+output;
+echo "done";
+`,
+		"Loop.cmp": "|tem|\n| 1 |\n",
+	})
+
+	var echo strings.Builder
+	runner := NewRunner(NewTarget)
+	runner.Echo = &echo
+
+	if err := runner.Run(filepath.Join(dir, "Loop.tst")); err != nil {
+		t.Fatal(err)
+	}
+
+	if echo.String() != "done\n" {
+		t.Errorf("Expected the script to echo 'done', got '%s'", echo.String())
+	}
+}
+
+func TestCPU(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Add.asm": "@2\nD=A\n@3\nD=D+A\n@0\nM=D\n(END)\n@END\n0;JMP\n",
+		"Add.tst": `load Add.asm, output-file Add.out, compare-to Add.cmp,
+output-list time%S1.4.1 PC%D0.3.0 A%D1.3.1 D%D1.3.1 RAM[0]%D1.3.1;
+output;
+tick, output; tock, output;
+repeat 5 { ticktock; }
+output;
+`,
+		"Add.cmp": "| time |PC |  A  |  D  |RAM[0|\n| 0    |  0|   0 |   0 |   0 |\n| 0+   |  0|   0 |   0 |   0 |\n| 1    |  1|   2 |   0 |   0 |\n| 6    |  6|   0 |   5 |   5 |\n",
+	})
+
+	if err := NewRunner(NewTarget).Run(filepath.Join(dir, "Add.tst")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComparisonFailure(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Add.vm":  "push constant 7\npush constant 8\nadd\n",
+		"Add.tst": "load Add.vm, output-file Add.out, compare-to Add.cmp, output-list RAM[256]%D1.6.1;\nset sp 256;\nrepeat 2 { vmstep; } output;\nvmstep, output;\n",
+		"Add.cmp": "|RAM[256]|\n|      7 |\n|     16 |\n",
+	})
+
+	err := NewRunner(NewTarget).Run(filepath.Join(dir, "Add.tst"))
+	var comparisonError *ComparisonError
+
+	if !errors.As(err, &comparisonError) {
+		t.Fatalf("Expected a comparison failure, got %v", err)
+	}
+
+	if comparisonError.Line != 3 || comparisonError.Actual != "|     15 |" {
+		t.Errorf("Expected line 3 to differ, got line %d: %s", comparisonError.Line, comparisonError.Actual)
+	}
+
+	// Lines compare with '*' wildcards.
+	os.WriteFile(filepath.Join(dir, "Add.cmp"), []byte("|RAM[256]|\n|      7 |\n|     1* |\n"), 0644)
+
+	if err := NewRunner(NewTarget).Run(filepath.Join(dir, "Add.tst")); err != nil {
+		t.Errorf("Expected '*' to match any character, got %v", err)
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	tests := []struct {
+		script string
+		line   int
+	}{
+		{"vmstep;", 1},
+		{"load Add.vm;\nfly;", 2},
+		{"load Add.vm;\nset RAM[0];", 2},
+		{"load Add.vm;\nset RAM[0] 70000;", 2},
+		{"load Add.vm;\nset RAM[x] 1;", 2},
+		{"load Add.vm;\nset foo 1;", 2},
+		{"load Add.vm;\noutput-list RAM[0]%Q1.2.3;", 2},
+		{"load Add.vm;\n\nrepeat 2 { vmstep;", 3},
+		{"load Add.vm;\n}", 2},
+		{"load Add.vm;\nset sp 256; repeat 1 { vmstep; }\noutput;", 3},
+		{"load Missing.vm;", 1},
+		{"load Add.hack;", 1},
+		{"load Add.vm;\n/* never closed", 2},
+	}
+
+	for _, test := range tests {
+		dir := writeFiles(t, map[string]string{"Add.vm": "push constant 7\n", "Add.tst": test.script})
+		err := NewRunner(NewTarget).Run(filepath.Join(dir, "Add.tst"))
+		var scriptError *ScriptError
+
+		if !errors.As(err, &scriptError) {
+			t.Errorf("Expected a script error for %q, got %v", test.script, err)
+		} else if scriptError.Line != test.line {
+			t.Errorf("Expected the error for %q to be on line %d, got %d: %s", test.script, test.line, scriptError.Line, scriptError.Message)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	for value, expected := range map[string]int16{
+		"12": 12, "-3": -3, "%D-3": -3, "%B101": 5, "%XFFFF": -1, "%X7FFF": 32767,
+	} {
+		if actual, err := ParseValue(value); err != nil || actual != expected {
+			t.Errorf("Expected %s to be %d, got %d (%v)", value, expected, actual, err)
+		}
+	}
+
+	for _, value := range []string{"", "x", "%Q1", "32768", "%B"} {
+		if _, err := ParseValue(value); err == nil {
+			t.Errorf("Expected %q to be invalid", value)
+		}
+	}
+}
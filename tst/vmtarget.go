@@ -0,0 +1,138 @@
+package tst
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/vm"
+)
+
+// segmentBases maps the segments a VM script can index to their base register.
+var segmentBases = map[string]int{
+	"local":    1,
+	"argument": 2,
+	"this":     3,
+	"that":     4,
+}
+
+// registers maps the registers a VM script can name to their address.
+var registers = map[string]int{
+	"sp":       0,
+	"local":    1,
+	"argument": 2,
+	"this":     3,
+	"that":     4,
+}
+
+// VMTarget runs test scripts on the VM interpreter, like the VM emulator of
+// the course: the program starts at Sys.init, or at its first command, and
+// every 'vmstep' executes a single command.
+type VMTarget struct {
+	Machine *vm.Machine
+}
+
+func NewVMTarget() *VMTarget {
+	return &VMTarget{Machine: vm.NewMachine()}
+}
+
+func (target *VMTarget) Load(path string) error {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return err
+	}
+
+	target.Machine = vm.NewMachine()
+
+	if info.IsDir() {
+		err = target.Machine.LoadDir(path)
+	} else {
+		var code []byte
+
+		if code, err = os.ReadFile(path); err == nil {
+			err = target.Machine.Load([]vm.File{{Name: path, Code: string(code)}})
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	target.Machine.Begin()
+	return nil
+}
+
+func (target *VMTarget) address(variable Variable) (int, error) {
+	ram := target.Machine.RAM[:]
+	address := -1
+
+	switch {
+	case variable.Name == "RAM" && variable.Indexed:
+		address = variable.Index
+	case variable.Name == "temp" && variable.Indexed && variable.Index < 8:
+		address = 5 + variable.Index
+	case variable.Indexed:
+		if base, ok := segmentBases[variable.Name]; ok {
+			address = int(uint16(ram[base])) + variable.Index
+		}
+	default:
+		if register, ok := registers[variable.Name]; ok {
+			address = register
+		}
+	}
+
+	if address < 0 {
+		return 0, fmt.Errorf("'%s' is not a variable of the VM emulator", variable)
+	}
+
+	if address >= vm.RAM_SIZE {
+		return 0, fmt.Errorf("'%s' is outside of the RAM", variable)
+	}
+
+	return address, nil
+}
+
+func (target *VMTarget) Get(variable Variable) (int16, error) {
+	address, err := target.address(variable)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return target.Machine.RAM[address], nil
+}
+
+func (target *VMTarget) Set(variable Variable, value int16) error {
+	address, err := target.address(variable)
+
+	if err != nil {
+		return err
+	}
+
+	target.Machine.RAM[address] = value
+	return nil
+}
+
+func (target *VMTarget) Run(command string) (bool, error) {
+	if command != "vmstep" {
+		return false, nil
+	}
+
+	return true, target.Machine.Step()
+}
+
+// NewTarget picks the emulator of a script from the program it loads: a
+// directory or a '.vm' file runs on the VM interpreter, a '.hack' or '.asm'
+// file on the CPU emulator.
+func NewTarget(path string) (Target, error) {
+	if strings.HasSuffix(path, ".hack") || strings.HasSuffix(path, ".asm") {
+		return NewCPUTarget(), nil
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() || strings.HasSuffix(path, ".vm") {
+		return NewVMTarget(), nil
+	}
+
+	return nil, fmt.Errorf("cannot run '%s', only '.vm', '.hack' and '.asm' programs can be tested", path)
+}
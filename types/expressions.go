@@ -7,9 +7,11 @@ import (
 
 type Expr interface {
 	fmt.Stringer
+	Node
 }
 
 type BinaryExpr struct {
+	Span
 	Operator BinaryOperator
 	Left     Expr
 	Right    Expr
@@ -25,6 +27,7 @@ func (expr BinaryExpr) String() string {
 }
 
 type CallExpr struct {
+	Span
 	Arguments []Expr
 	Callee    Expr
 }
@@ -43,6 +46,7 @@ func (expr CallExpr) String() string {
 }
 
 type IndexExpr struct {
+	Span
 	Indexer Expr
 	Object  Ident
 }
@@ -56,6 +60,7 @@ func (expr IndexExpr) String() string {
 }
 
 type LogicalExpr struct {
+	Span
 	Operator LogicalOperator
 	Left     Expr
 	Right    Expr
@@ -71,6 +76,7 @@ func (expr LogicalExpr) String() string {
 }
 
 type MemberExpr struct {
+	Span
 	Object   Ident
 	Property Ident
 }
@@ -84,6 +90,7 @@ func (expr MemberExpr) String() string {
 }
 
 type ParenExpr struct {
+	Span
 	Expression Expr
 }
 
@@ -92,6 +99,7 @@ func (expr ParenExpr) String() string {
 }
 
 type UnaryExpr struct {
+	Span
 	Operator UnaryOperator
 	Operand  Expr
 }
@@ -105,6 +113,7 @@ func (expr UnaryExpr) String() string {
 }
 
 type Literal struct {
+	Span
 	Type  LiteralType
 	Value string
 }
@@ -114,7 +123,10 @@ func (literal Literal) String() string {
 }
 
 type Ident struct {
+	Span
 	Name string
+	// Symbol is set by the resolver when the identifier refers to a variable.
+	Symbol *Symbol `json:",omitempty"`
 }
 
 func (i Ident) String() string {
@@ -5,7 +5,12 @@ import (
 	"strings"
 )
 
+// The Doc of a Class, SubroutineDecl or VarDecl is the text of the '/** */'
+// comment right before its declaration, without the delimiters and the '*'
+// starting its lines. It is only set when the lexer keeps comments.
 type Class struct {
+	Span
+	Doc         string `json:",omitempty"`
 	Name        Ident
 	Subroutines []SubroutineDecl
 	Vars        []VarDecl
@@ -13,20 +18,27 @@ type Class struct {
 
 type Stmt interface {
 	fmt.Stringer
+	Node
 }
 
+// VarDecl spans the name it declares, since a single declaration can declare several variables.
 type VarDecl struct {
+	Span
+	Doc  string `json:",omitempty"`
 	Name string
 	Kind SymbolKind
 	Type string
 }
 
 type Parameter struct {
+	Span
 	Name string
 	Type string
 }
 
 type SubroutineDecl struct {
+	Span
+	Doc    string `json:",omitempty"`
 	Name   Ident
 	Params []Parameter
 	Kind   SymbolKind
@@ -35,11 +47,13 @@ type SubroutineDecl struct {
 }
 
 type SubroutineBody struct {
+	Span
 	Statements []Stmt
 	Vars       []VarDecl
 }
 
 type BlockStmt struct {
+	Span
 	Statements []Stmt
 }
 
@@ -57,6 +71,7 @@ func (s BlockStmt) String() string {
 }
 
 type DoStmt struct {
+	Span
 	Expression CallExpr
 }
 
@@ -65,6 +80,7 @@ func (d DoStmt) String() string {
 }
 
 type IfStmt struct {
+	Span
 	Condition Expr
 	ThenStmt  BlockStmt
 	ElseStmt  BlockStmt
@@ -88,6 +104,7 @@ func (stmt IfStmt) String() string {
 }
 
 type LetStmt struct {
+	Span
 	Target Expr
 	Value  Expr
 }
@@ -101,6 +118,7 @@ func (stmt LetStmt) String() string {
 }
 
 type ReturnStmt struct {
+	Span
 	Expression Expr
 }
 
@@ -109,6 +127,7 @@ func (stmt ReturnStmt) String() string {
 }
 
 type WhileStmt struct {
+	Span
 	Body      BlockStmt
 	Condition Expr
 }
@@ -0,0 +1,46 @@
+package types
+
+import "fmt"
+
+// Position is a location in a source file. Lines and columns start at 1,
+// offsets are byte offsets from the start of the file and start at 0.
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+	Offset   int
+}
+
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+func (pos Position) String() string {
+	if !pos.IsValid() {
+		return pos.Filename
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+}
+
+// Node is implemented by every node of the AST.
+type Node interface {
+	// Pos returns the position of the first character of the node.
+	Pos() Position
+	// End returns the position immediately after the last character of the node.
+	End() Position
+}
+
+// Span records where a node starts and ends. It is embedded in every node
+// and left out of the JSON form of the AST.
+type Span struct {
+	StartPos Position `json:"-"`
+	EndPos   Position `json:"-"`
+}
+
+func (span Span) Pos() Position {
+	return span.StartPos
+}
+
+func (span Span) End() Position {
+	return span.EndPos
+}
@@ -0,0 +1,9 @@
+package types
+
+// Symbol describes a declared variable and the slot it occupies within its segment.
+type Symbol struct {
+	Kind     SymbolKind
+	Name     string
+	Position int
+	Type     string
+}
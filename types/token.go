@@ -1,10 +1,12 @@
 package types
 
+import "strings"
+
 type TokenType int
 
 func (tokenType TokenType) String() string {
 	return []string{
-		"identifier", "integerConstant", "keyword", "stringConstant", "symbol",
+		"identifier", "integerConstant", "keyword", "stringConstant", "symbol", "comment",
 	}[tokenType]
 }
 
@@ -14,6 +16,9 @@ const (
 	KEYWORD
 	STRING_CONSTANT
 	SYMBOL
+	// COMMENT tokens are only emitted by lexers created with lexer.WithComments.
+	// Their lexeme is the whole comment, delimiters included.
+	COMMENT
 )
 
 type Token struct {
@@ -21,5 +26,27 @@ type Token struct {
 	Filename  string
 	Lexeme    string
 	LineNum   int
+	Offset    int
 	TokenType TokenType
 }
+
+func (token Token) Pos() Position {
+	return Position{Filename: token.Filename, Line: token.LineNum, Column: token.ColNum, Offset: token.Offset}
+}
+
+// End returns the position immediately after the token. Only comments span lines.
+func (token Token) End() Position {
+	width := len(token.Lexeme)
+
+	if lines := strings.Count(token.Lexeme, "\n"); lines > 0 {
+		lastLine := width - strings.LastIndex(token.Lexeme, "\n") - 1
+		return Position{Filename: token.Filename, Line: token.LineNum + lines, Column: lastLine + 1, Offset: token.Offset + width}
+	}
+
+	if token.TokenType == STRING_CONSTANT {
+		// The lexeme of a string constant leaves out its quotes.
+		width += 2
+	}
+
+	return Position{Filename: token.Filename, Line: token.LineNum, Column: token.ColNum + width, Offset: token.Offset + width}
+}
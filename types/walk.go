@@ -0,0 +1,271 @@
+package types
+
+import "fmt"
+
+/*
+Children are visited in the order they appear in the source:
+
+	Class          Name, Vars, Subroutines
+	SubroutineDecl Name, Params, Body
+	SubroutineBody Vars, Statements
+	IfStmt         Condition, ThenStmt, ElseStmt
+	CallExpr       Callee, Arguments
+	IndexExpr      Object, Indexer
+
+VarDecl, Parameter, Literal and Ident have no children. The Expression of a
+bare 'return' is nil and is skipped.
+*/
+
+// A Visitor's Visit method is called for every node encountered by Walk. If
+// the result w is not nil, Walk visits each of the children of node with w,
+// followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order, starting with node.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case Class:
+		Walk(v, n.Name)
+		for _, decl := range n.Vars {
+			Walk(v, decl)
+		}
+		for _, subroutine := range n.Subroutines {
+			Walk(v, subroutine)
+		}
+
+	case VarDecl, Parameter, Literal, Ident:
+		// Leaves.
+
+	case SubroutineDecl:
+		Walk(v, n.Name)
+		for _, param := range n.Params {
+			Walk(v, param)
+		}
+		Walk(v, n.Body)
+
+	case SubroutineBody:
+		for _, decl := range n.Vars {
+			Walk(v, decl)
+		}
+		walkList(v, n.Statements)
+
+	case BlockStmt:
+		walkList(v, n.Statements)
+
+	case DoStmt:
+		Walk(v, n.Expression)
+
+	case IfStmt:
+		Walk(v, n.Condition)
+		Walk(v, n.ThenStmt)
+		Walk(v, n.ElseStmt)
+
+	case LetStmt:
+		Walk(v, n.Target)
+		Walk(v, n.Value)
+
+	case ReturnStmt:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+
+	case WhileStmt:
+		Walk(v, n.Condition)
+		Walk(v, n.Body)
+
+	case BinaryExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case CallExpr:
+		Walk(v, n.Callee)
+		walkList(v, n.Arguments)
+
+	case IndexExpr:
+		Walk(v, n.Object)
+		Walk(v, n.Indexer)
+
+	case LogicalExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case MemberExpr:
+		Walk(v, n.Object)
+		Walk(v, n.Property)
+
+	case ParenExpr:
+		Walk(v, n.Expression)
+
+	case UnaryExpr:
+		Walk(v, n.Operand)
+
+	default:
+		panic(fmt.Sprintf("types.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkList[T Node](v Visitor, nodes []T) {
+	for _, node := range nodes {
+		Walk(v, node)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: it starts by calling
+// f(node); if f returns true, Inspect invokes f recursively for each of the
+// children of node, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+type rewriter func(Node) Node
+
+// Rewrite returns a copy of node in which every node has been replaced by
+// the result of f. The tree is rewritten bottom-up, so f receives nodes whose
+// children have already been rewritten.
+//
+// The replacement must fit where the original node was: any Expr for an
+// expression, an Ident for a name and so on, otherwise Rewrite panics. f may
+// return nil to drop an element of a list (a statement, an argument, a
+// declaration) or the value of a 'return'.
+func Rewrite(node Node, f func(Node) Node) Node {
+	return rewriter(f).rewrite(node)
+}
+
+func (f rewriter) rewrite(node Node) Node {
+	switch n := node.(type) {
+	case Class:
+		n.Name = rewriteAs(f, n.Name)
+		n.Vars = rewriteList(f, n.Vars)
+		n.Subroutines = rewriteList(f, n.Subroutines)
+		node = n
+
+	case VarDecl, Parameter, Literal, Ident:
+		// Leaves.
+
+	case SubroutineDecl:
+		n.Name = rewriteAs(f, n.Name)
+		n.Params = rewriteList(f, n.Params)
+		n.Body = rewriteAs(f, n.Body)
+		node = n
+
+	case SubroutineBody:
+		n.Vars = rewriteList(f, n.Vars)
+		n.Statements = rewriteList(f, n.Statements)
+		node = n
+
+	case BlockStmt:
+		n.Statements = rewriteList(f, n.Statements)
+		node = n
+
+	case DoStmt:
+		n.Expression = rewriteAs(f, n.Expression)
+		node = n
+
+	case IfStmt:
+		n.Condition = rewriteAs(f, n.Condition)
+		n.ThenStmt = rewriteAs(f, n.ThenStmt)
+		n.ElseStmt = rewriteAs(f, n.ElseStmt)
+		node = n
+
+	case LetStmt:
+		n.Target = rewriteAs(f, n.Target)
+		n.Value = rewriteAs(f, n.Value)
+		node = n
+
+	case ReturnStmt:
+		if n.Expression != nil {
+			if expr := f.rewrite(n.Expression); expr == nil {
+				n.Expression = nil
+			} else {
+				n.Expression = assertNode[Expr](n.Expression, expr)
+			}
+		}
+		node = n
+
+	case WhileStmt:
+		n.Condition = rewriteAs(f, n.Condition)
+		n.Body = rewriteAs(f, n.Body)
+		node = n
+
+	case BinaryExpr:
+		n.Left = rewriteAs(f, n.Left)
+		n.Right = rewriteAs(f, n.Right)
+		node = n
+
+	case CallExpr:
+		n.Callee = rewriteAs(f, n.Callee)
+		n.Arguments = rewriteList(f, n.Arguments)
+		node = n
+
+	case IndexExpr:
+		n.Object = rewriteAs(f, n.Object)
+		n.Indexer = rewriteAs(f, n.Indexer)
+		node = n
+
+	case LogicalExpr:
+		n.Left = rewriteAs(f, n.Left)
+		n.Right = rewriteAs(f, n.Right)
+		node = n
+
+	case MemberExpr:
+		n.Object = rewriteAs(f, n.Object)
+		n.Property = rewriteAs(f, n.Property)
+		node = n
+
+	case ParenExpr:
+		n.Expression = rewriteAs(f, n.Expression)
+		node = n
+
+	case UnaryExpr:
+		n.Operand = rewriteAs(f, n.Operand)
+		node = n
+
+	default:
+		panic(fmt.Sprintf("types.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+func assertNode[T Node](original T, replacement Node) T {
+	result, ok := replacement.(T)
+
+	if !ok {
+		panic(fmt.Sprintf("types.Rewrite: cannot replace %T with %T", original, replacement))
+	}
+
+	return result
+}
+
+func rewriteAs[T Node](f rewriter, node T) T {
+	return assertNode(node, f.rewrite(node))
+}
+
+func rewriteList[T Node](f rewriter, nodes []T) []T {
+	var rewritten []T
+
+	for _, node := range nodes {
+		if replacement := f.rewrite(node); replacement != nil {
+			rewritten = append(rewritten, assertNode(node, replacement))
+		}
+	}
+
+	return rewritten
+}
@@ -0,0 +1,184 @@
+package types_test
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	. "github.com/MlkMahmud/jack-compiler/parser"
+	. "github.com/MlkMahmud/jack-compiler/types"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func parseSource(t *testing.T, source string) Class {
+	filePath := path.Join(t.TempDir(), "Main.jack")
+
+	if err := os.WriteFile(filePath, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return parseFile(t, filePath)
+}
+
+func parseFile(t *testing.T, filePath string) Class {
+	tokens, err := lexer.NewLexer().Tokenize(filePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	class, err := NewParser().Parse(tokens)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return class
+}
+
+type depthVisitor struct {
+	depth    *int
+	maxDepth *int
+}
+
+func (v depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		*v.depth--
+		return nil
+	}
+
+	*v.depth++
+	if *v.depth > *v.maxDepth {
+		*v.maxDepth = *v.depth
+	}
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	class := parseFile(t, path.Join(TEST_DATA_PATH, "Square.jack"))
+	depth, maxDepth := 0, 0
+
+	Walk(depthVisitor{&depth, &maxDepth}, class)
+
+	if depth != 0 {
+		t.Errorf("Expected every visited node to be closed by Visit(nil), %d were not", depth)
+	}
+
+	if maxDepth < 5 {
+		t.Errorf("Expected Walk to descend into expressions, got a depth of %d", maxDepth)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	class := parseSource(t, `class Main {
+  field int x;
+  method void run(int n) {
+    var Array a;
+    let a[n] = Math.max(x, n + 1);
+    if (n > 0) { do run(n - 1); } else { return; }
+    return;
+  }
+}`)
+	visited := []string{}
+
+	Inspect(class, func(node Node) bool {
+		switch node := node.(type) {
+		case Ident:
+			visited = append(visited, node.Name)
+		case Literal:
+			visited = append(visited, node.Value)
+		case VarDecl:
+			visited = append(visited, "var:"+node.Name)
+		case Parameter:
+			visited = append(visited, "param:"+node.Name)
+		case IfStmt:
+			// Only look at the condition.
+			Inspect(node.Condition, func(node Node) bool {
+				if ident, ok := node.(Ident); ok {
+					visited = append(visited, "if:"+ident.Name)
+				}
+				return true
+			})
+			return false
+		}
+		return true
+	})
+
+	expected := "[Main var:x run param:n var:a a n Math max x n 1 if:n]"
+
+	if actual := fmt.Sprint(visited); actual != expected {
+		t.Errorf("Expected Inspect to visit %s, got %s", expected, actual)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	class := parseSource(t, `class Main {
+  function int main() {
+    var int x;
+    let x = (2 + 3) * 4;
+    do Output.printInt(-(1 + 1));
+    return x;
+  }
+}`)
+
+	// Fold additions of integer constants and drop 'do' statements.
+	rewritten := Rewrite(class, func(node Node) Node {
+		switch node := node.(type) {
+		case BinaryExpr:
+			left, leftOk := node.Left.(Literal)
+			right, rightOk := node.Right.(Literal)
+			if leftOk && rightOk && node.Operator == Addition {
+				a, _ := strconv.Atoi(left.Value)
+				b, _ := strconv.Atoi(right.Value)
+				return Literal{Span: node.Span, Type: IntegerLiteral, Value: strconv.Itoa(a + b)}
+			}
+		case ParenExpr:
+			if literal, ok := node.Expression.(Literal); ok {
+				return literal
+			}
+		case DoStmt:
+			return nil
+		}
+		return node
+	}).(Class)
+
+	statements := rewritten.Subroutines[0].Body.Statements
+
+	if len(statements) != 2 {
+		t.Fatalf("Expected the 'do' statement to be dropped, got %v", statements)
+	}
+
+	if actual := statements[0].(LetStmt).Value.String(); actual != "5 * 4" {
+		t.Errorf("Expected '(2 + 3) * 4' to be rewritten to '5 * 4', got '%s'", actual)
+	}
+
+	if len(class.Subroutines[0].Body.Statements) != 3 {
+		t.Errorf("Expected Rewrite to leave the original class untouched")
+	}
+}
+
+func TestRewriteMismatch(t *testing.T) {
+	class := parseSource(t, `class Main {
+  function void main() {
+    do Output.printInt(1);
+    return;
+  }
+}`)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected replacing a call with a literal in a 'do' statement to panic")
+		}
+	}()
+
+	Rewrite(class, func(node Node) Node {
+		if _, ok := node.(CallExpr); ok {
+			return Literal{Type: IntegerLiteral, Value: "1"}
+		}
+		return node
+	})
+}
@@ -0,0 +1,640 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/screen"
+)
+
+/*
+The machine executes VM commands directly on a Hack sized RAM, with the same
+memory map and calling convention as the VM translator: SP, LCL, ARG, THIS
+and THAT in RAM[0-4], temp in RAM[5-12], statics from RAM[16], the stack
+from RAM[256], the heap from RAM[2048], the screen from RAM[16384] and the
+keyboard in RAM[24576].
+
+Execution starts by calling Sys.init, or Main.main when the program does not
+define Sys.init, and ends when that first call returns or when a builtin
+halts the machine.
+
+BUILTINS
+
+A call to a function that is not defined by the loaded VM code is
+dispatched to the builtin registered under its name, if any. Builtins
+receive the arguments of the call and their result is pushed in place of
+them, as if a VM function had returned it, so the OS classes can be
+implemented either way. A builtin that returns ErrWait is called again by the
+next step instead, which lets it wait for the program's input the way a VM
+function would spin in a loop.
+*/
+
+const (
+	RAM_SIZE   = 32768
+	STACK_BASE = 256
+	HEAP_BASE  = 2048
+)
+
+type VMErrorType int
+
+const (
+	BUILTIN_ERROR VMErrorType = iota
+	INVALID_COMMAND
+	SEGMENT_OUT_OF_RANGE
+	STACK_OVERFLOW
+	STEP_LIMIT_EXCEEDED
+	UNDEFINED_FUNCTION
+	UNDEFINED_LABEL
+)
+
+func (errorType VMErrorType) String() string {
+	return []string{
+		"BUILTIN_ERROR", "INVALID_COMMAND", "SEGMENT_OUT_OF_RANGE", "STACK_OVERFLOW",
+		"STEP_LIMIT_EXCEEDED", "UNDEFINED_FUNCTION", "UNDEFINED_LABEL",
+	}[errorType]
+}
+
+type VMError struct {
+	Filename string
+	Kind     VMErrorType
+	Line     int
+	Message  string
+}
+
+func (e *VMError) Error() string {
+	return fmt.Sprintf(
+		"(%s):[%d]: VM error: %s",
+		e.Filename,
+		e.Line,
+		e.Message,
+	)
+}
+
+// Builtin implements a VM function natively. args holds the arguments of
+// the call, the returned value is pushed as its result.
+type Builtin func(machine *Machine, args []int16) (int16, error)
+
+// ErrWait is returned by a builtin that waits for the machine, e.g. for a key
+// to be pressed. The call is then executed again by the next step.
+var ErrWait = errors.New("vm: builtin is waiting")
+
+// File is a VM file. Its name, without directory nor extension, scopes its
+// static variables.
+type File struct {
+	Name string
+	Code string
+}
+
+type opcode int
+
+const (
+	opAdd opcode = iota
+	opAnd
+	opCall
+	opEq
+	opFunction
+	opGoto
+	opGt
+	opIfGoto
+	opLabel
+	opLt
+	opNeg
+	opNot
+	opOr
+	opPop
+	opPush
+	opReturn
+	opSub
+)
+
+var opcodes = map[string]opcode{
+	"add": opAdd, "and": opAnd, "call": opCall, "eq": opEq, "function": opFunction,
+	"goto": opGoto, "gt": opGt, "if-goto": opIfGoto, "label": opLabel, "lt": opLt,
+	"neg": opNeg, "not": opNot, "or": opOr, "pop": opPop, "push": opPush,
+	"return": opReturn, "sub": opSub,
+}
+
+var arities = map[opcode]int{
+	opCall: 2, opFunction: 2, opGoto: 1, opIfGoto: 1, opLabel: 1, opPop: 2, opPush: 2,
+}
+
+type instruction struct {
+	filename string
+	line     int
+	op       opcode
+	// symbol is the segment of push and pop, the function of call and
+	// function and the resolved label of goto and if-goto.
+	symbol string
+	// value is the index of push and pop, the count of call and function
+	// and the target of goto and if-goto.
+	value int
+	// staticBase is the address of the first static variable of the file.
+	staticBase int
+}
+
+type Machine struct {
+	RAM [RAM_SIZE]int16
+	// Steps is the number of commands executed so far.
+	Steps     int
+	builtins  map[string]Builtin
+	depth     int
+	functions map[string]int
+	halted    bool
+	// nextStatic is the address of the first static variable of the next loaded file.
+	nextStatic int
+	pc         int
+	program    []instruction
+	started    bool
+}
+
+func NewMachine() *Machine {
+	return &Machine{
+		builtins:   map[string]Builtin{},
+		functions:  map[string]int{},
+		nextStatic: 16,
+	}
+}
+
+func (machine *Machine) emitError(kind VMErrorType, format string, args ...any) {
+	err := &VMError{Kind: kind, Message: fmt.Sprintf(format, args...)}
+
+	if machine.pc < len(machine.program) {
+		err.Filename = machine.program[machine.pc].filename
+		err.Line = machine.program[machine.pc].line
+	}
+
+	panic(err)
+}
+
+// Register makes a builtin handle calls to the named function, unless the
+// loaded VM code defines it.
+func (machine *Machine) Register(name string, builtin Builtin) {
+	machine.builtins[name] = builtin
+}
+
+// Load parses VM files and adds their functions to the program.
+func (machine *Machine) Load(files []File) error {
+	labels := map[string]int{}
+	start := len(machine.program)
+
+	for _, file := range files {
+		staticCount := 0
+		function := strings.TrimSuffix(filepath.Base(file.Name), ".vm")
+
+		for index, line := range strings.Split(file.Code, "\n") {
+			if comment := strings.Index(line, "//"); comment >= 0 {
+				line = line[:comment]
+			}
+
+			fields := strings.Fields(line)
+
+			if len(fields) == 0 {
+				continue
+			}
+
+			inst := instruction{filename: file.Name, line: index + 1, staticBase: machine.nextStatic}
+			loadError := func(format string, args ...any) error {
+				return &VMError{Filename: inst.filename, Kind: INVALID_COMMAND, Line: inst.line, Message: fmt.Sprintf(format, args...)}
+			}
+
+			op, ok := opcodes[fields[0]]
+
+			if !ok {
+				return loadError("unknown command '%s'", fields[0])
+			}
+
+			if len(fields)-1 != arities[op] {
+				return loadError("'%s' expects %d argument(s), got %d", fields[0], arities[op], len(fields)-1)
+			}
+
+			inst.op = op
+
+			if len(fields) > 1 {
+				inst.symbol = fields[1]
+			}
+
+			if len(fields) > 2 {
+				value, err := strconv.Atoi(fields[2])
+
+				if err != nil || value < 0 || value > 32767 {
+					return loadError("'%s' is not a valid number", fields[2])
+				}
+
+				inst.value = value
+			}
+
+			switch op {
+			case opFunction:
+				function = inst.symbol
+
+				if _, ok := machine.functions[function]; ok {
+					return loadError("function '%s' has already been declared", function)
+				}
+
+				machine.functions[function] = len(machine.program)
+
+			case opGoto, opIfGoto, opLabel:
+				// Labels are scoped to their function, as in the VM translator.
+				inst.symbol = function + "$" + inst.symbol
+
+				if op == opLabel {
+					labels[inst.symbol] = len(machine.program)
+				}
+
+			case opPop, opPush:
+				if inst.symbol == "static" && inst.value >= staticCount {
+					staticCount = inst.value + 1
+				}
+			}
+
+			machine.program = append(machine.program, inst)
+		}
+
+		machine.nextStatic += staticCount
+	}
+
+	for index := start; index < len(machine.program); index++ {
+		inst := &machine.program[index]
+
+		if inst.op == opGoto || inst.op == opIfGoto {
+			target, ok := labels[inst.symbol]
+
+			if !ok {
+				return &VMError{Filename: inst.filename, Kind: UNDEFINED_LABEL, Line: inst.line, Message: fmt.Sprintf("label '%s' is not defined", inst.symbol)}
+			}
+
+			inst.value = target
+		}
+	}
+
+	return nil
+}
+
+// LoadDir loads every '.vm' file of a directory.
+func (machine *Machine) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.vm"))
+
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(paths)
+	files := make([]File, 0, len(paths))
+
+	for _, path := range paths {
+		code, err := os.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		files = append(files, File{Name: path, Code: string(code)})
+	}
+
+	return machine.Load(files)
+}
+
+// Halt stops the machine after the current command.
+func (machine *Machine) Halt() {
+	machine.halted = true
+}
+
+func (machine *Machine) Halted() bool {
+	return machine.halted
+}
+
+// Returned reports whether the program ended by returning from its first
+// call, rather than being halted by a builtin or an error.
+func (machine *Machine) Returned() bool {
+	return machine.halted && machine.depth == 0
+}
+
+// Screen returns the screen memory map, the 8K words of the RAM from
+// screen.BASE that hold the 512 by 256 pixels of the screen.
+func (machine *Machine) Screen() []int16 {
+	return machine.RAM[screen.BASE:screen.KBD]
+}
+
+// Top returns the value at the top of the stack, which is the value
+// returned by the program once it has returned. It fails when a bad program
+// left the stack pointer outside of the RAM.
+func (machine *Machine) Top() (int16, error) {
+	top := int(machine.RAM[0]) - 1
+
+	if top < 0 || top >= RAM_SIZE {
+		return 0, &VMError{Kind: SEGMENT_OUT_OF_RANGE, Message: fmt.Sprintf("address %d is outside of the RAM", top)}
+	}
+
+	return machine.RAM[top], nil
+}
+
+func (machine *Machine) address(base int, index int) int {
+	address := base + index
+
+	if address < 0 || address >= RAM_SIZE {
+		machine.emitError(SEGMENT_OUT_OF_RANGE, "address %d is outside of the RAM", address)
+	}
+
+	return address
+}
+
+func (machine *Machine) segmentAddress(inst instruction) int {
+	switch inst.symbol {
+	case "argument":
+		return machine.address(int(machine.RAM[2]), inst.value)
+	case "local":
+		return machine.address(int(machine.RAM[1]), inst.value)
+	case "pointer":
+		if inst.value > 1 {
+			machine.emitError(SEGMENT_OUT_OF_RANGE, "pointer %d is out of range", inst.value)
+		}
+		return 3 + inst.value
+	case "static":
+		return machine.address(inst.staticBase, inst.value)
+	case "temp":
+		if inst.value > 7 {
+			machine.emitError(SEGMENT_OUT_OF_RANGE, "temp %d is out of range", inst.value)
+		}
+		return 5 + inst.value
+	case "that":
+		return machine.address(int(machine.RAM[4]), inst.value)
+	case "this":
+		return machine.address(int(machine.RAM[3]), inst.value)
+	}
+
+	machine.emitError(INVALID_COMMAND, "'%s' is not a valid segment", inst.symbol)
+	return 0
+}
+
+func (machine *Machine) push(value int16) {
+	sp := int(uint16(machine.RAM[0]))
+
+	if sp >= HEAP_BASE {
+		machine.emitError(STACK_OVERFLOW, "stack overflow")
+	}
+
+	machine.RAM[sp] = value
+	machine.RAM[0]++
+}
+
+func (machine *Machine) pop() int16 {
+	// The frame of the caller sits below LCL and must not be popped.
+	if machine.RAM[0] <= STACK_BASE || (machine.depth > 0 && machine.RAM[0] <= machine.RAM[1]) {
+		machine.emitError(STACK_OVERFLOW, "stack underflow")
+	}
+
+	machine.RAM[0]--
+	return machine.RAM[machine.RAM[0]]
+}
+
+func boolean(value bool) int16 {
+	if value {
+		return -1
+	}
+	return 0
+}
+
+func (machine *Machine) call(function string, argCount int, returnAddress int) {
+	start, ok := machine.functions[function]
+
+	if !ok {
+		builtin, ok := machine.builtins[function]
+
+		if !ok {
+			machine.emitError(UNDEFINED_FUNCTION, "function '%s' is not defined", function)
+		}
+
+		args := make([]int16, argCount)
+		for index := argCount - 1; index >= 0; index-- {
+			args[index] = machine.pop()
+		}
+
+		result, err := builtin(machine, args)
+
+		// The call is retried by the next step, with the same arguments.
+		if err == ErrWait {
+			for _, arg := range args {
+				machine.push(arg)
+			}
+			return
+		}
+
+		if err != nil {
+			machine.emitError(BUILTIN_ERROR, "%s: %s", function, err)
+		}
+
+		machine.push(result)
+		machine.pc = returnAddress
+		return
+	}
+
+	machine.push(int16(returnAddress))
+	for _, register := range []int{1, 2, 3, 4} {
+		machine.push(machine.RAM[register])
+	}
+
+	machine.RAM[2] = machine.RAM[0] - 5 - int16(argCount)
+	machine.RAM[1] = machine.RAM[0]
+	machine.depth++
+	machine.pc = start
+}
+
+func (machine *Machine) ret() {
+	frame := int(machine.RAM[1])
+	returnAddress := int(uint16(machine.RAM[machine.address(frame, -5)]))
+	machine.RAM[machine.address(int(machine.RAM[2]), 0)] = machine.pop()
+	machine.RAM[0] = machine.RAM[2] + 1
+
+	for index, register := range []int{4, 3, 2, 1} {
+		machine.RAM[register] = machine.RAM[machine.address(frame, -index-1)]
+	}
+
+	machine.depth--
+	machine.pc = returnAddress
+
+	if machine.depth == 0 {
+		machine.halted = true
+	}
+}
+
+func (machine *Machine) start() {
+	machine.started = true
+	machine.RAM[0] = STACK_BASE
+	entry := "Sys.init"
+
+	if _, ok := machine.functions[entry]; !ok {
+		entry = "Main.main"
+	}
+
+	machine.pc = len(machine.program)
+	machine.call(entry, 0, len(machine.program))
+
+	// A builtin entry point returns right away.
+	if machine.depth == 0 {
+		machine.halted = true
+	}
+}
+
+// Begin starts the program the way the VM emulator of the course does: at
+// Sys.init, or at the first command when the program does not define it,
+// without calling it. The stack and the segments are left as they are, for
+// test scripts to set up.
+func (machine *Machine) Begin() {
+	machine.started = true
+	machine.pc = 0
+
+	if start, ok := machine.functions["Sys.init"]; ok {
+		machine.pc = start
+	}
+}
+
+func (machine *Machine) execute(inst instruction) {
+	next := machine.pc + 1
+
+	switch inst.op {
+	case opAdd, opAnd, opEq, opGt, opLt, opOr, opSub:
+		y, x := machine.pop(), machine.pop()
+		var result int16
+
+		switch inst.op {
+		case opAdd:
+			result = x + y
+		case opAnd:
+			result = x & y
+		case opEq:
+			result = boolean(x == y)
+		case opGt:
+			result = boolean(x > y)
+		case opLt:
+			result = boolean(x < y)
+		case opOr:
+			result = x | y
+		case opSub:
+			result = x - y
+		}
+
+		machine.push(result)
+
+	case opNeg:
+		machine.push(-machine.pop())
+
+	case opNot:
+		machine.push(^machine.pop())
+
+	case opPush:
+		if inst.symbol == "constant" {
+			machine.push(int16(inst.value))
+		} else {
+			machine.push(machine.RAM[machine.segmentAddress(inst)])
+		}
+
+	case opPop:
+		if inst.symbol == "constant" {
+			machine.emitError(INVALID_COMMAND, "cannot pop to the constant segment")
+		}
+		address := machine.segmentAddress(inst)
+		machine.RAM[address] = machine.pop()
+
+	case opLabel:
+		// Labels were resolved when the program was loaded.
+
+	case opGoto:
+		next = inst.value
+
+	case opIfGoto:
+		if machine.pop() != 0 {
+			next = inst.value
+		}
+
+	case opFunction:
+		for i := 0; i < inst.value; i++ {
+			machine.push(0)
+		}
+
+	case opCall:
+		machine.call(inst.symbol, inst.value, next)
+		return
+
+	case opReturn:
+		machine.ret()
+		return
+	}
+
+	machine.pc = next
+}
+
+// Step executes a single command, starting the program if needed.
+func (machine *Machine) Step() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var vmError *VMError
+			if e, ok := r.(error); ok && errors.As(e, &vmError) {
+				machine.halted = true
+				err = vmError
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	if !machine.started {
+		machine.start()
+	}
+
+	if machine.halted {
+		return nil
+	}
+
+	if machine.pc >= len(machine.program) {
+		machine.emitError(INVALID_COMMAND, "execution ran past the last command")
+	}
+
+	machine.Steps++
+	machine.execute(machine.program[machine.pc])
+	return nil
+}
+
+// Run executes the program until it halts. A positive maxSteps bounds the
+// number of commands executed, to stop programs that never halt.
+func (machine *Machine) Run(maxSteps int) error {
+	return machine.RunEvery(maxSteps, 0, nil)
+}
+
+// RunEvery runs the program like Run, calling f every n commands and once
+// more when the program halts. An error returned by f stops the program.
+func (machine *Machine) RunEvery(maxSteps int, n int, f func(machine *Machine) error) error {
+	for !machine.halted {
+		if maxSteps > 0 && machine.Steps >= maxSteps {
+			err := &VMError{Kind: STEP_LIMIT_EXCEEDED, Message: fmt.Sprintf("the program did not halt within %d steps", maxSteps)}
+
+			// Point at the command the program was stopped on.
+			if machine.pc < len(machine.program) {
+				err.Filename = machine.program[machine.pc].filename
+				err.Line = machine.program[machine.pc].line
+			}
+
+			return err
+		}
+
+		if err := machine.Step(); err != nil {
+			return err
+		}
+
+		if f != nil && n > 0 && machine.Steps%n == 0 && !machine.halted {
+			if err := f(machine); err != nil {
+				return err
+			}
+		}
+	}
+
+	if f != nil {
+		return f(machine)
+	}
+
+	return nil
+}
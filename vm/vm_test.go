@@ -0,0 +1,301 @@
+package vm_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/codegen"
+	"github.com/MlkMahmud/jack-compiler/program"
+	. "github.com/MlkMahmud/jack-compiler/vm"
+)
+
+func TestRun(t *testing.T) {
+	machine := NewMachine()
+	err := machine.Load([]File{
+		{Name: "dir/Main.vm", Code: `// Main.main
+function Main.main 0
+  push constant 10
+  call Main.fib 1
+  push static 0
+  add
+  return
+function Main.fib 0
+  push argument 0
+  push constant 2
+  lt
+  if-goto BASE
+  push argument 0
+  push constant 1
+  sub
+  call Main.fib 1
+  push argument 0
+  push constant 2
+  sub
+  call Main.fib 1
+  add
+  return
+label BASE
+  push argument 0
+  return`},
+		{Name: "dir/Sys.vm", Code: "function Sys.init 0\ncall Sys.setup 0\npop temp 0\ncall Main.main 0\nreturn\nfunction Sys.setup 0\npush constant 100\npop static 0\npush constant 0\nreturn\n"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := machine.Run(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if !machine.Halted() || !machine.Returned() {
+		t.Errorf("Expected the program to return")
+	}
+
+	actual, err := machine.Top()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := int16(55); expected != actual {
+		t.Errorf("Expected the program to return %d, got %d", expected, actual)
+	}
+
+	// Statics are allocated per file, Main's first then Sys's.
+	if expected, actual := int16(100), machine.RAM[17]; expected != actual {
+		t.Errorf("Expected Sys.0 to be stored in RAM[17], got %d", actual)
+	}
+}
+
+func TestRunCompiledProgram(t *testing.T) {
+	code := `class Main {
+  function int main() {
+    var Array values;
+    var int i, sum;
+    let values = Array.new(5);
+    let i = 0;
+    while (i < 5) {
+      let values[i] = i * i;
+      let i = i + 1;
+    }
+    let i = 0;
+    while (i < 5) {
+      let sum = sum + values[i];
+      let i = i + 1;
+    }
+    return sum;
+  }
+}`
+
+	prog := program.New()
+
+	if err := prog.AddSource("Main.jack", code); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prog.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	vmCode, err := codegen.NewCodeGenerator().Generate(prog.Classes[0])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	machine := NewMachine()
+	heap := int16(HEAP_BASE)
+
+	machine.Register("Array.new", func(machine *Machine, args []int16) (int16, error) {
+		address := heap
+		heap += args[0]
+		return address, nil
+	})
+
+	machine.Register("Math.multiply", func(machine *Machine, args []int16) (int16, error) {
+		return args[0] * args[1], nil
+	})
+
+	if err := machine.Load([]File{{Name: "Main.vm", Code: vmCode}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := machine.Run(100000); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := machine.Top()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := int16(30); expected != actual {
+		t.Errorf("Expected the program to return %d, got %d", expected, actual)
+	}
+
+	if expected, actual := int16(16), machine.RAM[HEAP_BASE+4]; expected != actual {
+		t.Errorf("Expected values[4] to be %d, got %d", expected, actual)
+	}
+}
+
+func TestBuiltinHalt(t *testing.T) {
+	machine := NewMachine()
+	machine.Register("Sys.halt", func(machine *Machine, args []int16) (int16, error) {
+		machine.Halt()
+		return 0, nil
+	})
+
+	code := "function Main.main 0\ncall Sys.halt 0\nlabel LOOP\ngoto LOOP\n"
+
+	if err := machine.Load([]File{{Name: "Main.vm", Code: code}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := machine.Run(1000); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := 2, machine.Steps; expected != actual {
+		t.Errorf("Expected the program to halt after %d steps, got %d", expected, actual)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		kind VMErrorType
+		line int
+	}{
+		{"function Main.main 0\nlabel LOOP\ngoto LOOP", STEP_LIMIT_EXCEEDED, 0},
+		{"function Main.main 0\ncall Foo.bar 0\nreturn", UNDEFINED_FUNCTION, 2},
+		{"function Main.main 0\npush temp 8\nreturn", SEGMENT_OUT_OF_RANGE, 2},
+		{"function Main.main 0\npop local 0\nreturn", STACK_OVERFLOW, 2},
+		{"function Main.main 0\nlabel LOOP\npush constant 1\ngoto LOOP", STACK_OVERFLOW, 3},
+		{"function Main.main 0\npush constant 1\npop constant 0\nreturn", INVALID_COMMAND, 3},
+		{"function Main.main 0\nmul", INVALID_COMMAND, 2},
+		{"function Main.main 0\npush constant", INVALID_COMMAND, 2},
+		{"function Main.main 0\npush constant 40000", INVALID_COMMAND, 2},
+		{"function Main.main 0\ngoto END\nfunction Main.foo 0\nlabel END", UNDEFINED_LABEL, 2},
+		{"function Main.main 0\nreturn\nfunction Main.main 0\nreturn", INVALID_COMMAND, 3},
+		// ARG is set to -5 before returning.
+		{"function Main.main 0\npush constant 2\npop pointer 1\npush constant 5\nneg\npop that 0\npush constant 1\nreturn", SEGMENT_OUT_OF_RANGE, 8},
+	}
+
+	for _, test := range tests {
+		machine := NewMachine()
+		err := machine.Load([]File{{Name: "Main.vm", Code: test.code}})
+
+		if err == nil {
+			err = machine.Run(10000)
+		}
+
+		var vmError *VMError
+
+		if !errors.As(err, &vmError) {
+			t.Errorf("Expected a VM error for:\n%s\ngot %v", test.code, err)
+			continue
+		}
+
+		if vmError.Kind != test.kind {
+			t.Errorf("Expected a %s error for:\n%s\ngot %s: %s", test.kind, test.code, vmError.Kind, vmError.Message)
+		}
+
+		if test.line > 0 && vmError.Line != test.line {
+			t.Errorf("Expected the error for:\n%s\nto be on line %d, got %d", test.code, test.line, vmError.Line)
+		}
+	}
+}
+
+func TestTopOutsideOfRAM(t *testing.T) {
+	// The stack pointer of a machine that has not started is 0.
+	_, err := NewMachine().Top()
+	var vmError *VMError
+
+	if !errors.As(err, &vmError) || vmError.Kind != SEGMENT_OUT_OF_RANGE {
+		t.Errorf("Expected a %s error, got %v", SEGMENT_OUT_OF_RANGE, err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "Main.vm"), []byte("function Main.main 0\ncall Foo.bar 0\nreturn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Foo.vm"), []byte("function Foo.bar 0\npush constant 7\nreturn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	machine := NewMachine()
+
+	if err := machine.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := machine.Run(100); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := machine.Top()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := int16(7); expected != actual {
+		t.Errorf("Expected the program to return %d, got %d", expected, actual)
+	}
+}
+
+func TestRunEvery(t *testing.T) {
+	machine := NewMachine()
+	code := "function Main.main 0\npush constant 0\npush constant 0\npush constant 0\nadd\nadd\nreturn\n"
+
+	if err := machine.Load([]File{{Name: "Main.vm", Code: code}}); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []int{}
+	err := machine.RunEvery(0, 3, func(machine *Machine) error {
+		calls = append(calls, machine.Steps)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := "[3 6 7]", fmt.Sprint(calls); expected != actual {
+		t.Errorf("Expected f to be called after steps %s, got %s", expected, actual)
+	}
+}
+
+func TestBuiltinWait(t *testing.T) {
+	machine := NewMachine()
+	polls := 0
+
+	machine.Register("Keyboard.readChar", func(machine *Machine, args []int16) (int16, error) {
+		if polls++; polls < 3 {
+			return 0, ErrWait
+		}
+		return args[0] + 1, nil
+	})
+
+	if err := machine.Load([]File{{Name: "Main.vm", Code: "function Main.main 0\npush constant 41\ncall Keyboard.readChar 1\nreturn\n"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := machine.Run(100); err != nil {
+		t.Fatal(err)
+	}
+
+	if top, err := machine.Top(); err != nil || top != 42 || machine.Steps != 6 {
+		t.Errorf("Expected the call to be retried until it returns 42, got %d (%v) after %d steps", top, err, machine.Steps)
+	}
+}
@@ -0,0 +1,425 @@
+package vmtranslator
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/diag"
+)
+
+/*
+HACK MEMORY MAP
+
+RAM[0]           SP    stack pointer
+RAM[1]           LCL   base of the local segment of the current function
+RAM[2]           ARG   base of the argument segment of the current function
+RAM[3]           THIS  base of the this segment (pointer 0)
+RAM[4]           THAT  base of the that segment (pointer 1)
+RAM[5-12]        temp segment
+RAM[13-15]       general purpose registers, used by the translated code
+RAM[16-255]      static variables, one 'File.i' symbol per 'static i' of each file
+RAM[256-2047]    stack
+
+CALLING CONVENTION
+
+'call f n' pushes the return address, LCL, ARG, THIS and THAT, points ARG at
+the first of the n arguments and LCL at the top of the stack, then jumps to f.
+'return' copies the return value over the first argument, restores the
+caller's frame and jumps to the return address.
+
+Labels are scoped to the function they appear in ('f$label'), return
+addresses are named 'f$ret.i' after the calling function and the bootstrap
+code sets SP to 256 before calling Sys.init.
+
+A bootstrapped program is whole: every function it calls, Sys.init included,
+must be declared by one of its files, usually those of an OS.
+*/
+
+// commandArities maps every command to its number of arguments.
+var commandArities = map[string]int{
+	"add": 0, "and": 0, "eq": 0, "gt": 0, "lt": 0, "neg": 0, "not": 0, "or": 0, "sub": 0,
+	"pop": 2, "push": 2,
+	"goto": 1, "if-goto": 1, "label": 1,
+	"call": 2, "function": 2, "return": 0,
+}
+
+var arithmeticCommands = map[string]string{
+	"add": "M=D+M",
+	"and": "M=D&M",
+	"or":  "M=D|M",
+	"sub": "M=M-D",
+}
+
+var comparisonCommands = map[string]string{
+	"eq": "JEQ",
+	"gt": "JGT",
+	"lt": "JLT",
+}
+
+var unaryCommands = map[string]string{
+	"neg": "M=-M",
+	"not": "M=!M",
+}
+
+// segmentRegisters maps the segments addressed through a base pointer to it.
+var segmentRegisters = map[string]string{
+	"argument": "ARG",
+	"local":    "LCL",
+	"that":     "THAT",
+	"this":     "THIS",
+}
+
+type TranslatorError struct {
+	Filename string
+	Line     int
+	Message  string
+}
+
+func (e *TranslatorError) Error() string {
+	// The errors of the bootstrap code have no source.
+	if e.Filename == "" {
+		return "Translation error: " + e.Message
+	}
+
+	return fmt.Sprintf(
+		"(%s):[%d]: Translation error: %s",
+		e.Filename,
+		e.Line,
+		e.Message,
+	)
+}
+
+// ErrorList is the list of the calls to undeclared functions of a program.
+type ErrorList = diag.List[*TranslatorError]
+
+// File is a VM file. Its name, without directory nor extension, prefixes the
+// symbols of its static variables.
+type File struct {
+	Name string
+	Code string
+}
+
+// call is a call command, checked once every function is declared.
+type call struct {
+	filename string
+	function string
+	line     int
+}
+
+type Translator struct {
+	// callCount numbers the return addresses of the current function.
+	callCount int
+	calls     []call
+	// compareCount numbers the labels of the comparisons of the current function.
+	compareCount int
+	filename     string
+	function     string
+	// functions holds the name of every function declared by the files.
+	functions map[string]bool
+	line      int
+	output    strings.Builder
+}
+
+func NewTranslator() *Translator {
+	return new(Translator)
+}
+
+func (translator *Translator) emitError(format string, args ...any) {
+	panic(&TranslatorError{
+		Filename: translator.filename,
+		Line:     translator.line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (translator *Translator) write(instructions ...string) {
+	for _, instruction := range instructions {
+		translator.output.WriteString(instruction)
+		translator.output.WriteString("\n")
+	}
+}
+
+// pushD pushes the D register onto the stack.
+func (translator *Translator) pushD() {
+	translator.write("@SP", "A=M", "M=D", "@SP", "M=M+1")
+}
+
+// popD pops the top of the stack into the D register.
+func (translator *Translator) popD() {
+	translator.write("@SP", "AM=M-1", "D=M")
+}
+
+func (translator *Translator) staticSymbol() string {
+	return strings.TrimSuffix(filepath.Base(translator.filename), ".vm")
+}
+
+func (translator *Translator) parseIndex(value string) int {
+	index, err := strconv.Atoi(value)
+
+	if err != nil || index < 0 || index > 32767 {
+		translator.emitError("'%s' is not a valid index", value)
+	}
+
+	return index
+}
+
+// address returns the register that holds a fixed segment entry.
+func (translator *Translator) address(segment string, index int) string {
+	switch segment {
+	case "pointer":
+		if index > 1 {
+			translator.emitError("pointer %d is out of range", index)
+		}
+		return fmt.Sprintf("R%d", 3+index)
+
+	case "static":
+		return fmt.Sprintf("%s.%d", translator.staticSymbol(), index)
+
+	case "temp":
+		if index > 7 {
+			translator.emitError("temp %d is out of range", index)
+		}
+		return fmt.Sprintf("R%d", 5+index)
+	}
+
+	translator.emitError("'%s' is not a valid segment", segment)
+	return ""
+}
+
+func (translator *Translator) translatePush(segment string, index int) {
+	if segment == "constant" {
+		translator.write(fmt.Sprintf("@%d", index), "D=A")
+	} else if register, ok := segmentRegisters[segment]; ok {
+		translator.write(fmt.Sprintf("@%d", index), "D=A", "@"+register, "A=D+M", "D=M")
+	} else {
+		translator.write("@"+translator.address(segment, index), "D=M")
+	}
+
+	translator.pushD()
+}
+
+func (translator *Translator) translatePop(segment string, index int) {
+	if segment == "constant" {
+		translator.emitError("cannot pop to the constant segment")
+	}
+
+	if register, ok := segmentRegisters[segment]; ok {
+		// The target address is kept in R13 while the value is popped.
+		translator.write(fmt.Sprintf("@%d", index), "D=A", "@"+register, "D=D+M", "@R13", "M=D")
+		translator.popD()
+		translator.write("@R13", "A=M", "M=D")
+		return
+	}
+
+	address := translator.address(segment, index)
+	translator.popD()
+	translator.write("@"+address, "M=D")
+}
+
+func (translator *Translator) translateComparison(jump string) {
+	label := fmt.Sprintf("%s$cmp.%d", translator.function, translator.compareCount)
+	translator.compareCount++
+
+	// The result is assumed to be true and reset to false when the jump is not taken.
+	translator.write("@SP", "AM=M-1", "D=M", "A=A-1", "D=M-D", "M=-1")
+	translator.write("@"+label, "D;"+jump)
+	translator.write("@SP", "A=M-1", "M=0")
+	translator.write(fmt.Sprintf("(%s)", label))
+}
+
+func (translator *Translator) translateCall(function string, argCount int) {
+	translator.calls = append(translator.calls, call{filename: translator.filename, function: function, line: translator.line})
+	returnAddress := fmt.Sprintf("%s$ret.%d", translator.function, translator.callCount)
+	translator.callCount++
+
+	translator.write("@"+returnAddress, "D=A")
+	translator.pushD()
+
+	for _, register := range []string{"LCL", "ARG", "THIS", "THAT"} {
+		translator.write("@"+register, "D=M")
+		translator.pushD()
+	}
+
+	translator.write("@SP", "D=M", "@5", "D=D-A", fmt.Sprintf("@%d", argCount), "D=D-A", "@ARG", "M=D")
+	translator.write("@SP", "D=M", "@LCL", "M=D")
+	translator.write("@"+function, "0;JMP")
+	translator.write(fmt.Sprintf("(%s)", returnAddress))
+}
+
+func (translator *Translator) translateFunction(function string, localCount int) {
+	translator.functions[function] = true
+	translator.function = function
+	translator.callCount = 0
+	translator.compareCount = 0
+
+	translator.write(fmt.Sprintf("(%s)", function))
+
+	for i := 0; i < localCount; i++ {
+		translator.write("@SP", "A=M", "M=0", "@SP", "M=M+1")
+	}
+}
+
+func (translator *Translator) translateReturn() {
+	// R13 holds the end of the caller's saved frame and R14 the return address.
+	translator.write("@LCL", "D=M", "@R13", "M=D")
+	translator.write("@5", "A=D-A", "D=M", "@R14", "M=D")
+	translator.popD()
+	translator.write("@ARG", "A=M", "M=D")
+	translator.write("@ARG", "D=M+1", "@SP", "M=D")
+
+	for _, register := range []string{"THAT", "THIS", "ARG", "LCL"} {
+		translator.write("@R13", "AM=M-1", "D=M", "@"+register, "M=D")
+	}
+
+	translator.write("@R14", "A=M", "0;JMP")
+}
+
+func (translator *Translator) label(name string) string {
+	for index, char := range name {
+		valid := char == '_' || char == '.' || char == ':' || (char >= 'a' && char <= 'z') ||
+			(char >= 'A' && char <= 'Z') || (index > 0 && char >= '0' && char <= '9')
+		if !valid {
+			translator.emitError("'%s' is not a valid label", name)
+		}
+	}
+	return fmt.Sprintf("%s$%s", translator.function, name)
+}
+
+func (translator *Translator) translateCommand(fields []string) {
+	command := fields[0]
+	arity, ok := commandArities[command]
+
+	if !ok {
+		translator.emitError("unknown command '%s'", command)
+	}
+
+	if len(fields)-1 != arity {
+		translator.emitError("'%s' expects %d argument(s), got %d", command, arity, len(fields)-1)
+	}
+
+	switch command {
+	case "add", "and", "or", "sub":
+		translator.popD()
+		translator.write("A=A-1", arithmeticCommands[command])
+
+	case "eq", "gt", "lt":
+		translator.translateComparison(comparisonCommands[command])
+
+	case "neg", "not":
+		translator.write("@SP", "A=M-1", unaryCommands[command])
+
+	case "push":
+		translator.translatePush(fields[1], translator.parseIndex(fields[2]))
+
+	case "pop":
+		translator.translatePop(fields[1], translator.parseIndex(fields[2]))
+
+	case "label":
+		translator.write(fmt.Sprintf("(%s)", translator.label(fields[1])))
+
+	case "goto":
+		translator.write("@"+translator.label(fields[1]), "0;JMP")
+
+	case "if-goto":
+		translator.popD()
+		translator.write("@"+translator.label(fields[1]), "D;JNE")
+
+	case "call":
+		translator.translateCall(fields[1], translator.parseIndex(fields[2]))
+
+	case "function":
+		translator.translateFunction(fields[1], translator.parseIndex(fields[2]))
+
+	case "return":
+		translator.translateReturn()
+	}
+}
+
+func (translator *Translator) translateFile(file File) {
+	translator.filename = file.Name
+	translator.function = translator.staticSymbol()
+	translator.callCount = 0
+	translator.compareCount = 0
+
+	for index, line := range strings.Split(file.Code, "\n") {
+		translator.line = index + 1
+
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+
+		if fields := strings.Fields(line); len(fields) > 0 {
+			translator.write("// " + strings.Join(fields, " "))
+			translator.translateCommand(fields)
+		}
+	}
+}
+
+// checkCalls returns an error for every function that is called but that no
+// file declares, at its first call.
+func (translator *Translator) checkCalls() error {
+	undeclared := ErrorList{}
+	reported := map[string]bool{}
+
+	for _, call := range translator.calls {
+		if translator.functions[call.function] || reported[call.function] {
+			continue
+		}
+
+		reported[call.function] = true
+
+		message := fmt.Sprintf("function '%s' is not declared", call.function)
+
+		if call.function == "Sys.init" {
+			message += ", the program needs the VM files of an OS, such as Sys.vm"
+		}
+
+		undeclared = append(undeclared, &TranslatorError{Filename: call.filename, Line: call.line, Message: message})
+	}
+
+	return undeclared.Err()
+}
+
+// Translate translates VM files into a single Hack assembly program. With
+// bootstrap, the program starts by setting up the stack and calling Sys.init,
+// and every function it calls must be declared by one of the files.
+func (translator *Translator) Translate(files []File, bootstrap bool) (asm string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var translatorError *TranslatorError
+			if e, ok := r.(error); ok && errors.As(e, &translatorError) {
+				err = translatorError
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	translator.output.Reset()
+	translator.calls = nil
+	translator.functions = map[string]bool{}
+
+	if bootstrap {
+		translator.filename = ""
+		translator.function = "Bootstrap"
+		translator.line = 0
+		translator.callCount = 0
+		translator.write("// bootstrap", "@256", "D=A", "@SP", "M=D")
+		translator.translateCall("Sys.init", 0)
+	}
+
+	for _, file := range files {
+		translator.translateFile(file)
+	}
+
+	if bootstrap {
+		if err := translator.checkCalls(); err != nil {
+			return "", err
+		}
+	}
+
+	return translator.output.String(), nil
+}
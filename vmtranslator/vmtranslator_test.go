@@ -0,0 +1,118 @@
+package vmtranslator_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/MlkMahmud/jack-compiler/vmtranslator"
+)
+
+func TestTranslate(t *testing.T) {
+	files := []File{
+		{Name: "dir/Main.vm", Code: `// Main.main
+function Main.main 1
+  push static 3
+  pop local 0
+label LOOP
+  push local 0
+  push constant 1
+  lt
+  if-goto LOOP // back edge
+  call Foo.bar 0
+  return`},
+		{Name: "dir/Foo.vm", Code: "function Foo.bar 0\npush static 3\nreturn\n"},
+		{Name: "dir/Sys.vm", Code: "function Sys.init 0\ncall Main.main 0\nreturn\n"},
+	}
+
+	asm, err := NewTranslator().Translate(files, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(asm, "// bootstrap\n@256\nD=A\n@SP\nM=D\n") || !strings.Contains(asm, "@Sys.init\n0;JMP\n") {
+		t.Errorf("Expected the program to start with the bootstrap code, got:\n%s", asm[:100])
+	}
+
+	for _, expected := range []string{
+		"(Main.main)\n@SP\nA=M\nM=0\n@SP\nM=M+1\n",
+		"@Main.3\nD=M\n",
+		"@Foo.3\nD=M\n",
+		"(Main.main$LOOP)\n",
+		"@Main.main$LOOP\nD;JNE\n",
+		"@Main.main$cmp.0\nD;JLT\n",
+		"@Main.main$ret.0\nD=A\n",
+		"(Main.main$ret.0)\n",
+		"// push constant 1\n@1\nD=A\n",
+	} {
+		if !strings.Contains(asm, expected) {
+			t.Errorf("Expected the translation to contain:\n%s", expected)
+		}
+	}
+
+	if translated, _ := NewTranslator().Translate(files[1:], false); strings.Contains(translated, "bootstrap") {
+		t.Errorf("Expected no bootstrap code to be written")
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		code    string
+		line    int
+		message string
+	}{
+		{"push constant 1\nfoo", 2, "unknown command 'foo'"},
+		{"pop constant 0", 1, "cannot pop to the constant segment"},
+		{"\n\npush temp 8", 3, "temp 8 is out of range"},
+		{"push pointer 2", 1, "pointer 2 is out of range"},
+		{"push heap 0", 1, "'heap' is not a valid segment"},
+		{"push local -1", 1, "'-1' is not a valid index"},
+		{"add 1", 1, "'add' expects 0 argument(s), got 1"},
+		{"label 1abc", 1, "'1abc' is not a valid label"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.message, func(t *testing.T) {
+			_, err := NewTranslator().Translate([]File{{Name: "Main.vm", Code: test.code}}, false)
+			translatorError, ok := err.(*TranslatorError)
+
+			if !ok {
+				t.Fatalf("Expected a TranslatorError, got %v", err)
+			}
+
+			if translatorError.Line != test.line || translatorError.Message != test.message {
+				t.Errorf("Expected '%s' on line %d, got '%s' on line %d", test.message, test.line, translatorError.Message, translatorError.Line)
+			}
+		})
+	}
+}
+
+func TestTranslateUndeclaredFunctions(t *testing.T) {
+	// A program compiled without the VM files of an OS. Each function is
+	// reported once, at its first call.
+	files := []File{
+		{Name: "dir/Main.vm", Code: "function Main.main 0\ncall Main.draw 0\ncall Output.printInt 1\nreturn\nfunction Main.draw 0\ncall Screen.drawPixel 2\ncall Output.printInt 1\nreturn"},
+	}
+
+	_, err := NewTranslator().Translate(files, true)
+	errorList, ok := err.(ErrorList)
+
+	if !ok {
+		t.Fatalf("Expected an ErrorList, got %v", err)
+	}
+
+	expected := []string{
+		"Translation error: function 'Sys.init' is not declared, the program needs the VM files of an OS, such as Sys.vm",
+		"(dir/Main.vm):[3]: Translation error: function 'Output.printInt' is not declared",
+		"(dir/Main.vm):[6]: Translation error: function 'Screen.drawPixel' is not declared",
+	}
+
+	if errorList.Error() != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), errorList.Error())
+	}
+
+	// Without bootstrap, the files may be a part of a program.
+	if _, err := NewTranslator().Translate(files, false); err != nil {
+		t.Errorf("Expected no error without bootstrap, got %v", err)
+	}
+}
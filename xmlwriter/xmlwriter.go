@@ -0,0 +1,355 @@
+package xmlwriter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MlkMahmud/jack-compiler/helpers"
+	"github.com/MlkMahmud/jack-compiler/types"
+)
+
+/*
+The output follows the nand2tetris project 10 format: one element per line,
+no indentation, '<', '>' and '&' escaped in terminals, empty non-terminals
+written as '<tag></tag>' and no newline after the root element.
+
+The AST does not keep every terminal (var declaration groups, commas, the
+'else' keyword...), so the parse tree is rebuilt by walking the class and the
+tokens it was parsed from side by side: the class decides the structure and
+the tokens supply the terminals.
+*/
+
+type WriterError struct {
+	message string
+}
+
+func (e *WriterError) Error() string {
+	return e.message
+}
+
+func escape(lexeme string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(lexeme)
+}
+
+func writeTerminal(builder *strings.Builder, token types.Token) {
+	builder.WriteString(fmt.Sprintf(
+		"<%s> %s </%s>",
+		token.TokenType,
+		escape(token.Lexeme),
+		token.TokenType,
+	))
+}
+
+// WriteTokens writes tokens in the format of the FooT.xml files.
+func WriteTokens(w io.Writer, tokens []types.Token) error {
+	var builder strings.Builder
+
+	builder.WriteString("<tokens>\n")
+	for _, token := range helpers.WithoutComments(tokens) {
+		builder.WriteString("  ")
+		writeTerminal(&builder, token)
+		builder.WriteString("\n")
+	}
+	builder.WriteString("</tokens>")
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+type treeWriter struct {
+	lines  []string
+	tokens []types.Token
+}
+
+func (writer *treeWriter) emitError(format string, args ...any) {
+	panic(&WriterError{message: fmt.Sprintf("Parse tree error: %s", fmt.Sprintf(format, args...))})
+}
+
+func (writer *treeWriter) open(tag string) {
+	writer.lines = append(writer.lines, fmt.Sprintf("<%s>", tag))
+}
+
+func (writer *treeWriter) close(tag string) {
+	last := len(writer.lines) - 1
+	// Elements without children are written on a single line.
+	if writer.lines[last] == fmt.Sprintf("<%s>", tag) {
+		writer.lines[last] = fmt.Sprintf("<%s></%s>", tag, tag)
+		return
+	}
+	writer.lines = append(writer.lines, fmt.Sprintf("</%s>", tag))
+}
+
+func (writer *treeWriter) peek() types.Token {
+	if len(writer.tokens) == 0 {
+		writer.emitError("unexpected end of input")
+	}
+	return writer.tokens[0]
+}
+
+// terminal writes the next token, which must have the given lexeme.
+func (writer *treeWriter) terminal(lexeme string) {
+	token := writer.peek()
+
+	if token.Lexeme != lexeme {
+		writer.emitError(
+			"(%s):[%d:%d]: expected '%s' but found '%s'",
+			token.Filename,
+			token.LineNum,
+			token.ColNum,
+			lexeme,
+			token.Lexeme,
+		)
+	}
+
+	var builder strings.Builder
+	writeTerminal(&builder, token)
+	writer.lines = append(writer.lines, builder.String())
+	writer.tokens = writer.tokens[1:]
+}
+
+// terminalsThrough writes every token up to and including the given lexeme.
+func (writer *treeWriter) terminalsThrough(lexeme string) {
+	for writer.peek().Lexeme != lexeme {
+		writer.terminal(writer.peek().Lexeme)
+	}
+	writer.terminal(lexeme)
+}
+
+func (writer *treeWriter) writeSubroutineCall(expr types.CallExpr) {
+	switch callee := expr.Callee.(type) {
+	case types.Ident:
+		writer.terminal(callee.Name)
+	case types.MemberExpr:
+		writer.terminal(callee.Object.Name)
+		writer.terminal(".")
+		writer.terminal(callee.Property.Name)
+	default:
+		writer.emitError("invalid callee '%s'", expr.Callee)
+	}
+
+	writer.terminal("(")
+	writer.open("expressionList")
+	for index, arg := range expr.Arguments {
+		if index > 0 {
+			writer.terminal(",")
+		}
+		writer.writeExpression(arg)
+	}
+	writer.close("expressionList")
+	writer.terminal(")")
+}
+
+func (writer *treeWriter) writeTerm(expr types.Expr) {
+	writer.open("term")
+
+	switch expr := expr.(type) {
+	case types.CallExpr:
+		writer.writeSubroutineCall(expr)
+
+	case types.Ident:
+		writer.terminal(expr.Name)
+
+	case types.IndexExpr:
+		writer.terminal(expr.Object.Name)
+		writer.terminal("[")
+		writer.writeExpression(expr.Indexer)
+		writer.terminal("]")
+
+	case types.Literal:
+		writer.terminal(expr.Value)
+
+	case types.ParenExpr:
+		writer.terminal("(")
+		writer.writeExpression(expr.Expression)
+		writer.terminal(")")
+
+	case types.UnaryExpr:
+		writer.terminal(string(expr.Operator))
+		writer.writeTerm(expr.Operand)
+
+	default:
+		writer.emitError("'%s' is not a term", expr)
+	}
+
+	writer.close("term")
+}
+
+// writeOperands flattens nested binary expressions into the 'term (op term)*'
+// sequence of the grammar, which is also the order of their tokens.
+func (writer *treeWriter) writeOperands(expr types.Expr) {
+	switch expr := expr.(type) {
+	case types.BinaryExpr:
+		writer.writeOperands(expr.Left)
+		writer.terminal(string(expr.Operator))
+		writer.writeOperands(expr.Right)
+
+	case types.LogicalExpr:
+		writer.writeOperands(expr.Left)
+		writer.terminal(string(expr.Operator))
+		writer.writeOperands(expr.Right)
+
+	default:
+		writer.writeTerm(expr)
+	}
+}
+
+func (writer *treeWriter) writeExpression(expr types.Expr) {
+	writer.open("expression")
+	writer.writeOperands(expr)
+	writer.close("expression")
+}
+
+func (writer *treeWriter) writeBlock(block types.BlockStmt) {
+	writer.terminal("{")
+	writer.writeStatements(block.Statements)
+	writer.terminal("}")
+}
+
+func (writer *treeWriter) writeStatement(stmt types.Stmt) {
+	switch stmt := stmt.(type) {
+	case types.DoStmt:
+		writer.open("doStatement")
+		writer.terminal("do")
+		writer.writeSubroutineCall(stmt.Expression)
+		writer.terminal(";")
+		writer.close("doStatement")
+
+	case types.IfStmt:
+		writer.open("ifStatement")
+		writer.terminal("if")
+		writer.terminal("(")
+		writer.writeExpression(stmt.Condition)
+		writer.terminal(")")
+		writer.writeBlock(stmt.ThenStmt)
+		// An empty else block leaves no trace in the AST, only in the tokens.
+		if helpers.IsOneOfKeywords(writer.peek(), []string{"else"}) {
+			writer.terminal("else")
+			writer.writeBlock(stmt.ElseStmt)
+		}
+		writer.close("ifStatement")
+
+	case types.LetStmt:
+		writer.open("letStatement")
+		writer.terminal("let")
+		switch target := stmt.Target.(type) {
+		case types.Ident:
+			writer.terminal(target.Name)
+		case types.IndexExpr:
+			writer.terminal(target.Object.Name)
+			writer.terminal("[")
+			writer.writeExpression(target.Indexer)
+			writer.terminal("]")
+		default:
+			writer.emitError("invalid assignment target '%s'", stmt.Target)
+		}
+		writer.terminal("=")
+		writer.writeExpression(stmt.Value)
+		writer.terminal(";")
+		writer.close("letStatement")
+
+	case types.ReturnStmt:
+		writer.open("returnStatement")
+		writer.terminal("return")
+		if stmt.Expression != nil {
+			writer.writeExpression(stmt.Expression)
+		}
+		writer.terminal(";")
+		writer.close("returnStatement")
+
+	case types.WhileStmt:
+		writer.open("whileStatement")
+		writer.terminal("while")
+		writer.terminal("(")
+		writer.writeExpression(stmt.Condition)
+		writer.terminal(")")
+		writer.writeBlock(stmt.Body)
+		writer.close("whileStatement")
+
+	default:
+		writer.emitError("unsupported statement '%s'", stmt)
+	}
+}
+
+func (writer *treeWriter) writeStatements(stmts []types.Stmt) {
+	writer.open("statements")
+	for _, stmt := range stmts {
+		writer.writeStatement(stmt)
+	}
+	writer.close("statements")
+}
+
+func (writer *treeWriter) writeSubroutine(subroutine types.SubroutineDecl) {
+	writer.open("subroutineDec")
+	writer.terminal(string(subroutine.Kind))
+	writer.terminal(subroutine.Type)
+	writer.terminal(subroutine.Name.Name)
+	writer.terminal("(")
+
+	writer.open("parameterList")
+	for index, param := range subroutine.Params {
+		if index > 0 {
+			writer.terminal(",")
+		}
+		writer.terminal(param.Type)
+		writer.terminal(param.Name)
+	}
+	writer.close("parameterList")
+	writer.terminal(")")
+
+	writer.open("subroutineBody")
+	writer.terminal("{")
+	// Each 'var' declaration keeps its own element, however many names it declares.
+	for helpers.IsOneOfKeywords(writer.peek(), []string{"var"}) {
+		writer.open("varDec")
+		writer.terminalsThrough(";")
+		writer.close("varDec")
+	}
+	writer.writeStatements(subroutine.Body.Statements)
+	writer.terminal("}")
+	writer.close("subroutineBody")
+
+	writer.close("subroutineDec")
+}
+
+func (writer *treeWriter) writeClass(class types.Class) {
+	writer.open("class")
+	writer.terminal("class")
+	writer.terminal(class.Name.Name)
+	writer.terminal("{")
+
+	for helpers.IsOneOfKeywords(writer.peek(), []string{"field", "static"}) {
+		writer.open("classVarDec")
+		writer.terminalsThrough(";")
+		writer.close("classVarDec")
+	}
+
+	for _, subroutine := range class.Subroutines {
+		writer.writeSubroutine(subroutine)
+	}
+
+	writer.terminal("}")
+	writer.close("class")
+}
+
+// WriteClass writes the parse tree of class in the format of the Foo.xml
+// files. tokens must be the tokens class was parsed from.
+func WriteClass(w io.Writer, tokens []types.Token, class types.Class) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var writerError *WriterError
+			if e, ok := r.(error); ok && errors.As(e, &writerError) {
+				err = writerError
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	writer := &treeWriter{tokens: helpers.WithoutComments(tokens)}
+	writer.writeClass(class)
+
+	_, err = io.WriteString(w, strings.Join(writer.lines, "\n"))
+	return err
+}
@@ -0,0 +1,61 @@
+package xmlwriter_test
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/MlkMahmud/jack-compiler/lexer"
+	. "github.com/MlkMahmud/jack-compiler/parser"
+	. "github.com/MlkMahmud/jack-compiler/xmlwriter"
+)
+
+const TEST_DATA_PATH = "../testdata"
+
+func TestWriter(t *testing.T) {
+	files := []string{"Array", "Square", "SquareGame"}
+	lexer := lexer.NewLexer()
+	parser := NewParser()
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			tokens, err := lexer.Tokenize(path.Join(TEST_DATA_PATH, file+".jack"))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			class, err := parser.Parse(tokens)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var tokensXML, parseXML bytes.Buffer
+
+			if err := WriteTokens(&tokensXML, tokens); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := WriteClass(&parseXML, tokens, class); err != nil {
+				t.Fatal(err)
+			}
+
+			for cmpFile, actual := range map[string][]byte{
+				file + "T.xml": tokensXML.Bytes(),
+				file + ".xml":  parseXML.Bytes(),
+			} {
+				expected, err := os.ReadFile(path.Join(TEST_DATA_PATH, "expected", cmpFile))
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(expected, actual) {
+					t.Errorf("Expected output to match content of %s", cmpFile)
+				}
+			}
+		})
+	}
+}